@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/yaoxc/EasySwapBase/chain/chainclient"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/ordermanager"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service"
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+	"github.com/yaoxc/EasySwapSync/service/leader"
+	"github.com/yaoxc/EasySwapSync/service/orderbookindexer"
+)
+
+var (
+	cursorBlock   uint64
+	rewindBlocks  uint64
+	cursorDryRun  bool
+	cursorCleanup bool
+	cursorForce   bool
+)
+
+// CursorCmd 修改订单簿事件同步游标（ob_indexed_status）。修改前应停止 daemon，或通过管理接口暂停同步并等到
+// 返回 paused: true，之后通过管理接口恢复时 daemon 会重新读取游标。开启 leader 选举时有实例持有租约
+// 且没有暂停标记会拒绝执行，--force 跳过该检查；未开启时无法确认 daemon 状态，需由操作者保证
+var CursorCmd = &cobra.Command{
+	Use:   "cursor",
+	Short: "inspect and modify the orderbook event sync cursor.",
+	Long:  "inspect and modify the orderbook event sync cursor. stop the daemon, or pause it through the admin api and wait for paused: true; resuming through the admin api reloads the cursor.",
+}
+
+// CursorSetCmd 把游标设置到指定区块
+var CursorSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "set the next block to sync.",
+	Long:  "set the next block to sync, optionally deleting activities and contract upgrades from that block on. orders and order fills are kept.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !cmd.Flags().Changed("block") {
+			return errors.New("--block is required")
+		}
+		return moveCursor(func(current uint64) (uint64, error) {
+			return cursorBlock, nil
+		})
+	},
+}
+
+// CursorRewindCmd 把游标回退指定区块数
+var CursorRewindCmd = &cobra.Command{
+	Use:   "rewind",
+	Short: "rewind the next block to sync by a number of blocks.",
+	Long:  "rewind the next block to sync by a number of blocks, optionally deleting activities and contract upgrades from the new cursor on. orders and order fills are kept.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if rewindBlocks == 0 {
+			return errors.New("--blocks must be greater than 0")
+		}
+		return moveCursor(func(current uint64) (uint64, error) {
+			if rewindBlocks > current {
+				return 0, errors.Errorf("can not rewind %d blocks from block %d", rewindBlocks, current)
+			}
+			return current - rewindBlocks, nil
+		})
+	},
+}
+
+// moveCursor 按 target 计算新游标，检查后通过 SetCursor 写入，与管理接口共用清理逻辑
+func moveCursor(target func(current uint64) (uint64, error)) error {
+	cfg, err := config.UnmarshalCmdConfig()
+	if err != nil {
+		return errors.Wrap(err, "failed on unmarshal config")
+	}
+	if _, err := xzap.SetUp(*cfg.Log); err != nil {
+		return errors.Wrap(err, "failed on set up logger")
+	}
+
+	ctx := context.Background()
+	indexer, chainClient, err := newCmdIndexer(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	current, err := indexer.LoadCursor()
+	if err != nil {
+		return errors.Wrap(err, "failed on load cursor, run `sync init` first")
+	}
+	nextBlock, err := target(current)
+	if err != nil {
+		return err
+	}
+	head, err := chainClient.BlockNumber()
+	if err != nil {
+		return errors.Wrap(err, "failed on get current block number")
+	}
+	if nextBlock > head+1 {
+		return errors.Wrapf(orderbookindexer.ErrCursorAheadOfHead, "next block %d, head %d", nextBlock, head)
+	}
+	if startBlock, ok := cfg.ChainCfg.StartBlock(cfg.ContractCfg.DexAddress); ok && nextBlock < startBlock {
+		return errors.Wrapf(orderbookindexer.ErrCursorBeforeDeployment, "next block %d, deployment block %d", nextBlock, startBlock)
+	}
+
+	if cfg.LeaderCfg.Enable && !cursorForce {
+		elector := leader.New(ctx, service.NewKvStore(cfg), cfg.LeaderCfg, cfg.ChainCfg.Name, cfg.ContractCfg.DexAddress)
+		holder, err := elector.Holder()
+		if err != nil {
+			return err
+		}
+		if holder != "" {
+			paused, err := indexer.PauseRequested()
+			if err != nil {
+				return err
+			}
+			if !paused {
+				return errors.Errorf("daemon %s holds the leader lease, stop it or pause it through the admin api first", holder)
+			}
+		}
+	}
+
+	fmt.Printf("chain: %s, head: %d\n", cfg.ChainCfg.Name, head)
+	fmt.Printf("next block: %d -> %d\n", current, nextBlock)
+	if cursorCleanup {
+		counts, err := indexer.CountDerivedRows(nextBlock)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rows to delete: activities %d, contract upgrades %d\n", counts.Activities, counts.Upgrades)
+	}
+	if cursorDryRun {
+		fmt.Println("dry run, nothing changed")
+		return nil
+	}
+
+	// 先移动游标再清理，与管理接口的顺序一致
+	if err := indexer.SetCursor(nextBlock); err != nil {
+		return err
+	}
+	fmt.Println("cursor moved")
+	if cursorCleanup {
+		deleted, err := indexer.CleanupDerivedRows(nextBlock)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rows deleted: activities %d, contract upgrades %d\n", deleted.Activities, deleted.Upgrades)
+	}
+	return nil
+}
+
+// newCmdIndexer 创建命令行使用的订单簿索引器，不启动同步循环
+func newCmdIndexer(ctx context.Context, cfg *config.Config) (*orderbookindexer.Service, chainclient.ChainClient, error) {
+	db := model.NewDB(cfg.DB)
+	kvStore := service.NewKvStore(cfg)
+	chainClient, err := chainclient.New(int(cfg.ChainCfg.ID), cfg.AnkrCfg.HttpsUrl+cfg.AnkrCfg.ApiKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed on create evm client")
+	}
+	currencies, err := currency.New(cfg.ContractCfg, cfg.Currencies)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed on create currency registry")
+	}
+	orderManager := ordermanager.New(ctx, db, kvStore, cfg.ChainCfg.Name, cfg.ProjectCfg.Name)
+	indexer, err := orderbookindexer.New(ctx, cfg, db, kvStore, chainClient, cfg.ChainCfg.ID, cfg.ChainCfg.Name, orderManager, nil, nil, currencies)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed on create orderbook indexer")
+	}
+	return indexer, chainClient, nil
+}
+
+func init() {
+	flags := CursorCmd.PersistentFlags()
+	flags.BoolVar(&cursorDryRun, "dry-run", false, "print the change without applying it")
+	flags.BoolVar(&cursorCleanup, "cleanup", false, "delete activities and contract upgrades from the new cursor on, order fills are kept as the replay guard")
+	flags.BoolVar(&cursorForce, "force", false, "skip the leader lease check")
+	CursorSetCmd.Flags().Uint64Var(&cursorBlock, "block", 0, "next block to sync")
+	CursorRewindCmd.Flags().Uint64Var(&rewindBlocks, "blocks", 0, "number of blocks to rewind")
+	CursorCmd.AddCommand(CursorSetCmd, CursorRewindCmd)
+	rootCmd.AddCommand(CursorCmd)
+}
@@ -9,6 +9,7 @@ import (
 	"os/signal"        // 信号处理
 	"sync"             // 并发同步
 	"syscall"          // 系统调用
+	"time"             // 停机超时
 
 	"github.com/spf13/cobra" // 命令行工具库
 	"go.uber.org/zap"        // 日志库
@@ -26,14 +27,14 @@ var DaemonCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) { // 命令执行入口
 		fmt.Println("==== daemon Run begin ====")
 
-		wg := &sync.WaitGroup{}                // 创建WaitGroup用于等待goroutine结束
-		wg.Add(1)                              // 增加计数
-		ctx := context.Background()            // 创建根context
-		ctx, cancel := context.WithCancel(ctx) // 创建可取消的context
+		wg := &sync.WaitGroup{}     // 创建WaitGroup用于等待goroutine结束
+		wg.Add(1)                   // 增加计数
+		ctx := context.Background() // 创建根context，停机由 Service.Stop 负责
 
 		fmt.Println("Starting EasySwapSync daemon...")
 
 		onSyncExit := make(chan error, 1) // 服务退出信号通道
+		onShutdown := make(chan struct{}) // 收到信号或服务异常退出时关闭，通知主服务停机
 
 		// ==========================================
 		// 启动主服务goroutine 【同时也是一个子goroutine】
@@ -78,34 +79,95 @@ var DaemonCmd = &cobra.Command{
 				return
 			}
 
+			// 监控和 pprof 服务在主服务停机之后关闭，停机期间 /readyz 返回失败
+			serverCtx, stopServers := context.WithCancel(ctx)
+			defer stopServers()
+
+			// 配置了 http_port 时提供 /metrics、/healthz 和 /readyz
+			if cfg.Monitor.HttpPort > 0 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := s.ServeMonitor(serverCtx); err != nil {
+						xzap.WithContext(ctx).Error("Failed to serve monitor", zap.Error(err))
+					}
+				}()
+			}
+
+			// 开启 admin_cfg 时提供运维管理接口
+			if cfg.AdminCfg.Enable {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := s.ServeAdmin(serverCtx); err != nil {
+						xzap.WithContext(ctx).Error("Failed to serve admin api", zap.Error(err))
+					}
+				}()
+			}
+
 			// 如果配置项 cfg.Monitor.PprofEnable 为 true，
 			// 则启动 pprof 性能分析服务。
-			// 具体来说，http.ListenAndServe(fmt.Sprintf("0.0.0.0:%d", cfg.Monitor.PprofPort), nil) 会在指定端口启动一个 HTTP 服务器，
+			// 具体来说，会在指定端口启动一个使用 http.DefaultServeMux 的 HTTP 服务器，
 			// 暴露 Go 的运行时性能分析接口（如 /debug/pprof），方便开发者通过浏览器或工具远程分析程序的 CPU、内存等性能数据，用于排查和优化性能瓶颈。
 			if cfg.Monitor.PprofEnable {
 				fmt.Println("Starting pprof server on port：", cfg.Monitor.PprofPort)
-				http.ListenAndServe(fmt.Sprintf("0.0.0.0:%d", cfg.Monitor.PprofPort), nil) // 启动pprof服务
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					pprofServer := &http.Server{Addr: fmt.Sprintf("0.0.0.0:%d", cfg.Monitor.PprofPort)} // 启动pprof服务
+					if err := service.ServeHTTP(serverCtx, pprofServer); err != nil {
+						xzap.WithContext(ctx).Error("Failed to serve pprof", zap.Error(err))
+					}
+				}()
+			}
+
+			// 常驻循环连续失败次数过多时退出进程，由编排系统重新调度
+			select {
+			case err := <-s.Fatal():
+				onSyncExit <- err
+				<-onShutdown
+			case <-onShutdown:
+			}
+
+			// 优雅停机：等待当前区块区间提交、队列处理完，再关闭数据库
+			timeout := cfg.Supervisor.ShutdownTimeout
+			if timeout <= 0 {
+				timeout = service.DefaultShutdownTimeout
+			}
+			stopCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+			defer cancel()
+			if err := s.Stop(stopCtx); err != nil {
+				xzap.WithContext(ctx).Error("Failed to stop sync server gracefully", zap.Error(err))
+			} else {
+				xzap.WithContext(ctx).Info("sync server stopped")
 			}
 		}()
 
 		// 系统信号通道
-		onSignal := make(chan os.Signal)
+		onSignal := make(chan os.Signal, 1)
 		// 监听中断和终止信号
 		signal.Notify(onSignal, syscall.SIGINT, syscall.SIGTERM)
+		var exitErr error
 		select {
 		case sig := <-onSignal: // 收到信号
-			switch sig {
-			case syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM:
-				cancel()                                                                         // 取消context，优雅退出
-				xzap.WithContext(ctx).Info("Exit by signal", zap.String("signal", sig.String())) // 记录信号退出日志
-			}
+			xzap.WithContext(ctx).Info("Exit by signal", zap.String("signal", sig.String())) // 记录信号退出日志
 		case err := <-onSyncExit: // 服务异常退出
-			cancel()                                                     // 取消context
+			exitErr = err
 			xzap.WithContext(ctx).Error("Exit by error", zap.Error(err)) // 记录错误日志
 		}
-		wg.Wait() // 等待所有goroutine结束
+		close(onShutdown) // 通知主服务停机
+		// 停机期间再次收到信号时直接退出
+		go func() {
+			sig := <-onSignal
+			xzap.WithContext(ctx).Warn("Force exit by signal", zap.String("signal", sig.String()))
+			os.Exit(1)
+		}()
+		wg.Wait() // 等待主服务停机以及监控和 pprof 服务关闭
 
 		fmt.Println("==== daemon Run end ====")
+		if exitErr != nil {
+			os.Exit(1) // 非零退出码，便于编排系统感知并重新调度
+		}
 
 	},
 }
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+)
+
+var initStartBlock uint64
+
+// InitCmd 创建同步循环启动时要求存在的 ob_indexed_status 记录，已存在的记录不修改
+var InitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "create missing sync status rows.",
+	Long:  "create the ob_indexed_status rows the sync loops require. existing rows are left untouched.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.UnmarshalCmdConfig()
+		if err != nil {
+			return errors.Wrap(err, "failed on unmarshal config")
+		}
+		if _, err := xzap.SetUp(*cfg.Log); err != nil {
+			return errors.Wrap(err, "failed on set up logger")
+		}
+
+		// 默认从配置的合约部署区块开始同步；未配置时必须用 --block 指定，从链头开始会漏掉之前的订单
+		startBlock, deployed := cfg.ChainCfg.StartBlock(cfg.ContractCfg.DexAddress)
+		if cmd.Flags().Changed("block") {
+			startBlock = initStartBlock
+		} else if !deployed {
+			return errors.Errorf("no deployment block configured for %s on %s, pass --block", cfg.ContractCfg.DexAddress, cfg.ChainCfg.Name)
+		}
+
+		indexer, _, err := newCmdIndexer(context.Background(), cfg)
+		if err != nil {
+			return err
+		}
+
+		created, err := indexer.EnsureCursors(startBlock)
+		if err != nil {
+			return err
+		}
+		if len(created) == 0 {
+			fmt.Println("all sync status rows exist")
+			return nil
+		}
+		fmt.Println("created sync status rows for index types:", created, "start block:", startBlock)
+		return nil
+	},
+}
+
+func init() {
+	InitCmd.Flags().Uint64Var(&initStartBlock, "block", 0, "first block to sync, required unless the deployment block is configured in chain_cfg")
+	rootCmd.AddCommand(InitCmd)
+}
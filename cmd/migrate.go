@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+
+	"github.com/yaoxc/EasySwapSync/db/migrations"
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/config"
+)
+
+var (
+	migrateChains  []string
+	migrateSteps   int
+	migrateVersion int
+)
+
+// MigrateCmd 执行 db/migrations 下内置的表结构变更。链相关的表按 --chain 分别生成，
+// 默认是配置中的链；已执行的版本记录在 ob_schema_migration，daemon 启动时检查版本一致
+var MigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "manage the database schema.",
+	Long:  "apply, roll back and inspect the versioned schema migrations. chain tables are generated for every chain given by --chain, the configured chain by default.",
+}
+
+// MigrateUpCmd 执行未执行的变更
+var MigrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "apply pending migrations.",
+	Long:  "apply pending migrations, at most --steps versions when it is greater than 0.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		migrator, chains, err := newMigrator()
+		if err != nil {
+			return err
+		}
+		applied, err := migrator.Up(chains, migrateSteps)
+		fmt.Println("applied versions:", applied)
+		return err
+	},
+}
+
+// MigrateDownCmd 回滚已执行的变更
+var MigrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "roll back applied migrations.",
+	Long:  "roll back the latest --steps applied versions, all of them when it is 0. shared tables are kept while other chains still use them.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		migrator, chains, err := newMigrator()
+		if err != nil {
+			return err
+		}
+		reverted, err := migrator.Down(chains, migrateSteps)
+		fmt.Println("reverted versions:", reverted)
+		return err
+	},
+}
+
+// MigrateForceCmd 不执行变更，直接设置版本记录
+var MigrateForceCmd = &cobra.Command{
+	Use:   "force",
+	Short: "set the schema version without running migrations.",
+	Long:  "set the schema version without running migrations and clear the dirty flag. use it after fixing a failed migration by hand, or with --version 9 on a database created from the old hand-applied sql files.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !cmd.Flags().Changed("version") {
+			return errors.New("--version is required")
+		}
+		migrator, chains, err := newMigrator()
+		if err != nil {
+			return err
+		}
+		return migrator.Force(chains, migrateVersion)
+	},
+}
+
+// MigrateStatusCmd 打印各 scope 的版本
+var MigrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "print the schema version.",
+	Long:  "print the applied and required schema version of the shared tables and every chain.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		migrator, chains, err := newMigrator()
+		if err != nil {
+			return err
+		}
+		statuses, err := migrator.Status(chains)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SCOPE\tVERSION\tREQUIRED\tDIRTY")
+		for _, status := range statuses {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%v\n", status.Scope, status.Version, status.Latest, status.Dirty)
+		}
+		return w.Flush()
+	},
+}
+
+func newMigrator() (*migrations.Migrator, []string, error) {
+	cfg, err := config.UnmarshalCmdConfig()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed on unmarshal config")
+	}
+	if _, err := xzap.SetUp(*cfg.Log); err != nil {
+		return nil, nil, errors.Wrap(err, "failed on set up logger")
+	}
+	chains := migrateChains
+	if len(chains) == 0 {
+		chains = []string{cfg.ChainCfg.Name}
+	}
+	migrator, err := migrations.New(context.Background(), model.NewDB(cfg.DB))
+	if err != nil {
+		return nil, nil, err
+	}
+	return migrator, chains, nil
+}
+
+func init() {
+	flags := MigrateCmd.PersistentFlags()
+	flags.StringSliceVar(&migrateChains, "chain", nil, "chains to generate tables for, defaults to the configured chain")
+	MigrateUpCmd.Flags().IntVar(&migrateSteps, "steps", 0, "number of versions to apply, 0 means all")
+	MigrateDownCmd.Flags().IntVar(&migrateSteps, "steps", 1, "number of versions to roll back, 0 means all")
+	MigrateForceCmd.Flags().IntVar(&migrateVersion, "version", 0, "schema version to record")
+	MigrateCmd.AddCommand(MigrateUpCmd, MigrateDownCmd, MigrateForceCmd, MigrateStatusCmd)
+	rootCmd.AddCommand(MigrateCmd)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+	"github.com/yaoxc/EasySwapSync/service/itemprice"
+)
+
+// ReconcileItemsCmd 根据 ob_order 与 ob_activity 重建 item 的 list_price、list_time 和 sale_price，
+// 用于修复历史数据或事件处理失败导致的不一致
+var ReconcileItemsCmd = &cobra.Command{
+	Use:   "reconcile-items",
+	Short: "rebuild item list and sale prices from orders and activities.",
+	Long:  "rebuild item list and sale prices from orders and activities.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.UnmarshalCmdConfig()
+		if err != nil {
+			return errors.Wrap(err, "failed on unmarshal config")
+		}
+		if _, err := xzap.SetUp(*cfg.Log); err != nil {
+			return errors.Wrap(err, "failed on set up logger")
+		}
+
+		db := model.NewDB(cfg.DB)
+		currencies, err := currency.New(cfg.ContractCfg, cfg.Currencies)
+		if err != nil {
+			return errors.Wrap(err, "failed on create currency registry")
+		}
+		if err := itemprice.Rebuild(context.Background(), db, cfg.ProjectCfg.Name, cfg.ChainCfg.Name, currencies); err != nil {
+			return err
+		}
+
+		fmt.Println("item prices reconciled")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ReconcileItemsCmd)
+}
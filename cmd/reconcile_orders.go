@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/yaoxc/EasySwapBase/chain/chainclient"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/reconciler"
+)
+
+var (
+	reconcileSample int
+	reconcileRepair bool
+)
+
+// ReconcileOrdersCmd 通过 eth_call 读取订单簿合约存储，与 ob_order 中的有效订单对账并输出差异，
+// --repair 时按合约状态修正库里的订单
+var ReconcileOrdersCmd = &cobra.Command{
+	Use:   "reconcile-orders",
+	Short: "compare active orders with the order book contract storage.",
+	Long:  "compare active orders with the order book contract storage, report missing, extra, status and price differences and optionally repair them.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.UnmarshalCmdConfig()
+		if err != nil {
+			return errors.Wrap(err, "failed on unmarshal config")
+		}
+		if _, err := xzap.SetUp(*cfg.Log); err != nil {
+			return errors.Wrap(err, "failed on set up logger")
+		}
+
+		ctx := context.Background()
+		db := model.NewDB(cfg.DB)
+		chainClient, err := chainclient.New(int(cfg.ChainCfg.ID), cfg.AnkrCfg.HttpsUrl+cfg.AnkrCfg.ApiKey)
+		if err != nil {
+			return errors.Wrap(err, "failed on create evm client")
+		}
+		r, err := reconciler.New(ctx, cfg, db, chainClient)
+		if err != nil {
+			return err
+		}
+
+		report, err := r.Run(reconciler.Options{Sample: reconcileSample, Repair: reconcileRepair})
+		if err != nil {
+			return err
+		}
+		for _, diff := range report.Diffs {
+			fmt.Printf("%-8s order=%s collection=%s token=%s side=%d db_price=%s chain_price=%s repaired=%t %s\n",
+				diff.Kind, diff.OrderId, diff.CollectionAddress, diff.TokenId, diff.Side,
+				diff.DbPrice.String(), diff.ChainPrice.String(), diff.Repaired, diff.Detail)
+		}
+		fmt.Printf("checked %d orders at block %d, found %d differences\n", report.Checked, report.Block, len(report.Diffs))
+		return nil
+	},
+}
+
+func init() {
+	ReconcileOrdersCmd.Flags().IntVar(&reconcileSample, "sample", 0, "number of random active orders to check, 0 checks all")
+	ReconcileOrdersCmd.Flags().BoolVar(&reconcileRepair, "repair", false, "repair orders to match the contract state")
+	rootCmd.AddCommand(ReconcileOrdersCmd)
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/ordermanager"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service"
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+	"github.com/yaoxc/EasySwapSync/service/itemprice"
+	"github.com/yaoxc/EasySwapSync/service/orderbookindexer"
+)
+
+var (
+	replayFromBlock uint64
+	replayToBlock   uint64
+	replayWipe      bool
+	replayPublish   bool
+)
+
+// ReplayCmd 从 ob_raw_log 归档重放订单簿事件，不访问 RPC；
+// --wipe 会先清空订单、活动和成交记录，只能用于从头开始的全量重放，且归档需要从合约部署区块开始；
+// 默认不向订单管理器和价格更新队列推送重放的事件，--publish 时推送
+var ReplayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "replay orderbook events from the raw log archive.",
+	Long:  "replay orderbook events from the raw log archive.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if replayWipe && replayFromBlock > 0 {
+			return errors.New("--wipe can only be used when replaying from the first archived block")
+		}
+
+		cfg, err := config.UnmarshalCmdConfig()
+		if err != nil {
+			return errors.Wrap(err, "failed on unmarshal config")
+		}
+		if _, err := xzap.SetUp(*cfg.Log); err != nil {
+			return errors.Wrap(err, "failed on set up logger")
+		}
+
+		ctx := context.Background()
+		db := model.NewDB(cfg.DB)
+		kvStore := service.NewKvStore(cfg)
+		currencies, err := currency.New(cfg.ContractCfg, cfg.Currencies)
+		if err != nil {
+			return errors.Wrap(err, "failed on create currency registry")
+		}
+		orderManager := ordermanager.New(ctx, db, kvStore, cfg.ChainCfg.Name, cfg.ProjectCfg.Name)
+		// 不传 chainClient、导入器和元数据抓取器，重放过程不访问链
+		indexer, err := orderbookindexer.New(ctx, cfg, db, kvStore, nil, cfg.ChainCfg.ID, cfg.ChainCfg.Name, orderManager, nil, nil, currencies)
+		if err != nil {
+			return errors.Wrap(err, "failed on create orderbook indexer")
+		}
+
+		indexer.SetReplayMode(!replayPublish)
+		if replayWipe {
+			if err := indexer.WipeDerivedTables(); err != nil {
+				return err
+			}
+		}
+		replayed, err := indexer.Replay(replayFromBlock, replayToBlock)
+		if err != nil {
+			return err
+		}
+		// 重放后按最终订单状态重建 item 价格
+		if err := itemprice.Rebuild(ctx, db, cfg.ProjectCfg.Name, cfg.ChainCfg.Name, currencies); err != nil {
+			return err
+		}
+
+		fmt.Println("replayed logs:", replayed)
+		return nil
+	},
+}
+
+func init() {
+	flags := ReplayCmd.Flags()
+	flags.Uint64Var(&replayFromBlock, "from", 0, "first block to replay")
+	flags.Uint64Var(&replayToBlock, "to", 0, "last block to replay, 0 means the end of the archive")
+	flags.BoolVar(&replayWipe, "wipe", false, "wipe orders, activities and order fills before replaying, requires the archive to start at the deployment block")
+	flags.BoolVar(&replayPublish, "publish", false, "push replayed events into the live order manager and price update queues")
+	rootCmd.AddCommand(ReplayCmd)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/yaoxc/EasySwapBase/chain/chainclient"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/ordermanager"
+	"github.com/yaoxc/EasySwapBase/stores/gdb"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service"
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+	"github.com/yaoxc/EasySwapSync/service/itemprice"
+	"github.com/yaoxc/EasySwapSync/service/orderbookindexer"
+)
+
+var (
+	snapshotBlock       uint64
+	snapshotCollections []string
+)
+
+// SnapshotCmd 从订单簿合约当前状态初始化订单，不重放历史日志，完成后同步从快照区块之后继续；
+// 用于在已有合约上全新部署本服务
+var SnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "bootstrap live orders from the order book contract state.",
+	Long:  "walk the order book contract for each collection and side, insert all live orders and move the event cursor to the snapshot block.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.UnmarshalCmdConfig()
+		if err != nil {
+			return errors.Wrap(err, "failed on unmarshal config")
+		}
+		if _, err := xzap.SetUp(*cfg.Log); err != nil {
+			return errors.Wrap(err, "failed on set up logger")
+		}
+
+		ctx := context.Background()
+		db := model.NewDB(cfg.DB)
+		kvStore := service.NewKvStore(cfg)
+		chainClient, err := chainclient.New(int(cfg.ChainCfg.ID), cfg.AnkrCfg.HttpsUrl+cfg.AnkrCfg.ApiKey)
+		if err != nil {
+			return errors.Wrap(err, "failed on create evm client")
+		}
+		currencies, err := currency.New(cfg.ContractCfg, cfg.Currencies)
+		if err != nil {
+			return errors.Wrap(err, "failed on create currency registry")
+		}
+		orderManager := ordermanager.New(ctx, db, kvStore, cfg.ChainCfg.Name, cfg.ProjectCfg.Name)
+		indexer, err := orderbookindexer.New(ctx, cfg, db, kvStore, chainClient, cfg.ChainCfg.ID, cfg.ChainCfg.Name, orderManager, nil, nil, currencies)
+		if err != nil {
+			return errors.Wrap(err, "failed on create orderbook indexer")
+		}
+
+		// 默认使用与同步循环相同的安全区块高度
+		blockNumber := snapshotBlock
+		if blockNumber == 0 {
+			currentBlockNum, err := chainClient.BlockNumber()
+			if err != nil {
+				return errors.Wrap(err, "failed on get current block number")
+			}
+			blockNumber = currentBlockNum - orderbookindexer.MultiChainMaxBlockDifference[cfg.ChainCfg.Name]
+		}
+
+		// collection 只在首次出现于 LogMake 时导入，全新部署时 ob_collection 为空，需要通过 --collection 指定
+		collections := snapshotCollections
+		if len(collections) == 0 {
+			if err := db.WithContext(ctx).Table(gdb.GetMultiProjectCollectionTableName(cfg.ProjectCfg.Name, cfg.ChainCfg.Name)).
+				Pluck("address", &collections).Error; err != nil {
+				return errors.Wrap(err, "failed on query collections")
+			}
+			if len(collections) == 0 {
+				return errors.New("no known collections, pass the collections to snapshot with --collection")
+			}
+		}
+
+		inserted, err := indexer.Snapshot(collections, blockNumber)
+		if err != nil {
+			return err
+		}
+		// 没有写入任何订单时很可能漏掉了 collection，移动游标会跳过全部历史
+		if inserted == 0 && len(snapshotCollections) == 0 {
+			return errors.New("no live orders found in known collections, refusing to move the cursor; pass the collections to snapshot with --collection")
+		}
+		if err := indexer.SetCursor(blockNumber + 1); err != nil {
+			return err
+		}
+		if err := itemprice.Rebuild(ctx, db, cfg.ProjectCfg.Name, cfg.ChainCfg.Name, currencies); err != nil {
+			return err
+		}
+
+		fmt.Printf("snapshot at block %d: %d collections, %d live orders, indexing continues from block %d\n",
+			blockNumber, len(collections), inserted, blockNumber+1)
+		return nil
+	},
+}
+
+func init() {
+	flags := SnapshotCmd.Flags()
+	flags.Uint64Var(&snapshotBlock, "block", 0, "snapshot block, 0 means the latest safe block")
+	flags.StringSliceVar(&snapshotCollections, "collection", nil, "collections to snapshot, default all known collections; required on a fresh database")
+	rootCmd.AddCommand(SnapshotCmd)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/yaoxc/EasySwapBase/chain/chainclient"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/base"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/comm"
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/orderbookindexer"
+)
+
+// indexTypeNames sync status 展示的同步记录
+var indexTypeNames = map[int32]string{
+	orderbookindexer.EventIndexType:     "orderbook events",
+	comm.CollectionFloorChangeIndexType: "collection floor",
+}
+
+// StatusCmd 打印链头、各同步记录的进度和落后区块数，以及地板价任务最近一次运行时间
+var StatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "print sync progress.",
+	Long:  "print the chain head, the orderbook event cursor and lag, and the last run of the floor price job.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.UnmarshalCmdConfig()
+		if err != nil {
+			return errors.Wrap(err, "failed on unmarshal config")
+		}
+		if _, err := xzap.SetUp(*cfg.Log); err != nil {
+			return errors.Wrap(err, "failed on set up logger")
+		}
+
+		ctx := context.Background()
+		db := model.NewDB(cfg.DB)
+		chainClient, err := chainclient.New(int(cfg.ChainCfg.ID), cfg.AnkrCfg.HttpsUrl+cfg.AnkrCfg.ApiKey)
+		if err != nil {
+			return errors.Wrap(err, "failed on create evm client")
+		}
+		head, err := chainClient.BlockNumber()
+		if err != nil {
+			return errors.Wrap(err, "failed on get current block number")
+		}
+		// 同步循环只同步到安全区块高度
+		safeHead := head - orderbookindexer.MultiChainMaxBlockDifference[cfg.ChainCfg.Name]
+
+		var statuses []base.IndexedStatus
+		if err := db.WithContext(ctx).Table(base.IndexedStatusTableName()).
+			Where("chain_id = ?", cfg.ChainCfg.ID).
+			Find(&statuses).Error; err != nil {
+			return errors.Wrap(err, "failed on query index status")
+		}
+		byType := make(map[int32]base.IndexedStatus, len(statuses))
+		for _, status := range statuses {
+			byType[status.IndexType] = status
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "chain\t%s (id %d)\n", cfg.ChainCfg.Name, cfg.ChainCfg.ID)
+		fmt.Fprintf(w, "head\t%d (safe %d)\n", head, safeHead)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "INDEX TYPE\tNAME\tNEXT BLOCK\tLAG\tLAST RUN")
+		for _, indexType := range orderbookindexer.CursorIndexTypes {
+			status, ok := byType[indexType]
+			if !ok {
+				fmt.Fprintf(w, "%d\t%s\tmissing, run `sync init`\t-\t-\n", indexType, indexTypeNames[indexType])
+				continue
+			}
+			nextBlock, lag, lastRun := "-", "-", "-"
+			if indexType == orderbookindexer.EventIndexType {
+				nextBlock = fmt.Sprint(status.LastIndexedBlock)
+				lag = fmt.Sprint(blockLag(safeHead, uint64(status.LastIndexedBlock)))
+			}
+			if indexType == comm.CollectionFloorChangeIndexType {
+				lastRun = "never"
+				if status.LastIndexedTime > 0 {
+					lastRun = time.Unix(status.LastIndexedTime, 0).Format(time.RFC3339)
+				}
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", indexType, indexTypeNames[indexType], nextBlock, lag, lastRun)
+		}
+		return w.Flush()
+	},
+}
+
+// blockLag 下一个待同步区块落后安全区块高度的区块数
+func blockLag(safeHead uint64, nextBlock uint64) uint64 {
+	if nextBlock > safeHead {
+		return 0
+	}
+	return safeHead - nextBlock + 1
+}
+
+func init() {
+	rootCmd.AddCommand(StatusCmd)
+}
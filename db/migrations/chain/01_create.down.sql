@@ -0,0 +1,9 @@
+drop table if exists ob_order_{{.Chain}};
+drop table if exists ob_item_trait_{{.Chain}};
+drop table if exists ob_item_external_{{.Chain}};
+drop table if exists ob_item_{{.Chain}};
+drop table if exists ob_global_collection_{{.Chain}};
+drop table if exists ob_collection_import_record_{{.Chain}};
+drop table if exists ob_collection_floor_price_{{.Chain}};
+drop table if exists ob_collection_{{.Chain}};
+drop table if exists ob_activity_{{.Chain}};
@@ -1,4 +1,4 @@
-create table ob_activity_sepolia
+create table ob_activity_{{.Chain}}
 (
     id                 bigint auto_increment comment '主键'
         primary key,
@@ -22,7 +22,7 @@ create table ob_activity_sepolia
 )
     collate = utf8mb4_general_ci;
 
-create table ob_collection_sepolia
+create table ob_collection_{{.Chain}}
 (
     id                 bigint auto_increment comment '主键'
         primary key,
@@ -58,15 +58,15 @@ create table ob_collection_sepolia
     collate = utf8mb4_general_ci;
 
 create index index_collection_token_type
-    on ob_activity_sepolia (collection_address, token_id, activity_type);
+    on ob_activity_{{.Chain}} (collection_address, token_id, activity_type);
 
 create index index_hash_collection_token_type
-    on ob_activity_sepolia (tx_hash, collection_address, token_id, activity_type);
+    on ob_activity_{{.Chain}} (tx_hash, collection_address, token_id, activity_type);
 
 create index index_tx_collection_token_type_time
-    on ob_activity_sepolia (tx_hash, collection_address, token_id, activity_type, event_time);
+    on ob_activity_{{.Chain}} (tx_hash, collection_address, token_id, activity_type, event_time);
 
-create table ob_collection_floor_price_sepolia
+create table ob_collection_floor_price_{{.Chain}}
 (
     id                 bigint auto_increment comment '主键'
         primary key,
@@ -81,12 +81,12 @@ create table ob_collection_floor_price_sepolia
     collate = utf8mb4_general_ci;
 
 create index index_collection_address
-    on ob_collection_floor_price_sepolia (collection_address);
+    on ob_collection_floor_price_{{.Chain}} (collection_address);
 
 create index index_event_time
-    on ob_collection_floor_price_sepolia (event_time);
+    on ob_collection_floor_price_{{.Chain}} (event_time);
 
-create table ob_collection_import_record_sepolia
+create table ob_collection_import_record_{{.Chain}}
 (
     id                 bigint auto_increment comment '主键'
         primary key,
@@ -98,7 +98,7 @@ create table ob_collection_import_record_sepolia
 )
     collate = utf8mb4_general_ci;
 
-create table ob_global_collection_sepolia
+create table ob_global_collection_{{.Chain}}
 (
     id                 bigint auto_increment comment '主键'
         primary key,
@@ -110,7 +110,7 @@ create table ob_global_collection_sepolia
 )
     collate = utf8mb4_general_ci;
 
-create table ob_item_sepolia
+create table ob_item_{{.Chain}}
 (
     id                 bigint auto_increment comment '主键'
         primary key,
@@ -134,18 +134,18 @@ create table ob_item_sepolia
     collate = utf8mb4_general_ci;
 
 create index index_collection_item_name
-    on ob_item_sepolia (collection_address, token_id, name);
+    on ob_item_{{.Chain}} (collection_address, token_id, name);
 
 create index index_collection_owner
-    on ob_item_sepolia (collection_address, owner);
+    on ob_item_{{.Chain}} (collection_address, owner);
 
 create index index_collection_token_owner
-    on ob_item_sepolia (collection_address, token_id, owner);
+    on ob_item_{{.Chain}} (collection_address, token_id, owner);
 
 create index index_owner
-    on ob_item_sepolia (owner);
+    on ob_item_{{.Chain}} (owner);
 
-create table ob_item_external_sepolia
+create table ob_item_external_{{.Chain}}
 (
     id                  bigint auto_increment comment '主键'
         primary key,
@@ -168,7 +168,7 @@ create table ob_item_external_sepolia
 )
     collate = utf8mb4_general_ci;
 
-create table ob_item_trait_sepolia
+create table ob_item_trait_{{.Chain}}
 (
     id                 bigint auto_increment comment '主键'
         primary key,
@@ -182,15 +182,15 @@ create table ob_item_trait_sepolia
     collate = utf8mb4_general_ci;
 
 create index index_collection_token
-    on ob_item_trait_sepolia (collection_address, token_id);
+    on ob_item_trait_{{.Chain}} (collection_address, token_id);
 
 create index index_collection_trait_value
-    on ob_item_trait_sepolia (collection_address, trait, trait_value);
+    on ob_item_trait_{{.Chain}} (collection_address, trait, trait_value);
 
 create index index_trait_value
-    on ob_item_trait_sepolia (trait, trait_value);
+    on ob_item_trait_{{.Chain}} (trait, trait_value);
 
-create table ob_order_sepolia
+create table ob_order_{{.Chain}}
 (
     id                 bigint auto_increment comment '主键'
         primary key,
@@ -217,34 +217,7 @@ create table ob_order_sepolia
     collate = utf8mb4_general_ci;
 
 create index index_collection_maker_status_type_market_token_id
-    on ob_order_sepolia (collection_address, maker, order_status, order_type, marketplace_id, token_id);
+    on ob_order_{{.Chain}} (collection_address, maker, order_status, order_type, marketplace_id, token_id);
 
 create index index_collection_token
-    on ob_order_sepolia (collection_address, token_id);
-
-create table ob_user
-(
-    id          bigint auto_increment comment '主键'
-        primary key,
-    address     varchar(66)          not null comment '用户地址',
-    is_allowed  tinyint(1) default 0 not null comment '是否允许用户访问',
-    is_signed   tinyint(1) default 0 null,
-    create_time bigint               null comment '创建时间',
-    update_time bigint               null comment '更新时间',
-    constraint index_address
-        unique (address)
-)
-    collate = utf8mb4_general_ci;
-
-create table ob_indexed_status
-(
-    id                 bigint auto_increment comment '主键'
-        primary key,
-    chain_id           bigint  default 1 not null comment '链id (1:以太坊, 56: BSC)',
-    last_indexed_block bigint  default 0 null comment '区块号',
-    last_indexed_time  bigint            null comment '最后同步时间戳',
-    index_type         tinyint default 0 not null comment '0:activity, 1:trade info, 2:listing,3:sale,4:exchange,5:floor price',
-    create_time        bigint            null,
-    update_time        bigint            null
-)
-    collate = utf8mb4_general_ci;
\ No newline at end of file
+    on ob_order_{{.Chain}} (collection_address, token_id);
@@ -0,0 +1,2 @@
+drop table if exists ob_collection_trait_{{.Chain}};
+drop table if exists ob_item_rarity_{{.Chain}};
@@ -0,0 +1,34 @@
+create table ob_item_rarity_{{.Chain}}
+(
+    id                 bigint auto_increment comment '主键'
+        primary key,
+    collection_address varchar(42)                  not null comment '合约地址',
+    token_id           varchar(128)                 not null comment 'token_id',
+    rarity_score       decimal(30, 10) default 0    not null comment '稀有度得分',
+    rarity_rank        bigint          default 0    not null comment '稀有度排名(1最稀有)',
+    create_time        bigint                       null comment '创建时间',
+    update_time        bigint                       null comment '更新时间',
+    constraint index_collection_token
+        unique (collection_address, token_id)
+)
+    collate = utf8mb4_general_ci;
+
+create index index_collection_rank
+    on ob_item_rarity_{{.Chain}} (collection_address, rarity_rank);
+
+create table ob_collection_trait_{{.Chain}}
+(
+    id                 bigint auto_increment comment '主键'
+        primary key,
+    collection_address varchar(42)                  not null comment '合约地址',
+    trait              varchar(128)                 not null comment '属性名称',
+    trait_value        varchar(512)                 not null comment '属性值',
+    item_count         bigint          default 0    not null comment '拥有该属性值的item数量',
+    frequency          decimal(20, 10) default 0    not null comment 'item_count / collection item 总数',
+    floor_price        decimal(30)                  null comment '该属性值下listing的最低价格',
+    create_time        bigint                       null comment '创建时间',
+    update_time        bigint                       null comment '更新时间',
+    constraint index_collection_trait_value
+        unique (collection_address, trait, trait_value)
+)
+    collate = utf8mb4_general_ci;
@@ -0,0 +1 @@
+drop table if exists ob_order_fill_{{.Chain}};
@@ -0,0 +1,23 @@
+create table ob_order_fill_{{.Chain}}
+(
+    id                 bigint auto_increment comment '主键'
+        primary key,
+    order_id           varchar(66)              not null comment '订单ID',
+    tx_hash            varchar(66)              not null comment '成交交易hash',
+    log_index          bigint                   not null comment 'LogMatch 在区块中的日志序号',
+    collection_address varchar(42)              not null comment '合约地址',
+    token_id           varchar(128)             not null comment '实际成交的 token_id',
+    quantity           bigint      default 1    not null comment '成交数量',
+    price              decimal(30) default 0    not null comment '成交价格',
+    counterparty       varchar(42)              not null comment '对手方订单的 maker',
+    block_number       bigint      default 0    not null comment '区块号',
+    event_time         bigint                   null comment '成交时间',
+    create_time        bigint                   null comment '创建时间',
+    update_time        bigint                   null comment '更新时间',
+    constraint index_order_tx_log
+        unique (order_id, tx_hash, log_index)
+)
+    collate = utf8mb4_general_ci;
+
+create index index_collection_token
+    on ob_order_fill_{{.Chain}} (collection_address, token_id);
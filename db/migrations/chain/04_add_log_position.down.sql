@@ -0,0 +1,13 @@
+alter table ob_order_{{.Chain}}
+    drop column log_index,
+    drop column block_hash,
+    drop column tx_hash;
+
+alter table ob_activity_{{.Chain}}
+    drop index index_tx_log_collection_token_type,
+    add constraint index_tx_collection_token_type
+        unique (tx_hash, collection_address, token_id, activity_type);
+
+alter table ob_activity_{{.Chain}}
+    drop column log_index,
+    drop column block_hash;
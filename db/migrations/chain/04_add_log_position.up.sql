@@ -0,0 +1,14 @@
+alter table ob_activity_{{.Chain}}
+    add block_hash varchar(66) default '' not null comment '区块hash' after tx_hash,
+    add log_index  bigint      default 0  not null comment '日志在区块中的序号' after block_hash;
+
+-- 同一笔交易中多条日志可能对应同一 token 的同类活动，去重键加入 log_index
+alter table ob_activity_{{.Chain}}
+    drop index index_tx_collection_token_type,
+    add constraint index_tx_log_collection_token_type
+        unique (tx_hash, log_index, collection_address, token_id, activity_type);
+
+alter table ob_order_{{.Chain}}
+    add tx_hash    varchar(66) default '' not null comment 'LogMake 交易hash',
+    add block_hash varchar(66) default '' not null comment 'LogMake 区块hash',
+    add log_index  bigint      default 0  not null comment 'LogMake 在区块中的序号';
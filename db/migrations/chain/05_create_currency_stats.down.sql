@@ -0,0 +1,2 @@
+drop view if exists ob_collection_eth_stats_{{.Chain}};
+drop table if exists ob_collection_currency_stats_{{.Chain}};
@@ -0,0 +1,26 @@
+create table ob_collection_currency_stats_{{.Chain}}
+(
+    id                 bigint auto_increment comment '主键'
+        primary key,
+    collection_address varchar(42)            not null comment '合约地址',
+    currency_address   varchar(42)            not null comment '计价代币地址',
+    floor_price        decimal(30)            null comment '该币种有效listing的最低价',
+    floor_price_eth    decimal(30)            null comment '地板价折合ETH(wei)',
+    volume_total       decimal(30) default 0  not null comment '该币种总交易量',
+    volume_total_eth   decimal(30) default 0  not null comment '总交易量折合ETH(wei)',
+    sale_count         bigint      default 0  not null comment '成交次数',
+    create_time        bigint                 null comment '创建时间',
+    update_time        bigint                 null comment '更新时间',
+    constraint index_collection_currency
+        unique (collection_address, currency_address)
+)
+    collate = utf8mb4_general_ci;
+
+-- 折合 ETH 后的 collection 地板价与交易量
+create view ob_collection_eth_stats_{{.Chain}} as
+select collection_address,
+       min(floor_price_eth)  as floor_price_eth,
+       sum(volume_total_eth) as volume_total_eth,
+       sum(sale_count)       as sale_count
+from ob_collection_currency_stats_{{.Chain}}
+group by collection_address;
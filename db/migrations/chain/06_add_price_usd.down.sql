@@ -0,0 +1,5 @@
+alter table ob_collection_floor_price_{{.Chain}}
+    drop column price_usd;
+
+alter table ob_activity_{{.Chain}}
+    drop column price_usd;
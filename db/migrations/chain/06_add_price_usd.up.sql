@@ -0,0 +1,5 @@
+alter table ob_activity_{{.Chain}}
+    add price_usd decimal(30, 10) null comment '成交时的美元价值';
+
+alter table ob_collection_floor_price_{{.Chain}}
+    add price_usd decimal(30, 10) null comment '地板价的美元价值';
@@ -0,0 +1 @@
+drop table if exists ob_raw_log_{{.Chain}};
@@ -0,0 +1,19 @@
+create table ob_raw_log_{{.Chain}}
+(
+    id           bigint auto_increment comment '主键'
+        primary key,
+    address      varchar(42)  not null comment '合约地址',
+    topics       varchar(300) not null comment 'topic 列表，逗号分隔',
+    data         mediumtext   not null comment 'data 的十六进制编码',
+    block_number bigint       not null comment '区块号',
+    block_hash   varchar(66)  not null comment '区块hash',
+    block_time   bigint       not null comment '区块时间戳',
+    tx_hash      varchar(66)  not null comment '交易hash',
+    tx_index     bigint       not null comment '交易在区块中的序号',
+    log_index    bigint       not null comment '日志在区块中的序号',
+    create_time  bigint       null comment '创建时间',
+    update_time  bigint       null comment '更新时间',
+    constraint index_block_log
+        unique (block_number, log_index)
+)
+    collate = utf8mb4_general_ci;
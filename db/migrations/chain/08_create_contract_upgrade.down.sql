@@ -0,0 +1 @@
+drop table if exists ob_contract_upgrade_{{.Chain}};
@@ -0,0 +1,17 @@
+create table ob_contract_upgrade_{{.Chain}}
+(
+    id               bigint auto_increment comment '主键'
+        primary key,
+    contract_address varchar(42) not null comment '代理合约地址',
+    implementation   varchar(42) not null comment '新实现合约地址',
+    block_number     bigint      not null comment '升级所在区块号',
+    tx_hash          varchar(66) not null comment '交易hash',
+    log_index        bigint      not null comment '日志在区块中的序号',
+    abi_version      varchar(32) not null comment '该区块使用的 ABI 版本',
+    compatible       tinyint(1)  not null comment '新实现的事件签名是否与 ABI 一致',
+    create_time      bigint      null comment '创建时间',
+    update_time      bigint      null comment '更新时间',
+    constraint index_tx_log
+        unique (tx_hash, log_index)
+)
+    collate = utf8mb4_general_ci;
@@ -0,0 +1,3 @@
+drop table if exists ob_webhook_dead_letter_{{.Chain}};
+drop table if exists ob_webhook_delivery_{{.Chain}};
+drop table if exists ob_webhook_subscriber_{{.Chain}};
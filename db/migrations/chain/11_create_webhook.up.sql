@@ -0,0 +1,53 @@
+create table ob_webhook_subscriber_{{.Chain}}
+(
+    id          bigint auto_increment comment '主键'
+        primary key,
+    name        varchar(128)             not null comment '订阅者名称',
+    url         varchar(512)             not null comment '推送地址',
+    secret      varchar(128)             not null comment 'HMAC 签名密钥',
+    collections text                     not null comment '逗号分隔的合约地址，空表示全部',
+    event_types varchar(128) default ''  not null comment '逗号分隔的事件类型，空表示全部',
+    min_price   decimal(30)  default 0   not null comment '折合ETH(wei)的最低价格',
+    create_time bigint                   null comment '创建时间',
+    update_time bigint                   null comment '更新时间'
+)
+    collate = utf8mb4_general_ci;
+
+-- 待推送的 webhook，推送成功后删除；同一事件对同一订阅者只排队一次，重放日志不会重复入队
+create table ob_webhook_delivery_{{.Chain}}
+(
+    id                bigint auto_increment comment '主键'
+        primary key,
+    subscriber_id     bigint                  not null comment '订阅者ID',
+    event_id          varchar(96)             not null comment '事件ID，交易hash-日志序号',
+    event_type        varchar(32)             not null comment '事件类型',
+    payload           text                    not null comment '推送的 JSON',
+    attempts          int          default 0  not null comment '已推送次数',
+    next_attempt_time bigint                  not null comment '下次推送时间(秒)',
+    last_error        varchar(512) default '' not null comment '最近一次失败原因',
+    create_time       bigint                  null comment '创建时间',
+    update_time       bigint                  null comment '更新时间',
+    constraint index_subscriber_event
+        unique (subscriber_id, event_id)
+)
+    collate = utf8mb4_general_ci;
+
+create index index_next_attempt_time
+    on ob_webhook_delivery_{{.Chain}} (next_attempt_time);
+
+create table ob_webhook_dead_letter_{{.Chain}}
+(
+    id            bigint auto_increment comment '主键'
+        primary key,
+    subscriber_id bigint                  not null comment '订阅者ID',
+    event_id      varchar(96)             not null comment '事件ID，交易hash-日志序号',
+    event_type    varchar(32)             not null comment '事件类型',
+    payload       text                    not null comment '推送的 JSON',
+    attempts      int          default 0  not null comment '已推送次数',
+    last_error    varchar(512) default '' not null comment '最后一次失败原因',
+    create_time   bigint                  null comment '创建时间',
+    update_time   bigint                  null comment '更新时间',
+    constraint index_subscriber_event
+        unique (subscriber_id, event_id)
+)
+    collate = utf8mb4_general_ci;
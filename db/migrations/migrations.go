@@ -0,0 +1,523 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// shared 目录是各链共用的表，chain 目录是以 {{.Chain}} 为表名后缀的模板，按链分别执行；
+// 两个目录共用一套版本号，同一版本共用表的变更先执行
+//
+//go:embed shared/*.sql chain/*.sql
+var files embed.FS
+
+const (
+	SchemaTableName = "ob_schema_migration"
+	// SharedScope 共用表的版本记录 scope，链相关的表以链名为 scope
+	SharedScope = "shared"
+)
+
+type Kind string
+
+const (
+	KindShared Kind = "shared"
+	KindChain  Kind = "chain"
+)
+
+const createSchemaTable = `create table if not exists ob_schema_migration
+(
+    scope        varchar(64)          not null comment 'shared 或链名',
+    version      bigint               not null comment '变更版本',
+    name         varchar(128)         not null comment '变更名称',
+    dirty        tinyint(1) default 0 not null comment '变更执行中断，需人工修复后 force',
+    applied_time bigint               not null comment '执行时间',
+    primary key (scope, version)
+)
+    collate = utf8mb4_general_ci`
+
+var (
+	fileNamePattern  = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	chainNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+	statementEnd     = regexp.MustCompile(`;[ \t]*(\r?\n|$)`)
+)
+
+// Migration 一个版本的表结构变更
+type Migration struct {
+	Version int
+	Name    string
+	Kind    Kind
+	up      string
+	down    string
+}
+
+// Scope 在 chain 上执行时的版本记录 scope
+func (m Migration) Scope(chain string) string {
+	if m.Kind == KindShared {
+		return SharedScope
+	}
+	return chain
+}
+
+// Statements 渲染 chain 上的升级或回滚语句
+func (m Migration) Statements(chain string, up bool) ([]string, error) {
+	src := m.down
+	if up {
+		src = m.up
+	}
+	tmpl, err := template.New(fmt.Sprintf("%s/%02d_%s", m.Kind, m.Version, m.Name)).
+		Option("missingkey=error").Parse(src)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on parse migration template")
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct{ Chain string }{chain}); err != nil {
+		return nil, errors.Wrap(err, "failed on render migration template")
+	}
+	return splitStatements(buf.String()), nil
+}
+
+// Load 读取内置的所有变更，按版本排序
+func Load() ([]Migration, error) {
+	var migrations []Migration
+	index := make(map[string]int)
+	for _, kind := range []Kind{KindShared, KindChain} {
+		entries, err := fs.ReadDir(files, string(kind))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed on read %s migrations", kind)
+		}
+		for _, entry := range entries {
+			match := fileNamePattern.FindStringSubmatch(entry.Name())
+			if match == nil {
+				return nil, errors.Errorf("invalid migration file name %s/%s", kind, entry.Name())
+			}
+			version, _ := strconv.Atoi(match[1])
+			content, err := files.ReadFile(path.Join(string(kind), entry.Name()))
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed on read migration %s/%s", kind, entry.Name())
+			}
+
+			key := fmt.Sprintf("%s/%d", kind, version)
+			i, ok := index[key]
+			if !ok {
+				migrations = append(migrations, Migration{Version: version, Name: match[2], Kind: kind})
+				i = len(migrations) - 1
+				index[key] = i
+			}
+			if migrations[i].Name != match[2] {
+				return nil, errors.Errorf("duplicate %s migration version %d", kind, version)
+			}
+			if match[3] == "up" {
+				migrations[i].up = string(content)
+			} else {
+				migrations[i].down = string(content)
+			}
+		}
+	}
+	for _, m := range migrations {
+		if m.up == "" || m.down == "" {
+			return nil, errors.Errorf("%s migration %02d_%s needs both up and down files", m.Kind, m.Version, m.Name)
+		}
+	}
+	// 同一版本共用表的变更在前
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// LatestVersion kind 类变更的最新版本
+func LatestVersion(migrations []Migration, kind Kind) int {
+	var latest int
+	for _, m := range migrations {
+		if m.Kind == kind && m.Version > latest {
+			latest = m.Version
+		}
+	}
+	return latest
+}
+
+// ScopeStatus 一个 scope 已执行的最新版本
+type ScopeStatus struct {
+	Scope   string
+	Version int
+	Latest  int
+	Dirty   bool
+}
+
+type record struct {
+	Scope       string `gorm:"column:scope;primaryKey"`
+	Version     int    `gorm:"column:version;primaryKey"`
+	Name        string `gorm:"column:name"`
+	Dirty       bool   `gorm:"column:dirty"`
+	AppliedTime int64  `gorm:"column:applied_time"`
+}
+
+// history scope -> version -> 版本记录
+type history map[string]map[int]record
+
+func (h history) has(scope string, version int) bool {
+	_, ok := h[scope][version]
+	return ok
+}
+
+func (h history) dirty(scopes []string) error {
+	for _, scope := range scopes {
+		for _, r := range h[scope] {
+			if r.Dirty {
+				return errors.Errorf("schema %s is dirty at version %d, fix it by hand and run `sync migrate force`", scope, r.Version)
+			}
+		}
+	}
+	return nil
+}
+
+// Migrator 执行内置的表结构变更，在 ob_schema_migration 中按 scope 记录已执行的版本
+type Migrator struct {
+	ctx        context.Context
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func New(ctx context.Context, db *gorm.DB) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		ctx:        ctx,
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up 按版本顺序执行 chains 上未执行的变更，steps 大于 0 时最多执行 steps 个版本，返回执行的版本数
+func (m *Migrator) Up(chains []string, steps int) (int, error) {
+	h, err := m.prepare(chains)
+	if err != nil {
+		return 0, err
+	}
+
+	var done int
+	for _, version := range m.versions() {
+		if steps > 0 && done >= steps {
+			break
+		}
+		var ran bool
+		for _, migration := range m.byVersion(version) {
+			for _, chain := range targets(migration, chains) {
+				if h.has(migration.Scope(chain), version) {
+					continue
+				}
+				if err := m.apply(migration, chain, true); err != nil {
+					return done, err
+				}
+				ran = true
+			}
+		}
+		if ran {
+			done++
+		}
+	}
+	return done, nil
+}
+
+// Down 按版本倒序回滚 chains 上已执行的变更，steps 大于 0 时最多回滚 steps 个版本，返回回滚的版本数。
+// 还有其他链在使用时不回滚共用表
+func (m *Migrator) Down(chains []string, steps int) (int, error) {
+	h, err := m.prepare(chains)
+	if err != nil {
+		return 0, err
+	}
+
+	shared := true
+	for scope := range h {
+		if scope != SharedScope && !slices.Contains(chains, scope) && len(h[scope]) > 0 {
+			shared = false
+			xzap.WithContext(m.ctx).Warn("shared migrations are kept, other chains are still migrated", zap.String("chain", scope))
+			break
+		}
+	}
+
+	versions := m.versions()
+	var done int
+	for i := len(versions) - 1; i >= 0; i-- {
+		if steps > 0 && done >= steps {
+			break
+		}
+		migrations := m.byVersion(versions[i])
+		var ran bool
+		// 同一版本先回滚链相关的变更
+		for j := len(migrations) - 1; j >= 0; j-- {
+			migration := migrations[j]
+			if migration.Kind == KindShared && !shared {
+				continue
+			}
+			for _, chain := range targets(migration, chains) {
+				if !h.has(migration.Scope(chain), migration.Version) {
+					continue
+				}
+				if err := m.apply(migration, chain, false); err != nil {
+					return done, err
+				}
+				ran = true
+			}
+		}
+		if ran {
+			done++
+		}
+	}
+	return done, nil
+}
+
+// Force 不执行变更，直接把 chains 和共用表的版本记录设置为 version 并清除 dirty 标记；
+// 用于手工修复中断的变更，或者给按旧版 SQL 文件手工建表的数据库补上版本记录
+func (m *Migrator) Force(chains []string, version int) error {
+	if err := validateChains(chains); err != nil {
+		return err
+	}
+	if err := m.db.WithContext(m.ctx).Exec(createSchemaTable).Error; err != nil {
+		return errors.Wrap(err, "failed on create schema migration table")
+	}
+	for _, migration := range m.migrations {
+		for _, chain := range targets(migration, chains) {
+			scope := migration.Scope(chain)
+			if migration.Version > version {
+				if err := m.db.WithContext(m.ctx).Table(SchemaTableName).
+					Where("scope = ? and version = ?", scope, migration.Version).
+					Delete(&record{}).Error; err != nil {
+					return errors.Wrap(err, "failed on delete schema migration")
+				}
+				continue
+			}
+			if err := m.save(record{
+				Scope:       scope,
+				Version:     migration.Version,
+				Name:        migration.Name,
+				AppliedTime: time.Now().Unix(),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	xzap.WithContext(m.ctx).Info("schema version forced", zap.Strings("chains", chains), zap.Int("version", version))
+	return nil
+}
+
+// Status 返回共用表和 chains 已执行的最新版本
+func (m *Migrator) Status(chains []string) ([]ScopeStatus, error) {
+	if err := validateChains(chains); err != nil {
+		return nil, err
+	}
+	h, err := m.history()
+	if err != nil {
+		return nil, err
+	}
+	statuses := []ScopeStatus{m.scopeStatus(h, SharedScope, KindShared)}
+	for _, chain := range chains {
+		statuses = append(statuses, m.scopeStatus(h, chain, KindChain))
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) scopeStatus(h history, scope string, kind Kind) ScopeStatus {
+	status := ScopeStatus{Scope: scope, Latest: LatestVersion(m.migrations, kind)}
+	for version, r := range h[scope] {
+		if version > status.Version {
+			status.Version = version
+		}
+		status.Dirty = status.Dirty || r.Dirty
+	}
+	return status
+}
+
+// Verify 检查共用表和 chain 的表结构版本与程序内置的最新版本一致，daemon 启动时调用
+func Verify(ctx context.Context, db *gorm.DB, chain string) error {
+	m, err := New(ctx, db)
+	if err != nil {
+		return err
+	}
+	statuses, err := m.Status([]string{chain})
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if status.Dirty {
+			return errors.Errorf("schema %s is dirty at version %d, fix it by hand and run `sync migrate force`", status.Scope, status.Version)
+		}
+		if status.Version != status.Latest {
+			return errors.Errorf("schema %s is at version %d, this binary requires version %d, run `sync migrate up`",
+				status.Scope, status.Version, status.Latest)
+		}
+	}
+	return nil
+}
+
+// prepare 建版本记录表并检查 chains 没有中断的变更
+func (m *Migrator) prepare(chains []string) (history, error) {
+	if err := validateChains(chains); err != nil {
+		return nil, err
+	}
+	if err := m.db.WithContext(m.ctx).Exec(createSchemaTable).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on create schema migration table")
+	}
+	h, err := m.history()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.dirty(append([]string{SharedScope}, chains...)); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (m *Migrator) history() (history, error) {
+	h := make(history)
+	if !m.db.WithContext(m.ctx).Migrator().HasTable(SchemaTableName) {
+		return h, nil
+	}
+	var records []record
+	if err := m.db.WithContext(m.ctx).Table(SchemaTableName).Find(&records).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query schema migrations")
+	}
+	for _, r := range records {
+		if h[r.Scope] == nil {
+			h[r.Scope] = make(map[int]record)
+		}
+		h[r.Scope][r.Version] = r
+	}
+	return h, nil
+}
+
+// apply 执行一个变更。MySQL 的 DDL 不能回滚，执行前标记 dirty，全部语句成功后清除
+func (m *Migrator) apply(migration Migration, chain string, up bool) error {
+	scope := migration.Scope(chain)
+	statements, err := migration.Statements(chain, up)
+	if err != nil {
+		return err
+	}
+	if err := m.save(record{
+		Scope:       scope,
+		Version:     migration.Version,
+		Name:        migration.Name,
+		Dirty:       true,
+		AppliedTime: time.Now().Unix(),
+	}); err != nil {
+		return err
+	}
+
+	direction := "down"
+	if up {
+		direction = "up"
+	}
+	for _, stmt := range statements {
+		if err := m.db.WithContext(m.ctx).Exec(stmt).Error; err != nil {
+			return errors.Wrapf(err, "failed on migrate %s %s %02d_%s, schema is dirty, fix it by hand and run `sync migrate force`",
+				scope, direction, migration.Version, migration.Name)
+		}
+	}
+
+	if up {
+		err = m.db.WithContext(m.ctx).Table(SchemaTableName).
+			Where("scope = ? and version = ?", scope, migration.Version).
+			Update("dirty", false).Error
+	} else {
+		err = m.db.WithContext(m.ctx).Table(SchemaTableName).
+			Where("scope = ? and version = ?", scope, migration.Version).
+			Delete(&record{}).Error
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed on update schema migration")
+	}
+	xzap.WithContext(m.ctx).Info("schema migrated", zap.String("scope", scope), zap.String("direction", direction),
+		zap.Int("version", migration.Version), zap.String("name", migration.Name))
+	return nil
+}
+
+func (m *Migrator) save(r record) error {
+	if err := m.db.WithContext(m.ctx).Table(SchemaTableName).Clauses(clause.OnConflict{
+		UpdateAll: true,
+	}).Create(&r).Error; err != nil {
+		return errors.Wrap(err, "failed on save schema migration")
+	}
+	return nil
+}
+
+// versions 去重后升序排列的版本号
+func (m *Migrator) versions() []int {
+	var versions []int
+	for _, migration := range m.migrations {
+		if len(versions) == 0 || versions[len(versions)-1] != migration.Version {
+			versions = append(versions, migration.Version)
+		}
+	}
+	return versions
+}
+
+func (m *Migrator) byVersion(version int) []Migration {
+	var migrations []Migration
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			migrations = append(migrations, migration)
+		}
+	}
+	return migrations
+}
+
+// targets 共用表的变更只执行一次，链相关的变更每条链执行一次
+func targets(migration Migration, chains []string) []string {
+	if migration.Kind == KindShared {
+		return []string{""}
+	}
+	return chains
+}
+
+func validateChains(chains []string) error {
+	if len(chains) == 0 {
+		return errors.New("no chain to migrate")
+	}
+	for _, chain := range chains {
+		// 模板中表名不加引号，链名只能是小写字母、数字和下划线
+		if !chainNamePattern.MatchString(chain) || chain == SharedScope {
+			return errors.Errorf("invalid chain name %q for table suffix", chain)
+		}
+	}
+	return nil
+}
+
+func splitStatements(sql string) []string {
+	var statements []string
+	for _, part := range statementEnd.Split(sql, -1) {
+		stmt := strings.TrimSpace(part)
+		if !onlyComments(stmt) {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
+}
+
+func onlyComments(stmt string) bool {
+	for _, line := range strings.Split(stmt, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
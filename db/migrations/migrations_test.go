@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := LatestVersion(migrations, KindShared); got != 10 {
+		t.Errorf("latest shared version = %d, want 10", got)
+	}
+	if got := LatestVersion(migrations, KindChain); got != 11 {
+		t.Errorf("latest chain version = %d, want 11", got)
+	}
+	// 旧库通过 force --version 9 接入，之后的版本号在 shared 和 chain 之间不能重复
+	seen := make(map[int]Kind)
+	for _, m := range migrations {
+		if kind, ok := seen[m.Version]; ok && m.Version > 9 && kind != m.Kind {
+			t.Errorf("version %d is used by both %s and %s migrations", m.Version, kind, m.Kind)
+		}
+		seen[m.Version] = m.Kind
+	}
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i-1].Version > migrations[i].Version {
+			t.Fatalf("migrations not sorted: %d before %d", migrations[i-1].Version, migrations[i].Version)
+		}
+		if migrations[i-1].Version == migrations[i].Version && migrations[i-1].Kind != KindShared {
+			t.Errorf("shared migration %d should run first", migrations[i].Version)
+		}
+	}
+}
+
+func TestStatements(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	for _, m := range migrations {
+		for _, up := range []bool{true, false} {
+			statements, err := m.Statements("eth", up)
+			if err != nil {
+				t.Fatalf("%s %d: %v", m.Kind, m.Version, err)
+			}
+			if len(statements) == 0 {
+				t.Errorf("%s %d up=%v has no statements", m.Kind, m.Version, up)
+			}
+			for _, stmt := range statements {
+				if strings.Contains(stmt, "sepolia") || strings.HasSuffix(stmt, ";") {
+					t.Errorf("%s %d up=%v: unexpected statement %q", m.Kind, m.Version, up, stmt)
+				}
+				if m.Kind == KindChain && !strings.Contains(stmt, "_eth") {
+					t.Errorf("%s %d up=%v: statement without chain suffix %q", m.Kind, m.Version, up, stmt)
+				}
+			}
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	sql := "-- header\ncreate table a (id bigint comment 'x;y');\n\n-- only a comment;\n-- next\nalter table a\n    add b bigint;\n"
+	statements := splitStatements(sql)
+	if len(statements) != 2 {
+		t.Fatalf("got %d statements: %q", len(statements), statements)
+	}
+	if !strings.HasPrefix(statements[1], "-- next\nalter table a") {
+		t.Errorf("unexpected statement %q", statements[1])
+	}
+}
+
+func TestValidateChains(t *testing.T) {
+	if err := validateChains([]string{"sepolia", "eth"}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	for _, chains := range [][]string{nil, {"zksync-era"}, {SharedScope}, {"Eth"}} {
+		if err := validateChains(chains); err == nil {
+			t.Errorf("expected error for %q", chains)
+		}
+	}
+}
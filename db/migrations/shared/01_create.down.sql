@@ -0,0 +1,2 @@
+drop table if exists ob_indexed_status;
+drop table if exists ob_user;
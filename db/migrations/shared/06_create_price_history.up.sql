@@ -0,0 +1,13 @@
+create table ob_price_history
+(
+    id          bigint auto_increment comment '主键'
+        primary key,
+    symbol      varchar(32)     not null comment '代币符号，如 ETH',
+    price_time  bigint          not null comment '价格时间(秒)',
+    price_usd   decimal(30, 10) not null comment '美元价格',
+    create_time bigint          null comment '创建时间',
+    update_time bigint          null comment '更新时间',
+    constraint index_symbol_time
+        unique (symbol, price_time)
+)
+    collate = utf8mb4_general_ci;
@@ -0,0 +1,2 @@
+alter table ob_indexed_status
+    drop column fence_token;
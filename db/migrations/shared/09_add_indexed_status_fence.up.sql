@@ -0,0 +1,3 @@
+-- leader 提交同步游标时写入自己的 fencing token，token 更小的旧 leader 无法覆盖
+alter table ob_indexed_status
+    add fence_token bigint default 0 not null comment 'leader fencing token' after last_indexed_time;
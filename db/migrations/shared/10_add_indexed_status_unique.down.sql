@@ -0,0 +1,2 @@
+alter table ob_indexed_status
+    drop index index_chain_type;
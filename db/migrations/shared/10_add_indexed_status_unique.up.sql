@@ -0,0 +1,4 @@
+-- 每条链每种同步记录只有一行，多个实例同时启动建行时不会重复
+alter table ob_indexed_status
+    add constraint index_chain_type
+        unique (chain_id, index_type);
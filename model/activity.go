@@ -0,0 +1,13 @@
+package model
+
+import (
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+)
+
+// Activity 在 multi.Activity 的基础上记录产生该活动的日志位置，
+// 同一笔交易中的多条日志按 log_index 区分，重放时按日志精确去重
+type Activity struct {
+	multi.Activity
+	BlockHash string `gorm:"column:block_hash" json:"block_hash"` // 区块hash
+	LogIndex  int64  `gorm:"column:log_index" json:"log_index"`   // 日志在区块中的序号
+}
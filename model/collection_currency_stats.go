@@ -0,0 +1,25 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// CollectionCurrencyStats collection 按计价代币统计的地板价与交易量，*_eth 字段为折合 ETH(wei) 的值
+type CollectionCurrencyStats struct {
+	Id                int64            `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`                                          // 主键
+	CollectionAddress string           `gorm:"column:collection_address;NOT NULL" json:"collection_address"`                            // 合约地址
+	CurrencyAddress   string           `gorm:"column:currency_address;NOT NULL" json:"currency_address"`                                // 计价代币地址
+	FloorPrice        *decimal.Decimal `gorm:"column:floor_price;type:decimal(30)" json:"floor_price"`                                  // 该币种有效listing的最低价
+	FloorPriceEth     *decimal.Decimal `gorm:"column:floor_price_eth;type:decimal(30)" json:"floor_price_eth"`                          // 地板价折合ETH
+	VolumeTotal       decimal.Decimal  `gorm:"column:volume_total;type:decimal(30)" json:"volume_total"`                                // 该币种总交易量
+	VolumeTotalEth    decimal.Decimal  `gorm:"column:volume_total_eth;type:decimal(30)" json:"volume_total_eth"`                        // 总交易量折合ETH
+	SaleCount         int64            `gorm:"column:sale_count" json:"sale_count"`                                                     // 成交次数
+	CreateTime        int64            `json:"create_time" gorm:"column:create_time;type:bigint(20);autoCreateTime:milli;comment:创建时间"` // 创建时间
+	UpdateTime        int64            `json:"update_time" gorm:"column:update_time;type:bigint(20);autoUpdateTime:milli;comment:更新时间"` // 更新时间
+}
+
+func CollectionCurrencyStatsTableName(chainName string) string {
+	return fmt.Sprintf("ob_collection_currency_stats_%s", chainName)
+}
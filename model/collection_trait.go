@@ -0,0 +1,24 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// CollectionTrait collection 内每个 trait/value 的出现次数、频率和地板价
+type CollectionTrait struct {
+	Id                int64           `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`                                          // 主键
+	CollectionAddress string          `gorm:"column:collection_address;NOT NULL" json:"collection_address"`                            // 合约地址
+	Trait             string          `gorm:"column:trait;NOT NULL" json:"trait"`                                                      // 属性名称
+	TraitValue        string          `gorm:"column:trait_value;NOT NULL" json:"trait_value"`                                          // 属性值
+	ItemCount         int64           `gorm:"column:item_count;default:0;NOT NULL" json:"item_count"`                                  // 拥有该属性值的item数量
+	Frequency         decimal.Decimal `gorm:"column:frequency;type:decimal(20,10)" json:"frequency"`                                   // item_count / collection item 总数
+	FloorPrice        decimal.Decimal `gorm:"column:floor_price;type:decimal(30)" json:"floor_price"`                                  // 该属性值下 listing 的最低价格
+	CreateTime        int64           `json:"create_time" gorm:"column:create_time;type:bigint(20);autoCreateTime:milli;comment:创建时间"` // 创建时间
+	UpdateTime        int64           `json:"update_time" gorm:"column:update_time;type:bigint(20);autoUpdateTime:milli;comment:更新时间"` // 更新时间
+}
+
+func CollectionTraitTableName(chainName string) string {
+	return fmt.Sprintf("ob_collection_trait_%s", chainName)
+}
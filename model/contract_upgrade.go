@@ -0,0 +1,23 @@
+package model
+
+import (
+	"fmt"
+)
+
+// ContractUpgrade 代理合约的实现升级记录，来自 EIP-1967 Upgraded 事件
+type ContractUpgrade struct {
+	Id              int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`                                          // 主键
+	ContractAddress string `gorm:"column:contract_address;NOT NULL" json:"contract_address"`                                // 代理合约地址
+	Implementation  string `gorm:"column:implementation;NOT NULL" json:"implementation"`                                    // 新实现合约地址
+	BlockNumber     int64  `gorm:"column:block_number;NOT NULL" json:"block_number"`                                        // 升级所在区块号
+	TxHash          string `gorm:"column:tx_hash;NOT NULL" json:"tx_hash"`                                                  // 交易hash
+	LogIndex        int64  `gorm:"column:log_index;NOT NULL" json:"log_index"`                                              // 日志在区块中的序号
+	AbiVersion      string `gorm:"column:abi_version;NOT NULL" json:"abi_version"`                                          // 该区块使用的 ABI 版本
+	Compatible      bool   `gorm:"column:compatible;NOT NULL" json:"compatible"`                                            // 新实现的事件签名是否与 ABI 一致
+	CreateTime      int64  `json:"create_time" gorm:"column:create_time;type:bigint(20);autoCreateTime:milli;comment:创建时间"` // 创建时间
+	UpdateTime      int64  `json:"update_time" gorm:"column:update_time;type:bigint(20);autoUpdateTime:milli;comment:更新时间"` // 更新时间
+}
+
+func ContractUpgradeTableName(chainName string) string {
+	return fmt.Sprintf("ob_contract_upgrade_%s", chainName)
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// ItemRarity item 的统计稀有度得分与排名
+type ItemRarity struct {
+	Id                int64           `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`                                          // 主键
+	CollectionAddress string          `gorm:"column:collection_address;NOT NULL" json:"collection_address"`                            // 合约地址
+	TokenId           string          `gorm:"column:token_id;NOT NULL" json:"token_id"`                                                // token_id
+	RarityScore       decimal.Decimal `gorm:"column:rarity_score;type:decimal(30,10)" json:"rarity_score"`                             // 稀有度得分
+	RarityRank        int64           `gorm:"column:rarity_rank" json:"rarity_rank"`                                                   // 稀有度排名(1最稀有)
+	CreateTime        int64           `json:"create_time" gorm:"column:create_time;type:bigint(20);autoCreateTime:milli;comment:创建时间"` // 创建时间
+	UpdateTime        int64           `json:"update_time" gorm:"column:update_time;type:bigint(20);autoUpdateTime:milli;comment:更新时间"` // 更新时间
+}
+
+func ItemRarityTableName(chainName string) string {
+	return fmt.Sprintf("ob_item_rarity_%s", chainName)
+}
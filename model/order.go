@@ -0,0 +1,13 @@
+package model
+
+import (
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+)
+
+// Order 在 multi.Order 的基础上记录创建订单的 LogMake 日志位置
+type Order struct {
+	multi.Order
+	TxHash    string `gorm:"column:tx_hash" json:"tx_hash"`       // LogMake 交易hash
+	BlockHash string `gorm:"column:block_hash" json:"block_hash"` // LogMake 区块hash
+	LogIndex  int64  `gorm:"column:log_index" json:"log_index"`   // LogMake 在区块中的序号
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// OrderFill 订单的一次成交记录，每个 LogMatch 为撮合双方的订单各写一条，
+// 集合买单多次成交时可据此还原每次成交的 token
+type OrderFill struct {
+	Id                int64           `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`                                          // 主键
+	OrderId           string          `gorm:"column:order_id;NOT NULL" json:"order_id"`                                                // 订单ID
+	TxHash            string          `gorm:"column:tx_hash;NOT NULL" json:"tx_hash"`                                                  // 成交交易hash
+	LogIndex          int64           `gorm:"column:log_index;NOT NULL" json:"log_index"`                                              // LogMatch 在区块中的日志序号
+	CollectionAddress string          `gorm:"column:collection_address;NOT NULL" json:"collection_address"`                            // 合约地址
+	TokenId           string          `gorm:"column:token_id;NOT NULL" json:"token_id"`                                                // 实际成交的 token_id
+	Quantity          int64           `gorm:"column:quantity;NOT NULL" json:"quantity"`                                                // 成交数量
+	Price             decimal.Decimal `gorm:"column:price;type:decimal(30);NOT NULL" json:"price"`                                     // 成交价格
+	Counterparty      string          `gorm:"column:counterparty;NOT NULL" json:"counterparty"`                                        // 对手方订单的 maker
+	BlockNumber       int64           `gorm:"column:block_number;NOT NULL" json:"block_number"`                                        // 区块号
+	EventTime         int64           `gorm:"column:event_time" json:"event_time"`                                                     // 成交时间
+	CreateTime        int64           `json:"create_time" gorm:"column:create_time;type:bigint(20);autoCreateTime:milli;comment:创建时间"` // 创建时间
+	UpdateTime        int64           `json:"update_time" gorm:"column:update_time;type:bigint(20);autoUpdateTime:milli;comment:更新时间"` // 更新时间
+}
+
+func OrderFillTableName(chainName string) string {
+	return fmt.Sprintf("ob_order_fill_%s", chainName)
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// PriceHistory 代币美元价格历史，与链无关
+type PriceHistory struct {
+	Id         int64           `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`                                          // 主键
+	Symbol     string          `gorm:"column:symbol;NOT NULL" json:"symbol"`                                                    // 代币符号，如 ETH
+	PriceTime  int64           `gorm:"column:price_time;NOT NULL" json:"price_time"`                                            // 价格时间(秒)
+	PriceUsd   decimal.Decimal `gorm:"column:price_usd;type:decimal(30,10);NOT NULL" json:"price_usd"`                          // 美元价格
+	CreateTime int64           `json:"create_time" gorm:"column:create_time;type:bigint(20);autoCreateTime:milli;comment:创建时间"` // 创建时间
+	UpdateTime int64           `json:"update_time" gorm:"column:update_time;type:bigint(20);autoUpdateTime:milli;comment:更新时间"` // 更新时间
+}
+
+func PriceHistoryTableName() string {
+	return "ob_price_history"
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"fmt"
+)
+
+// RawLog 订单簿合约的原始日志归档，用于不经过 RPC 重放事件
+type RawLog struct {
+	Id          int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`                                          // 主键
+	Address     string `gorm:"column:address;NOT NULL" json:"address"`                                                  // 合约地址
+	Topics      string `gorm:"column:topics;NOT NULL" json:"topics"`                                                    // topic 列表，逗号分隔
+	Data        string `gorm:"column:data;type:mediumtext;NOT NULL" json:"data"`                                        // data 的十六进制编码
+	BlockNumber int64  `gorm:"column:block_number;NOT NULL" json:"block_number"`                                        // 区块号
+	BlockHash   string `gorm:"column:block_hash;NOT NULL" json:"block_hash"`                                            // 区块hash
+	BlockTime   int64  `gorm:"column:block_time;NOT NULL" json:"block_time"`                                            // 区块时间戳
+	TxHash      string `gorm:"column:tx_hash;NOT NULL" json:"tx_hash"`                                                  // 交易hash
+	TxIndex     int64  `gorm:"column:tx_index;NOT NULL" json:"tx_index"`                                                // 交易在区块中的序号
+	LogIndex    int64  `gorm:"column:log_index;NOT NULL" json:"log_index"`                                              // 日志在区块中的序号
+	CreateTime  int64  `json:"create_time" gorm:"column:create_time;type:bigint(20);autoCreateTime:milli;comment:创建时间"` // 创建时间
+	UpdateTime  int64  `json:"update_time" gorm:"column:update_time;type:bigint(20);autoUpdateTime:milli;comment:更新时间"` // 更新时间
+}
+
+func RawLogTableName(chainName string) string {
+	return fmt.Sprintf("ob_raw_log_%s", chainName)
+}
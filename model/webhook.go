@@ -0,0 +1,59 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// WebhookSubscriber webhook 订阅者，过滤条件为空表示不过滤
+type WebhookSubscriber struct {
+	Id          int64           `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`                                          // 主键
+	Name        string          `gorm:"column:name;NOT NULL" json:"name"`                                                        // 订阅者名称
+	Url         string          `gorm:"column:url;NOT NULL" json:"url"`                                                          // 推送地址
+	Secret      string          `gorm:"column:secret;NOT NULL" json:"-"`                                                         // HMAC 签名密钥
+	Collections string          `gorm:"column:collections;NOT NULL" json:"collections"`                                          // 逗号分隔的小写合约地址
+	EventTypes  string          `gorm:"column:event_types;NOT NULL" json:"event_types"`                                          // 逗号分隔的事件类型
+	MinPrice    decimal.Decimal `gorm:"column:min_price;type:decimal(30);NOT NULL" json:"min_price"`                             // 折合 ETH(wei) 的最低价格
+	CreateTime  int64           `json:"create_time" gorm:"column:create_time;type:bigint(20);autoCreateTime:milli;comment:创建时间"` // 创建时间
+	UpdateTime  int64           `json:"update_time" gorm:"column:update_time;type:bigint(20);autoUpdateTime:milli;comment:更新时间"` // 更新时间
+}
+
+func WebhookSubscriberTableName(chainName string) string {
+	return fmt.Sprintf("ob_webhook_subscriber_%s", chainName)
+}
+
+// WebhookDelivery 待推送的 webhook，即持久化的重试队列，推送成功后删除
+type WebhookDelivery struct {
+	Id              int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`                                          // 主键
+	SubscriberId    int64  `gorm:"column:subscriber_id;NOT NULL" json:"subscriber_id"`                                      // 订阅者ID
+	EventId         string `gorm:"column:event_id;NOT NULL" json:"event_id"`                                                // 事件ID，交易hash-日志序号
+	EventType       string `gorm:"column:event_type;NOT NULL" json:"event_type"`                                            // 事件类型
+	Payload         string `gorm:"column:payload;NOT NULL" json:"payload"`                                                  // 推送的 JSON
+	Attempts        int    `gorm:"column:attempts;NOT NULL" json:"attempts"`                                                // 已推送次数
+	NextAttemptTime int64  `gorm:"column:next_attempt_time;NOT NULL" json:"next_attempt_time"`                              // 下次推送时间(秒)
+	LastError       string `gorm:"column:last_error;NOT NULL" json:"last_error"`                                            // 最近一次失败原因
+	CreateTime      int64  `json:"create_time" gorm:"column:create_time;type:bigint(20);autoCreateTime:milli;comment:创建时间"` // 创建时间
+	UpdateTime      int64  `json:"update_time" gorm:"column:update_time;type:bigint(20);autoUpdateTime:milli;comment:更新时间"` // 更新时间
+}
+
+func WebhookDeliveryTableName(chainName string) string {
+	return fmt.Sprintf("ob_webhook_delivery_%s", chainName)
+}
+
+// WebhookDeadLetter 重试次数用尽的 webhook，可通过管理接口重新投递
+type WebhookDeadLetter struct {
+	Id           int64  `gorm:"column:id;AUTO_INCREMENT;primary_key" json:"id"`                                          // 主键
+	SubscriberId int64  `gorm:"column:subscriber_id;NOT NULL" json:"subscriber_id"`                                      // 订阅者ID
+	EventId      string `gorm:"column:event_id;NOT NULL" json:"event_id"`                                                // 事件ID，交易hash-日志序号
+	EventType    string `gorm:"column:event_type;NOT NULL" json:"event_type"`                                            // 事件类型
+	Payload      string `gorm:"column:payload;NOT NULL" json:"payload"`                                                  // 推送的 JSON
+	Attempts     int    `gorm:"column:attempts;NOT NULL" json:"attempts"`                                                // 已推送次数
+	LastError    string `gorm:"column:last_error;NOT NULL" json:"last_error"`                                            // 最后一次失败原因
+	CreateTime   int64  `json:"create_time" gorm:"column:create_time;type:bigint(20);autoCreateTime:milli;comment:创建时间"` // 创建时间
+	UpdateTime   int64  `json:"update_time" gorm:"column:update_time;type:bigint(20);autoUpdateTime:milli;comment:更新时间"` // 更新时间
+}
+
+func WebhookDeadLetterTableName(chainName string) string {
+	return fmt.Sprintf("ob_webhook_dead_letter_%s", chainName)
+}
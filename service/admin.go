@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/stores/gdb"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/base"
+	"go.uber.org/zap"
+
+	"github.com/yaoxc/EasySwapSync/service/orderbookindexer"
+)
+
+const (
+	AdminPathPre = "/admin/chains/"
+
+	pauseWaitTimeout = 60 * time.Second // 等待同步循环停在区间边界的最长时间
+	pausePollPeriod  = 200 * time.Millisecond
+)
+
+// Cursor 同步游标和同步进度
+type Cursor struct {
+	IndexType        int32 `json:"index_type"`
+	LastIndexedBlock int64 `json:"last_indexed_block"`
+	LastIndexedTime  int64 `json:"last_indexed_time"`
+	UpdateTime       int64 `json:"update_time"`
+}
+
+type cursorsResponse struct {
+	Chain     string   `json:"chain"`
+	Head      uint64   `json:"head"`
+	NextBlock uint64   `json:"next_block"`
+	Paused    bool     `json:"paused"`
+	Leader    bool     `json:"leader"`
+	Cursors   []Cursor `json:"cursors"`
+}
+
+type moveCursorRequest struct {
+	NextBlock uint64 `json:"next_block"`
+	Cleanup   bool   `json:"cleanup"`
+}
+
+// AdminHandler 运维管理接口，需要 Authorization: Bearer <admin_cfg.token>：
+//
+//	GET  /admin/chains/{chain}/cursors                    查看同步游标
+//	POST /admin/chains/{chain}/pause                      暂停订单簿同步，等待当前区间提交
+//	POST /admin/chains/{chain}/resume                     恢复订单簿同步
+//	POST /admin/chains/{chain}/cursor                     回退或快进游标，{"next_block": N, "cleanup": true}
+//	POST /admin/chains/{chain}/floor-price/recompute      重算地板价
+//	POST /admin/chains/{chain}/collection-filter/reload   重新加载集合过滤器
+//
+// 开启 leader 选举时 pause、resume 和 cursor 只能发给 leader；
+// 开启 webhook_cfg 时还提供 webhook 订阅管理，见 handleWebhooks
+func (s *Service) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(AdminPathPre, func(w http.ResponseWriter, r *http.Request) {
+		chain, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, AdminPathPre), "/")
+		if chain != s.config.ChainCfg.Name {
+			writeError(w, http.StatusNotFound, errors.Errorf("chain %s is not served by this instance", chain))
+			return
+		}
+		if action == webhooksAction || strings.HasPrefix(action, webhooksAction+"/") {
+			s.handleWebhooks(w, r, strings.TrimPrefix(strings.TrimPrefix(action, webhooksAction), "/"))
+			return
+		}
+
+		method := http.MethodPost
+		if action == "cursors" {
+			method = http.MethodGet
+		}
+		if r.Method != method {
+			writeError(w, http.StatusMethodNotAllowed, errors.Errorf("%s %s not allowed", r.Method, r.URL.Path))
+			return
+		}
+
+		switch action {
+		case "cursors":
+			s.handleCursors(w, r)
+		case "pause":
+			s.handlePause(w, r)
+		case "resume":
+			s.handleResume(w, r)
+		case "cursor":
+			s.handleMoveCursor(w, r)
+		case "floor-price/recompute":
+			s.handleRecomputeFloorPrice(w, r)
+		case "collection-filter/reload":
+			s.handleReloadCollectionFilter(w, r)
+		default:
+			writeError(w, http.StatusNotFound, errors.Errorf("unknown admin action %s", action))
+		}
+	})
+	return s.authenticate(mux)
+}
+
+// ServeAdmin 在 admin_cfg.port 上提供管理接口，ctx 取消后关闭
+func (s *Service) ServeAdmin(ctx context.Context) error {
+	if s.config.AdminCfg.Token == "" {
+		return errors.New("admin_cfg.token is required when admin api is enabled")
+	}
+	return ServeHTTP(ctx, &http.Server{
+		Addr:    fmt.Sprintf("0.0.0.0:%d", s.config.AdminCfg.Port),
+		Handler: s.AdminHandler(),
+	})
+}
+
+func (s *Service) authenticate(next http.Handler) http.Handler {
+	expected := []byte("Bearer " + s.config.AdminCfg.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.config.AdminCfg.Token == "" ||
+			subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+		xzap.WithContext(r.Context()).Info("admin request",
+			zap.String("method", r.Method), zap.String("path", r.URL.Path), zap.String("remote_addr", r.RemoteAddr))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Service) handleCursors(w http.ResponseWriter, r *http.Request) {
+	var cursors []Cursor
+	if err := s.db.WithContext(r.Context()).Table(base.IndexedStatusTableName()).
+		Select("index_type, last_indexed_block, last_indexed_time, update_time").
+		Where("chain_id = ?", s.config.ChainCfg.ID).
+		Order("index_type").
+		Scan(&cursors).Error; err != nil {
+		writeError(w, http.StatusInternalServerError, errors.Wrap(err, "failed on query cursors"))
+		return
+	}
+
+	resp := cursorsResponse{
+		Chain:   s.config.ChainCfg.Name,
+		Leader:  s.elector.IsLeader(),
+		Cursors: cursors,
+	}
+	if s.orderbookIndexer != nil {
+		resp.Head, resp.NextBlock = s.orderbookIndexer.Progress()
+		resp.Paused = s.orderbookIndexer.Paused()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) handlePause(w http.ResponseWriter, r *http.Request) {
+	if !s.requireIndexer(w) || !s.requireLeader(w) {
+		return
+	}
+	if err := s.orderbookIndexer.Pause(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), pauseWaitTimeout)
+	defer cancel()
+	ticker := time.NewTicker(pausePollPeriod)
+	defer ticker.Stop()
+	for !s.orderbookIndexer.Paused() {
+		select {
+		case <-ctx.Done():
+			// 暂停请求仍然有效，同步循环提交完当前区间后会停下
+			writeJSON(w, http.StatusAccepted, map[string]bool{"paused": false})
+			return
+		case <-ticker.C:
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": true})
+}
+
+func (s *Service) handleResume(w http.ResponseWriter, r *http.Request) {
+	if !s.requireIndexer(w) || !s.requireLeader(w) {
+		return
+	}
+	if err := s.orderbookIndexer.Resume(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": false})
+}
+
+func (s *Service) handleMoveCursor(w http.ResponseWriter, r *http.Request) {
+	if !s.requireIndexer(w) || !s.requireLeader(w) {
+		return
+	}
+	var req moveCursorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.Wrap(err, "invalid request body"))
+		return
+	}
+	if req.NextBlock == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("next_block is required"))
+		return
+	}
+
+	cleanup, err := s.orderbookIndexer.MoveCursor(req.NextBlock, req.Cleanup)
+	switch {
+	case errors.Is(err, orderbookindexer.ErrNotPaused):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, orderbookindexer.ErrCursorAheadOfHead), errors.Is(err, orderbookindexer.ErrCursorBeforeDeployment):
+		writeError(w, http.StatusBadRequest, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"next_block": req.NextBlock, "cleanup": cleanup})
+	}
+}
+
+func (s *Service) handleRecomputeFloorPrice(w http.ResponseWriter, r *http.Request) {
+	if !s.requireIndexer(w) {
+		return
+	}
+	if s.config.ProjectCfg.Name != gdb.OrderBookDexProject {
+		writeError(w, http.StatusBadRequest, errors.Errorf("floor price is not maintained for project %s", s.config.ProjectCfg.Name))
+		return
+	}
+	if !s.elector.IsLeader() {
+		writeError(w, http.StatusConflict, errors.New("floor price is recomputed by the leader"))
+		return
+	}
+	if err := s.orderbookIndexer.UpdateFloorPrices(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"recomputed": true})
+}
+
+func (s *Service) handleReloadCollectionFilter(w http.ResponseWriter, r *http.Request) {
+	count, err := s.collectionFilter.Reload()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"collections": count})
+}
+
+func (s *Service) requireIndexer(w http.ResponseWriter) bool {
+	if s.orderbookIndexer == nil {
+		writeError(w, http.StatusNotFound, errors.Errorf("no orderbook indexer on chain %s", s.config.ChainCfg.Name))
+		return false
+	}
+	return true
+}
+
+// requireLeader 暂停、恢复和移动游标只在 leader 上执行，Paused 确认的是本实例同步循环的状态
+func (s *Service) requireLeader(w http.ResponseWriter) bool {
+	if !s.elector.IsLeader() {
+		writeError(w, http.StatusConflict, errors.New("indexing is operated on the leader"))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"go.uber.org/zap"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/leader"
+	"github.com/yaoxc/EasySwapSync/service/orderbookindexer"
+)
+
+func TestAdminHandler(t *testing.T) {
+	s := &Service{
+		ctx: xzap.ToContext(context.Background(), zap.NewNop()),
+		config: &config.Config{
+			ChainCfg: config.ChainCfg{Name: "sepolia"},
+			AdminCfg: config.AdminCfg{Enable: true, Token: "secret"},
+		},
+	}
+	handler := s.AdminHandler()
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		code   int
+	}{
+		{"missing token", http.MethodGet, "/admin/chains/sepolia/cursors", "", http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "/admin/chains/sepolia/cursors", "Bearer wrong", http.StatusUnauthorized},
+		{"other chain", http.MethodPost, "/admin/chains/mainnet/pause", "Bearer secret", http.StatusNotFound},
+		{"wrong method", http.MethodGet, "/admin/chains/sepolia/pause", "Bearer secret", http.StatusMethodNotAllowed},
+		{"unknown action", http.MethodPost, "/admin/chains/sepolia/unknown", "Bearer secret", http.StatusNotFound},
+		{"no indexer", http.MethodPost, "/admin/chains/sepolia/pause", "Bearer secret", http.StatusNotFound},
+		{"webhooks disabled", http.MethodGet, "/admin/chains/sepolia/webhooks", "Bearer secret", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			req = req.WithContext(s.ctx)
+			if c.auth != "" {
+				req.Header.Set("Authorization", c.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != c.code {
+				t.Errorf("status = %d, want %d, body %s", rec.Code, c.code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminRequiresLeader(t *testing.T) {
+	ctx := xzap.ToContext(context.Background(), zap.NewNop())
+	indexer, err := orderbookindexer.New(ctx, nil, nil, nil, nil, 11155111, "sepolia", nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{
+		ctx: ctx,
+		config: &config.Config{
+			ChainCfg: config.ChainCfg{Name: "sepolia"},
+			AdminCfg: config.AdminCfg{Enable: true, Token: "secret"},
+		},
+		orderbookIndexer: indexer,
+		// 还没有当选
+		elector: leader.New(ctx, nil, config.LeaderCfg{}, "sepolia", "0x1111111111111111111111111111111111111111"),
+	}
+	handler := s.AdminHandler()
+	for _, action := range []string{"pause", "resume", "cursor"} {
+		req := httptest.NewRequest(http.MethodPost, "/admin/chains/sepolia/"+action, strings.NewReader(`{"next_block": 100}`))
+		req = req.WithContext(ctx)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusConflict {
+			t.Errorf("%s status = %d, want %d, body %s", action, rec.Code, http.StatusConflict, rec.Body.String())
+		}
+	}
+}
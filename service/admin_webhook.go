@@ -0,0 +1,107 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/yaoxc/EasySwapSync/service/webhook"
+)
+
+const webhooksAction = "webhooks"
+
+// handleWebhooks webhook 订阅管理：
+//
+//	GET    /admin/chains/{chain}/webhooks                 查看订阅者，不返回密钥
+//	POST   /admin/chains/{chain}/webhooks                 注册订阅者，{"name": "", "url": "", "collections": [], "event_types": [], "min_price": "0"}，返回签名密钥
+//	DELETE /admin/chains/{chain}/webhooks/{id}            删除订阅者及其待推送的 webhook
+//	POST   /admin/chains/{chain}/webhooks/{id}/redrive    把订阅者的死信重新放回推送队列
+func (s *Service) handleWebhooks(w http.ResponseWriter, r *http.Request, path string) {
+	if s.webhooks == nil {
+		writeError(w, http.StatusNotFound, errors.Errorf("webhooks are not enabled on chain %s", s.config.ChainCfg.Name))
+		return
+	}
+	if path == "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.handleListWebhooks(w)
+		case http.MethodPost:
+			s.handleCreateWebhook(w, r)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, errors.Errorf("%s %s not allowed", r.Method, r.URL.Path))
+		}
+		return
+	}
+
+	rawId, action, _ := strings.Cut(path, "/")
+	id, err := strconv.ParseInt(rawId, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.Errorf("invalid webhook subscriber id %s", rawId))
+		return
+	}
+	method := http.MethodDelete
+	switch action {
+	case "":
+	case "redrive":
+		method = http.MethodPost
+	default:
+		writeError(w, http.StatusNotFound, errors.Errorf("unknown admin action %s/%s", webhooksAction, path))
+		return
+	}
+	if r.Method != method {
+		writeError(w, http.StatusMethodNotAllowed, errors.Errorf("%s %s not allowed", r.Method, r.URL.Path))
+		return
+	}
+
+	if action == "redrive" {
+		count, err := s.webhooks.Redrive(id)
+		if err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"redriven": count})
+		return
+	}
+	if err := s.webhooks.DeleteSubscriber(id); err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+func (s *Service) handleListWebhooks(w http.ResponseWriter) {
+	subscribers, err := s.webhooks.ListSubscribers()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]webhook.Subscriber{"subscribers": subscribers})
+}
+
+func (s *Service) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req webhook.Subscriber
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.Wrap(err, "invalid request body"))
+		return
+	}
+	subscriber, err := s.webhooks.CreateSubscriber(req)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, subscriber)
+}
+
+func writeWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrInvalidSubscriber):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, webhook.ErrSubscriberNotFound):
+		writeError(w, http.StatusNotFound, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
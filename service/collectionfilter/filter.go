@@ -60,18 +60,9 @@ func (f *Filter) Contains(element string) bool {
 }
 
 func (f *Filter) PreloadCollections() error {
-	var addresses []string
-	var err error
-
-	// Query the addresses directly from the database
-	err = f.db.WithContext(f.ctx).
-		Table(gdb.GetMultiProjectCollectionTableName(f.project, f.chain)).
-		Select("address").
-		Where("floor_price_status = ?", comm.CollectionFloorPriceImported).
-		Scan(&addresses).Error
-
+	addresses, err := f.queryImportedCollections()
 	if err != nil {
-		return errors.Wrap(err, "failed on query collections from db")
+		return err
 	}
 
 	// Add each address into the Filter
@@ -81,3 +72,36 @@ func (f *Filter) PreloadCollections() error {
 
 	return nil
 }
+
+// Reload replaces the Filter content with the collections currently imported in the database,
+// dropping the ones that are no longer imported. It returns the number of collections loaded.
+func (f *Filter) Reload() (int, error) {
+	addresses, err := f.queryImportedCollections()
+	if err != nil {
+		return 0, err
+	}
+
+	set := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		set[strings.ToLower(address)] = true
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.set = set
+	return len(set), nil
+}
+
+func (f *Filter) queryImportedCollections() ([]string, error) {
+	var addresses []string
+	// Query the addresses directly from the database
+	err := f.db.WithContext(f.ctx).
+		Table(gdb.GetMultiProjectCollectionTableName(f.project, f.chain)).
+		Select("address").
+		Where("floor_price_status = ?", comm.CollectionFloorPriceImported).
+		Scan(&addresses).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on query collections from db")
+	}
+	return addresses, nil
+}
@@ -0,0 +1,431 @@
+package collectionimporter
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/chain/chainclient"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/ordermanager"
+	"github.com/yaoxc/EasySwapBase/stores/gdb"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/yaoxc/EasySwapBase/stores/xkv"
+	"github.com/zeromicro/go-zero/core/threading"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/yaoxc/EasySwapSync/service/collectionfilter"
+	"github.com/yaoxc/EasySwapSync/service/comm"
+)
+
+const (
+	importQueueSize    = 1000
+	drainPollInterval  = 100 * time.Millisecond
+	maxRecordMsgLength = 1600
+	erc721InterfaceId  = "80ac58cd"
+	erc1155InterfaceId = "d9b67a26"
+	zeroAddress        = "0x0000000000000000000000000000000000000000"
+	failedRetryBackoff = time.Hour // 导入失败的 collection 至少间隔这么久才重新导入
+)
+
+// 导入 collection 时需要用到的只读方法（ERC721 / ERC1155 / Ownable）
+const collectionAbi = `[
+{"inputs":[],"name":"name","outputs":[{"internalType":"string","name":"","type":"string"}],"stateMutability":"view","type":"function"},
+{"inputs":[],"name":"symbol","outputs":[{"internalType":"string","name":"","type":"string"}],"stateMutability":"view","type":"function"},
+{"inputs":[],"name":"totalSupply","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},
+{"inputs":[],"name":"owner","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"},
+{"inputs":[{"internalType":"bytes4","name":"interfaceId","type":"bytes4"}],"name":"supportsInterface","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"}
+]`
+
+// CollectionInfo 从链上读取到的 collection 基本信息
+type CollectionInfo struct {
+	Address       string
+	Name          string
+	Symbol        string
+	Creator       string
+	TokenStandard int64
+	TotalSupply   int64
+}
+
+// Importer 首次出现的 collection 导入器：
+// 读取链上 name/symbol/supportsInterface/totalSupply，写入 collection 相关表，并加入过滤器
+type Importer struct {
+	ctx         context.Context
+	db          *gorm.DB
+	kv          *xkv.Store
+	chainClient chainclient.ChainClient
+	chainId     int64
+	chain       string
+	project     string
+	filter      *collectionfilter.Filter
+	parsedAbi   abi.ABI
+
+	queue   chan string
+	pending map[string]bool // 已入队但尚未处理完的 collection
+	lock    *sync.Mutex
+}
+
+func New(ctx context.Context, db *gorm.DB, kv *xkv.Store, chainClient chainclient.ChainClient, chainId int64, chain string, project string, filter *collectionfilter.Filter) (*Importer, error) {
+	parsedAbi, err := abi.JSON(strings.NewReader(collectionAbi))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on parse collection abi")
+	}
+	return &Importer{
+		ctx:         ctx,
+		db:          db,
+		kv:          kv,
+		chainClient: chainClient,
+		chainId:     chainId,
+		chain:       chain,
+		project:     project,
+		filter:      filter,
+		parsedAbi:   parsedAbi,
+		queue:       make(chan string, importQueueSize),
+		pending:     make(map[string]bool),
+		lock:        &sync.Mutex{},
+	}, nil
+}
+
+func (i *Importer) Start() {
+	threading.GoSafe(i.importLoop)
+}
+
+// Submit 提交一个 collection 地址，已在过滤器中或正在导入的直接忽略；队列满时丢弃，等待下次出现再导入
+func (i *Importer) Submit(address string) {
+	if i == nil { // 重放等离线场景不导入
+		return
+	}
+	address = strings.ToLower(address)
+	if i.filter.Contains(address) {
+		return
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.pending[address] {
+		return
+	}
+
+	select {
+	case i.queue <- address:
+		i.pending[address] = true
+	default:
+		xzap.WithContext(i.ctx).Warn("collection import queue is full",
+			zap.String("collection_address", address))
+	}
+}
+
+// Drain 等待队列中已提交的 collection 全部导入完成，ctx 结束时返回剩余数量；
+// 未导入的 collection 下次出现时会重新提交
+func (i *Importer) Drain(ctx context.Context) error {
+	if i == nil {
+		return nil
+	}
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		i.lock.Lock()
+		remaining := len(i.pending)
+		i.lock.Unlock()
+		if remaining == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "%d collections left in import queue", remaining)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (i *Importer) importLoop() {
+	for {
+		select {
+		case <-i.ctx.Done():
+			xzap.WithContext(i.ctx).Info("collection importLoop stopped due to context cancellation")
+			return
+		case address := <-i.queue:
+			if err := i.Import(address); err != nil {
+				xzap.WithContext(i.ctx).Error("failed on import collection",
+					zap.String("collection_address", address), zap.Error(err))
+			}
+
+			i.lock.Lock()
+			delete(i.pending, address)
+			i.lock.Unlock()
+		}
+	}
+}
+
+// Import 导入单个 collection，已存在时只补充到过滤器中
+func (i *Importer) Import(address string) error {
+	address = strings.ToLower(address)
+
+	var count int64
+	if err := i.db.WithContext(i.ctx).Table(gdb.GetMultiProjectCollectionTableName(i.project, i.chain)).
+		Where("address = ?", address).
+		Count(&count).Error; err != nil {
+		return errors.Wrap(err, "failed on query collection")
+	}
+	if count > 0 {
+		i.filter.Add(address)
+		return nil
+	}
+
+	// 最近导入失败的 collection（如非 NFT 合约）在退避时间内不再重复调用链上接口
+	var failed int64
+	if err := i.db.WithContext(i.ctx).Table(multi.GlobalCollectionTableName(i.chain)).
+		Where("collection_address = ? and import_status = ? and update_time > ?",
+			address, comm.CollectionImportFailed, time.Now().Add(-failedRetryBackoff).UnixMilli()).
+		Count(&failed).Error; err != nil {
+		return errors.Wrap(err, "failed on query global collection")
+	}
+	if failed > 0 {
+		return nil
+	}
+
+	recordId, err := i.upsertImportRecord(address)
+	if err != nil {
+		return err
+	}
+
+	info, err := i.FetchCollectionInfo(address)
+	if err != nil {
+		err = errors.Wrap(err, "failed on fetch collection info")
+		i.markImportFailed(recordId, address, comm.TokenStandardNative, err)
+		return err
+	}
+
+	if err := i.createCollection(info); err != nil {
+		i.markImportFailed(recordId, address, info.TokenStandard, err)
+		return err
+	}
+	i.updateImportRecord(recordId, comm.CollectionImportStageImported, "")
+
+	i.filter.Add(address)
+
+	// 通知订单管理器开始跟踪该 collection 的地板价
+	if err := ordermanager.AddUpdatePriceEvent(i.kv, &ordermanager.TradeEvent{
+		EventType:      ordermanager.ImportCollection,
+		CollectionAddr: address,
+	}, i.chain); err != nil {
+		xzap.WithContext(i.ctx).Error("failed on add import collection event",
+			zap.String("collection_address", address), zap.Error(err))
+	}
+
+	xzap.WithContext(i.ctx).Info("collection imported",
+		zap.String("collection_address", address),
+		zap.String("name", info.Name),
+		zap.Int64("token_standard", info.TokenStandard))
+	return nil
+}
+
+// FetchCollectionInfo 通过 eth_call 读取 collection 的链上信息
+func (i *Importer) FetchCollectionInfo(address string) (*CollectionInfo, error) {
+	info := CollectionInfo{
+		Address: address,
+		Creator: zeroAddress,
+	}
+
+	is721, err := i.supportsInterface(address, erc721InterfaceId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on call supportsInterface")
+	}
+	if is721 {
+		info.TokenStandard = comm.TokenStandardErc721
+	} else {
+		is1155, err := i.supportsInterface(address, erc1155InterfaceId)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed on call supportsInterface")
+		}
+		if !is1155 {
+			return nil, errors.New(fmt.Sprintf("unsupported token standard: %s", address))
+		}
+		info.TokenStandard = comm.TokenStandardErc1155
+	}
+
+	// name/symbol/totalSupply/owner 都是可选实现，调用失败时保留默认值
+	if res, err := i.call(address, "name"); err == nil {
+		info.Name = res[0].(string)
+	}
+	if res, err := i.call(address, "symbol"); err == nil {
+		info.Symbol = res[0].(string)
+	}
+	if res, err := i.call(address, "totalSupply"); err == nil {
+		// 超过 int64 的 totalSupply 不可信，按未知处理
+		if supply := res[0].(*big.Int); supply.IsInt64() {
+			info.TotalSupply = supply.Int64()
+		} else {
+			xzap.WithContext(i.ctx).Warn("collection total supply overflows int64",
+				zap.String("collection_address", address), zap.String("total_supply", supply.String()))
+		}
+	}
+	if res, err := i.call(address, "owner"); err == nil {
+		info.Creator = strings.ToLower(res[0].(common.Address).String())
+	}
+
+	return &info, nil
+}
+
+func (i *Importer) supportsInterface(address string, interfaceId string) (bool, error) {
+	var id [4]byte
+	copy(id[:], common.FromHex(interfaceId))
+	res, err := i.call(address, "supportsInterface", id)
+	if err != nil {
+		return false, err
+	}
+	return res[0].(bool), nil
+}
+
+func (i *Importer) call(address string, method string, args ...interface{}) ([]interface{}, error) {
+	data, err := i.parsedAbi.Pack(method, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed on pack %s", method))
+	}
+
+	to := common.HexToAddress(address)
+	respData, err := i.chainClient.CallContract(i.ctx, ethereum.CallMsg{To: &to, Data: data}, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed on call %s", method))
+	}
+
+	res, err := i.parsedAbi.Unpack(method, respData)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed on unpack %s", method))
+	}
+	if len(res) == 0 {
+		return nil, errors.New(fmt.Sprintf("empty result of %s", method))
+	}
+	return res, nil
+}
+
+// createCollection 写入 ob_collection 与 ob_global_collection
+func (i *Importer) createCollection(info *CollectionInfo) error {
+	now := time.Now().UnixMilli()
+	return i.db.WithContext(i.ctx).Transaction(func(tx *gorm.DB) error {
+		collection := multi.Collection{
+			ChainId:          int(i.chainId),
+			Symbol:           info.Symbol,
+			Name:             info.Name,
+			Creator:          info.Creator,
+			Address:          info.Address,
+			TokenStandard:    info.TokenStandard,
+			ItemAmount:       info.TotalSupply,
+			FloorPriceStatus: comm.CollectionFloorPriceImported,
+		}
+		if err := tx.Table(gdb.GetMultiProjectCollectionTableName(i.project, i.chain)).
+			Create(&collection).Error; err != nil {
+			return errors.Wrap(err, "failed on create collection")
+		}
+
+		return upsertGlobalCollection(tx, i.chain, info.Address, info.TokenStandard, comm.CollectionImportSucceeded, now)
+	})
+}
+
+// upsertGlobalCollection 写入或更新 ob_global_collection 的导入状态，
+// multi.GlobalCollection 中 import_status 的列名标注有误，这里直接按列写入
+func upsertGlobalCollection(tx *gorm.DB, chain string, address string, tokenStandard int64, status int, now int64) error {
+	var count int64
+	if err := tx.Table(multi.GlobalCollectionTableName(chain)).
+		Where("collection_address = ?", address).
+		Count(&count).Error; err != nil {
+		return errors.Wrap(err, "failed on query global collection")
+	}
+	if count > 0 {
+		if err := tx.Table(multi.GlobalCollectionTableName(chain)).
+			Where("collection_address = ?", address).
+			Updates(map[string]interface{}{
+				"token_standard": tokenStandard,
+				"import_status":  status,
+				"update_time":    now,
+			}).Error; err != nil {
+			return errors.Wrap(err, "failed on update global collection")
+		}
+		return nil
+	}
+	if err := tx.Table(multi.GlobalCollectionTableName(chain)).Create(map[string]interface{}{
+		"collection_address": address,
+		"token_standard":     tokenStandard,
+		"import_status":      status,
+		"create_time":        now,
+		"update_time":        now,
+	}).Error; err != nil {
+		return errors.Wrap(err, "failed on create global collection")
+	}
+	return nil
+}
+
+// markImportFailed 导入失败时记录失败原因，并把 ob_global_collection 标记为导入失败，
+// collection 下次出现时重新导入
+func (i *Importer) markImportFailed(recordId int64, address string, tokenStandard int64, importErr error) {
+	i.updateImportRecord(recordId, comm.CollectionImportStageQueued, importErr.Error())
+	if err := upsertGlobalCollection(i.db.WithContext(i.ctx), i.chain, address, tokenStandard,
+		comm.CollectionImportFailed, time.Now().UnixMilli()); err != nil {
+		xzap.WithContext(i.ctx).Error("failed on mark collection import failed",
+			zap.String("collection_address", address), zap.Error(err))
+	}
+}
+
+// upsertImportRecord 每个 collection 只保留一条导入记录，重试时复用上次的记录
+func (i *Importer) upsertImportRecord(address string) (int64, error) {
+	var id int64
+	if err := i.db.WithContext(i.ctx).Table(multi.CollectionImportRecordTableName(i.chain)).
+		Select("id").
+		Where("collection_address = ?", address).
+		Order("id desc").
+		Limit(1).
+		Scan(&id).Error; err != nil {
+		return 0, errors.Wrap(err, "failed on query collection import record")
+	}
+	if id > 0 {
+		i.updateImportRecord(id, comm.CollectionImportStageQueued, "")
+		return id, nil
+	}
+
+	now := time.Now().UnixMilli()
+	record := map[string]interface{}{
+		"collection_address": address,
+		"msg":                "",
+		"finished_stage":     comm.CollectionImportStageQueued,
+		"create_time":        now,
+		"update_time":        now,
+	}
+	if err := i.db.WithContext(i.ctx).Table(multi.CollectionImportRecordTableName(i.chain)).
+		Create(record).Error; err != nil {
+		return 0, errors.Wrap(err, "failed on create collection import record")
+	}
+
+	if err := i.db.WithContext(i.ctx).Table(multi.CollectionImportRecordTableName(i.chain)).
+		Select("id").
+		Where("collection_address = ?", address).
+		Order("id desc").
+		Limit(1).
+		Scan(&id).Error; err != nil {
+		return 0, errors.Wrap(err, "failed on query collection import record")
+	}
+	return id, nil
+}
+
+func (i *Importer) updateImportRecord(id int64, stage int, msg string) {
+	if len(msg) > maxRecordMsgLength {
+		msg = msg[:maxRecordMsgLength]
+	}
+	if err := i.db.WithContext(i.ctx).Table(multi.CollectionImportRecordTableName(i.chain)).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"finished_stage": stage,
+			"msg":            msg,
+			"update_time":    time.Now().UnixMilli(),
+		}).Error; err != nil {
+		xzap.WithContext(i.ctx).Error("failed on update collection import record",
+			zap.Int64("id", id), zap.Error(err))
+	}
+}
@@ -0,0 +1,34 @@
+package collectionimporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yaoxc/EasySwapSync/service/collectionfilter"
+)
+
+func TestSubmit(t *testing.T) {
+	ctx := context.Background()
+	filter := collectionfilter.New(ctx, nil, "sepolia", "OrderBookDex")
+	filter.Add("0x0000000000000000000000000000000000000001")
+	importer, err := New(ctx, nil, nil, nil, 11155111, "sepolia", "OrderBookDex", filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 已在过滤器中的 collection 不需要导入
+	importer.Submit("0x0000000000000000000000000000000000000001")
+	if len(importer.queue) != 0 {
+		t.Errorf("Expected known collection to be skipped, got queue length %d", len(importer.queue))
+	}
+
+	// 重复提交只入队一次，且不区分大小写
+	importer.Submit("0x00000000000000000000000000000000000000AB")
+	importer.Submit("0x00000000000000000000000000000000000000ab")
+	if len(importer.queue) != 1 {
+		t.Errorf("Expected queue length 1, got %d", len(importer.queue))
+	}
+	if address := <-importer.queue; address != "0x00000000000000000000000000000000000000ab" {
+		t.Errorf("Expected lowercase address, got %s", address)
+	}
+}
@@ -0,0 +1,16 @@
+package comm
+
+import (
+	"time"
+
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+)
+
+// ActiveListingCondition 有效 listing 的判断条件，co 为订单表别名，ci 为 item 表别名，
+// 与 ActiveListingArgs 配合使用：挂单类型、状态有效、未过期（expire_time 为 0 表示永不过期）且挂单人仍持有该 item
+const ActiveListingCondition = `co.order_type = ? and co.order_status = ? and (co.expire_time = 0 or co.expire_time > ?) and co.maker = ci.owner`
+
+// ActiveListingArgs ActiveListingCondition 的参数
+func ActiveListingArgs() []interface{} {
+	return []interface{}{multi.ListingType, multi.OrderStatusActive, time.Now().Unix()}
+}
@@ -5,6 +5,35 @@ const (
 	CollectionFloorPriceImported  = 1
 )
 
+// ob_collection_import_record.finished_stage
+const (
+	CollectionImportStageQueued   = 0 // 加入任务
+	CollectionImportStageImported = 1 // collection 导入完成
+	CollectionImportStageFinished = 2 // 全部完成
+)
+
+// ob_global_collection.token_standard
+const (
+	TokenStandardNative  = 0
+	TokenStandardErc721  = 1
+	TokenStandardErc1155 = 2
+)
+
+// ob_global_collection.import_status
+const (
+	CollectionUnImported      = 0
+	CollectionWaitImport      = 1
+	CollectionImportFailed    = 2
+	CollectionImportSucceeded = 3
+)
+
+// ob_activity.activity_type 中的订单过期类型。multi 中的链上活动类型从 1 开始连续编号，
+// 本服务补充的类型从 101 开始，上游新增类型不会与之冲突（activity_type 为 tinyint，不超过 127）
+const (
+	ActivityListingExpired = 101
+	ActivityOfferExpired   = 102
+)
+
 const (
 	DBBatchSizeLimit                 = 200
 	CollectionFloorChangeIndexType   = 5
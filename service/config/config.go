@@ -18,22 +18,107 @@ type Config struct {
 	ChainCfg    ChainCfg         `toml:"chain_cfg" mapstructure:"chain_cfg" json:"chain_cfg"`
 	ContractCfg ContractCfg      `toml:"contract_cfg" mapstructure:"contract_cfg" json:"contract_cfg"`
 	ProjectCfg  ProjectCfg       `toml:"project_cfg" mapstructure:"project_cfg" json:"project_cfg"`
+	MetadataCfg MetadataCfg      `toml:"metadata_cfg" mapstructure:"metadata_cfg" json:"metadata_cfg"`
+	Currencies  []CurrencyCfg    `toml:"currencies" mapstructure:"currencies" json:"currencies"`
+	PricingCfg  PricingCfg       `toml:"pricing_cfg" mapstructure:"pricing_cfg" json:"pricing_cfg"`
+	Supervisor  SupervisorCfg    `toml:"supervisor_cfg" mapstructure:"supervisor_cfg" json:"supervisor_cfg"`
+	LeaderCfg   LeaderCfg        `toml:"leader_cfg" mapstructure:"leader_cfg" json:"leader_cfg"`
+	AdminCfg    AdminCfg         `toml:"admin_cfg" mapstructure:"admin_cfg" json:"admin_cfg"`
+	WebhookCfg  WebhookCfg       `toml:"webhook_cfg" mapstructure:"webhook_cfg" json:"webhook_cfg"`
 }
 
 type ChainCfg struct {
-	Name string `toml:"name" mapstructure:"name" json:"name"`
-	ID   int64  `toml:"id" mapstructure:"id" json:"id"`
+	Name        string          `toml:"name" mapstructure:"name" json:"name"`
+	ID          int64           `toml:"id" mapstructure:"id" json:"id"`
+	Deployments []DeploymentCfg `toml:"deployments" mapstructure:"deployments" json:"deployments"`
+}
+
+// DeploymentCfg 合约部署所在区块，缺少同步记录时从这里开始同步
+type DeploymentCfg struct {
+	Address    string `toml:"address" mapstructure:"address" json:"address"`
+	StartBlock uint64 `toml:"start_block" mapstructure:"start_block" json:"start_block"`
+}
+
+// StartBlock 返回合约 address 的部署区块，未配置时返回 false
+func (c ChainCfg) StartBlock(address string) (uint64, bool) {
+	for _, deployment := range c.Deployments {
+		if strings.EqualFold(deployment.Address, address) {
+			return deployment.StartBlock, true
+		}
+	}
+	return 0, false
 }
 
 type ContractCfg struct {
 	EthAddress  string `toml:"eth_address" mapstructure:"eth_address" json:"eth_address"`
 	WethAddress string `toml:"weth_address" mapstructure:"weth_address" json:"weth_address"`
 	DexAddress  string `toml:"dex_address" mapstructure:"dex_address" json:"dex_address"`
+
+	Abis []AbiCfg `toml:"abis" mapstructure:"abis" json:"abis"`
+}
+
+// AbiCfg 合约在某个区块区间内使用的 ABI 版本，用于可升级代理合约的实现升级
+type AbiCfg struct {
+	Address   string `toml:"address" mapstructure:"address" json:"address"`
+	Version   string `toml:"version" mapstructure:"version" json:"version"`
+	Path      string `toml:"path" mapstructure:"path" json:"path"`
+	FromBlock uint64 `toml:"from_block" mapstructure:"from_block" json:"from_block"`
+	ToBlock   uint64 `toml:"to_block" mapstructure:"to_block" json:"to_block"` // 0 表示一直生效
+}
+
+// CurrencyCfg 除 ETH/WETH 以外支持的 ERC20 计价代币
+type CurrencyCfg struct {
+	Address  string `toml:"address" mapstructure:"address" json:"address"`
+	Symbol   string `toml:"symbol" mapstructure:"symbol" json:"symbol"`
+	Decimals int32  `toml:"decimals" mapstructure:"decimals" json:"decimals"`
+	EthRate  string `toml:"eth_rate" mapstructure:"eth_rate" json:"eth_rate"` // 1 个代币折合的 ETH 数量
+}
+
+type PricingCfg struct {
+	Source       string `toml:"source" mapstructure:"source" json:"source"` // 价格数据源，默认 csv
+	CsvPath      string `toml:"csv_path" mapstructure:"csv_path" json:"csv_path"`
+	SyncInterval int64  `toml:"sync_interval" mapstructure:"sync_interval" json:"sync_interval"` // in seconds
+	MaxPriceAge  int64  `toml:"max_price_age" mapstructure:"max_price_age" json:"max_price_age"` // in seconds，超过该时长的价格不用于换算
+}
+
+// SupervisorCfg 常驻循环的重启和停机策略
+type SupervisorCfg struct {
+	InitialBackoff  int64 `toml:"initial_backoff" mapstructure:"initial_backoff" json:"initial_backoff"`    // in seconds
+	MaxBackoff      int64 `toml:"max_backoff" mapstructure:"max_backoff" json:"max_backoff"`                // in seconds
+	MaxFailures     int   `toml:"max_failures" mapstructure:"max_failures" json:"max_failures"`             // 连续失败该次数后进程退出
+	ShutdownTimeout int64 `toml:"shutdown_timeout" mapstructure:"shutdown_timeout" json:"shutdown_timeout"` // in seconds，停机时等待进行中工作完成的最长时间
+}
+
+// LeaderCfg 多副本部署时的 leader 选举，只有 leader 运行订单簿同步和地板价循环
+type LeaderCfg struct {
+	Enable   bool  `toml:"enable" mapstructure:"enable" json:"enable"`
+	LeaseTtl int64 `toml:"lease_ttl" mapstructure:"lease_ttl" json:"lease_ttl"` // in seconds，每 lease_ttl/3 续期一次
+}
+
+// AdminCfg 运维管理接口，请求需携带 Authorization: Bearer <token>
+type AdminCfg struct {
+	Enable bool   `toml:"enable" mapstructure:"enable" json:"enable"`
+	Port   int64  `toml:"port" mapstructure:"port" json:"port"`
+	Token  string `toml:"token" mapstructure:"token" json:"-"`
+}
+
+// WebhookCfg 挂单、成交和取消事件的 webhook 推送，失败按指数退避重试，超过 max_attempts 后移入死信表
+type WebhookCfg struct {
+	Enable         bool  `toml:"enable" mapstructure:"enable" json:"enable"`
+	Timeout        int64 `toml:"timeout" mapstructure:"timeout" json:"timeout"`                         // in seconds
+	MaxAttempts    int   `toml:"max_attempts" mapstructure:"max_attempts" json:"max_attempts"`          // 包含首次推送
+	InitialBackoff int64 `toml:"initial_backoff" mapstructure:"initial_backoff" json:"initial_backoff"` // in seconds
+	MaxBackoff     int64 `toml:"max_backoff" mapstructure:"max_backoff" json:"max_backoff"`             // in seconds
 }
 
 type Monitor struct {
 	PprofEnable bool  `toml:"pprof_enable" mapstructure:"pprof_enable" json:"pprof_enable"`
 	PprofPort   int64 `toml:"pprof_port" mapstructure:"pprof_port" json:"pprof_port"`
+
+	HttpPort      int64  `toml:"http_port" mapstructure:"http_port" json:"http_port"` // /metrics、/healthz、/readyz 端口，0 表示不开启
+	MetricsEnable bool   `toml:"metrics_enable" mapstructure:"metrics_enable" json:"metrics_enable"`
+	MaxIndexerLag uint64 `toml:"max_indexer_lag" mapstructure:"max_indexer_lag" json:"max_indexer_lag"` // 同步落后超过该区块数时 readyz 失败
+	LoopDeadline  int64  `toml:"loop_deadline" mapstructure:"loop_deadline" json:"loop_deadline"`       // 常驻循环超过该秒数没有心跳时 healthz 失败
 }
 
 type AnkrCfg struct {
@@ -43,6 +128,14 @@ type AnkrCfg struct {
 	EnableWss    bool   `toml:"enable_wss" mapstructure:"enable_wss" json:"enable_wss"`
 }
 
+type MetadataCfg struct {
+	IpfsGateway   string `toml:"ipfs_gateway" mapstructure:"ipfs_gateway" json:"ipfs_gateway"`
+	HttpTimeout   int64  `toml:"http_timeout" mapstructure:"http_timeout" json:"http_timeout"` // in seconds
+	MaxRetries    int    `toml:"max_retries" mapstructure:"max_retries" json:"max_retries"`
+	HostRateLimit int    `toml:"host_rate_limit" mapstructure:"host_rate_limit" json:"host_rate_limit"` // requests per second per host
+	WorkerNum     int    `toml:"worker_num" mapstructure:"worker_num" json:"worker_num"`
+}
+
 type ProjectCfg struct {
 	Name string `toml:"name" mapstructure:"name" json:"name"`
 }
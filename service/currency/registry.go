@@ -0,0 +1,87 @@
+package currency
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+)
+
+const (
+	EthDecimals = 18
+	EthSymbol   = "ETH"
+	WethSymbol  = "WETH"
+)
+
+// Currency 支持的计价代币
+type Currency struct {
+	Address  string
+	Symbol   string
+	Decimals int32
+	EthRate  decimal.Decimal // 1 个代币（按 decimals 换算后的整币）折合的 ETH 数量
+}
+
+// Registry 支持的计价代币注册表，按小写地址索引；
+// ETH 与 WETH 由 contract_cfg 自动注册，其余 ERC20 通过 [[currencies]] 配置
+type Registry struct {
+	currencies map[string]Currency
+	eth        string
+}
+
+func New(contractCfg config.ContractCfg, currencies []config.CurrencyCfg) (*Registry, error) {
+	r := &Registry{
+		currencies: make(map[string]Currency),
+		eth:        strings.ToLower(contractCfg.EthAddress),
+	}
+	r.register(Currency{Address: contractCfg.EthAddress, Symbol: EthSymbol, Decimals: EthDecimals, EthRate: decimal.NewFromInt(1)})
+	if contractCfg.WethAddress != "" {
+		r.register(Currency{Address: contractCfg.WethAddress, Symbol: WethSymbol, Decimals: EthDecimals, EthRate: decimal.NewFromInt(1)})
+	}
+
+	for _, c := range currencies {
+		rate, err := decimal.NewFromString(c.EthRate)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid eth_rate of currency %s", c.Address)
+		}
+		if c.Decimals < 0 {
+			return nil, errors.Errorf("invalid decimals of currency %s", c.Address)
+		}
+		r.register(Currency{Address: c.Address, Symbol: c.Symbol, Decimals: c.Decimals, EthRate: rate})
+	}
+	return r, nil
+}
+
+func (r *Registry) register(c Currency) {
+	c.Address = strings.ToLower(c.Address)
+	r.currencies[c.Address] = c
+}
+
+// Eth 原生 ETH 对应的地址，未解析出币种的订单使用该地址
+func (r *Registry) Eth() string {
+	return r.eth
+}
+
+// Currencies 返回全部支持的计价代币
+func (r *Registry) Currencies() []Currency {
+	currencies := make([]Currency, 0, len(r.currencies))
+	for _, c := range r.currencies {
+		currencies = append(currencies, c)
+	}
+	return currencies
+}
+
+func (r *Registry) Get(address string) (Currency, bool) {
+	c, ok := r.currencies[strings.ToLower(address)]
+	return c, ok
+}
+
+// ToEth 把以 address 计价的最小单位金额换算成以 wei 计的 ETH 金额
+func (r *Registry) ToEth(address string, amount decimal.Decimal) (decimal.Decimal, error) {
+	c, ok := r.Get(address)
+	if !ok {
+		return decimal.Zero, errors.Errorf("unsupported currency %s", address)
+	}
+	return amount.Mul(c.EthRate).Shift(EthDecimals - c.Decimals).Truncate(0), nil
+}
@@ -0,0 +1,51 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+)
+
+func TestToEth(t *testing.T) {
+	r, err := New(config.ContractCfg{
+		EthAddress:  "0x0000000000000000000000000000000000000000",
+		WethAddress: "0x4200000000000000000000000000000000000006",
+	}, []config.CurrencyCfg{
+		{Address: "0x5FbDB2315678afecb367f032d93F642f64180aa3", Symbol: "USDC", Decimals: 6, EthRate: "0.0004"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		address string
+		amount  string
+		want    string
+	}{
+		{"0x0000000000000000000000000000000000000000", "1500000000000000000", "1500000000000000000"},
+		{"0x4200000000000000000000000000000000000006", "2000000000000000000", "2000000000000000000"},
+		// 2500 USDC * 0.0004 = 1 ETH
+		{"0x5fbdb2315678afecb367f032d93f642f64180aa3", "2500000000", "1000000000000000000"},
+	}
+	for _, c := range cases {
+		got, err := r.ToEth(c.address, decimal.RequireFromString(c.amount))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != c.want {
+			t.Errorf("ToEth(%s, %s) = %s, want %s", c.address, c.amount, got, c.want)
+		}
+	}
+
+	if _, err := r.ToEth("0x1111111111111111111111111111111111111111", decimal.NewFromInt(1)); err == nil {
+		t.Error("expected error for unsupported currency")
+	}
+}
+
+func TestNewInvalidRate(t *testing.T) {
+	if _, err := New(config.ContractCfg{}, []config.CurrencyCfg{{Address: "0x1", EthRate: "abc"}}); err == nil {
+		t.Error("expected error for invalid eth_rate")
+	}
+}
@@ -0,0 +1,96 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+
+	readinessTimeout = 3 * time.Second
+)
+
+// Heartbeat 记录常驻循环最近一次心跳，用于存活检查。循环是否退出由 supervisor 跟踪
+type Heartbeat struct {
+	beats map[string]time.Time
+	lock  *sync.RWMutex
+}
+
+func NewHeartbeat() *Heartbeat {
+	return &Heartbeat{
+		beats: make(map[string]time.Time),
+		lock:  &sync.RWMutex{},
+	}
+}
+
+// Beat 循环每轮调用一次，h 为 nil 时忽略
+func (h *Heartbeat) Beat(loop string) {
+	if h == nil {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.beats[loop] = time.Now()
+}
+
+// Check 任一循环超过 deadline 没有心跳时返回错误
+func (h *Heartbeat) Check(deadline time.Duration) error {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	now := time.Now()
+	loops := make([]string, 0, len(h.beats))
+	for loop := range h.beats {
+		loops = append(loops, loop)
+	}
+	sort.Strings(loops)
+	for _, loop := range loops {
+		if deadline > 0 && now.Sub(h.beats[loop]) > deadline {
+			return fmt.Errorf("loop %s has not ticked for %s", loop, now.Sub(h.beats[loop]).Truncate(time.Second))
+		}
+	}
+	return nil
+}
+
+// Check 单项检查，返回 nil 表示通过
+type Check func(ctx context.Context) error
+
+type result struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Register 在 mux 上注册 /healthz 和 /readyz，检查全部通过时返回 200，否则返回 503
+func Register(mux *http.ServeMux, liveness map[string]Check, readiness map[string]Check) {
+	mux.Handle(LivenessPath, handler(liveness))
+	mux.Handle(ReadinessPath, handler(readiness))
+}
+
+func handler(checks map[string]Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		res := result{Status: "ok", Checks: make(map[string]string)}
+		for name, check := range checks {
+			if err := check(ctx); err != nil {
+				res.Status = "fail"
+				res.Checks[name] = err.Error()
+				continue
+			}
+			res.Checks[name] = "ok"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if res.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	})
+}
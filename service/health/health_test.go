@@ -0,0 +1,54 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatCheck(t *testing.T) {
+	h := NewHeartbeat()
+	if err := h.Check(time.Minute); err != nil {
+		t.Fatalf("empty heartbeat: %v", err)
+	}
+
+	h.Beat("sync")
+	if err := h.Check(time.Minute); err != nil {
+		t.Fatalf("fresh beat: %v", err)
+	}
+
+	h.beats["sync"] = time.Now().Add(-2 * time.Minute)
+	if err := h.Check(time.Minute); err == nil {
+		t.Fatal("expected stale beat to fail")
+	}
+
+	var nilHeartbeat *Heartbeat
+	nilHeartbeat.Beat("sync")
+}
+
+func TestRegister(t *testing.T) {
+	mux := http.NewServeMux()
+	Register(mux, map[string]Check{
+		"loops": func(ctx context.Context) error { return nil },
+	}, map[string]Check{
+		"db": func(ctx context.Context) error { return errors.New("down") },
+	})
+
+	cases := []struct {
+		path string
+		code int
+	}{
+		{LivenessPath, http.StatusOK},
+		{ReadinessPath, http.StatusServiceUnavailable},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if rec.Code != c.code {
+			t.Errorf("%s: got %d, want %d", c.path, rec.Code, c.code)
+		}
+	}
+}
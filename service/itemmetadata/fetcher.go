@@ -0,0 +1,177 @@
+package itemmetadata
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	base64JsonPrefix = "data:application/json;base64,"
+	plainJsonPrefix  = "data:application/json,"
+	ipfsPrefix       = "ipfs://"
+	maxMetadataSize  = 2 << 20
+)
+
+// Metadata ERC721/ERC1155 元数据中需要落库的字段
+type Metadata struct {
+	Name       string
+	Image      string
+	Attributes []Attribute
+}
+
+type Attribute struct {
+	TraitType string
+	Value     string
+}
+
+// hostLimiter 按 host 限速，保证同一个 host 两次请求间隔不小于 interval
+type hostLimiter struct {
+	interval time.Duration
+	next     map[string]time.Time
+	lock     *sync.Mutex
+}
+
+func newHostLimiter(ratePerSecond int) *hostLimiter {
+	return &hostLimiter{
+		interval: time.Second / time.Duration(ratePerSecond),
+		next:     make(map[string]time.Time),
+		lock:     &sync.Mutex{},
+	}
+}
+
+// Wait 阻塞到该 host 允许发出下一次请求
+func (l *hostLimiter) Wait(host string) {
+	l.lock.Lock()
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(l.interval)
+	l.lock.Unlock()
+
+	time.Sleep(at.Sub(now))
+}
+
+// resolveUri 把 tokenURI 转换成可以直接请求的地址，ipfs:// 使用配置的网关
+func resolveUri(uri string, ipfsGateway string) string {
+	uri = strings.TrimSpace(uri)
+	if strings.HasPrefix(uri, ipfsPrefix) {
+		path := strings.TrimPrefix(uri, ipfsPrefix)
+		path = strings.TrimPrefix(path, "ipfs/")
+		return strings.TrimSuffix(ipfsGateway, "/") + "/" + path
+	}
+	return uri
+}
+
+// erc1155Uri 替换 ERC1155 uri 中的 {id} 占位符（64 位小写十六进制）
+func erc1155Uri(uri string, tokenId string) string {
+	if !strings.Contains(uri, "{id}") {
+		return uri
+	}
+	var id [32]byte
+	if n, ok := parseBigInt(tokenId); ok {
+		n.FillBytes(id[:])
+	}
+	return strings.ReplaceAll(uri, "{id}", fmt.Sprintf("%x", id))
+}
+
+// fetchMetadata 获取 tokenURI 对应的元数据原文，data uri 直接解码，http(s)/ipfs 走限速后的 HTTP 请求
+func (w *Worker) fetchMetadata(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, base64JsonPrefix) {
+		body, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, base64JsonPrefix))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed on decode base64 metadata")
+		}
+		return body, nil
+	}
+	if strings.HasPrefix(uri, plainJsonPrefix) {
+		body, err := url.PathUnescape(strings.TrimPrefix(uri, plainJsonPrefix))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed on unescape metadata")
+		}
+		return []byte(body), nil
+	}
+
+	fullUrl := resolveUri(uri, w.cfg.IpfsGateway)
+	u, err := url.Parse(fullUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.New(fmt.Sprintf("invalid metadata uri: %s", uri))
+	}
+
+	w.limiter.Wait(u.Host)
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodGet, fullUrl, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on create metadata request")
+	}
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on request metadata")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("unexpected metadata response status: %d", resp.StatusCode))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on read metadata response")
+	}
+
+	return bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")), nil
+}
+
+// decodeMetadata 解析 OpenSea 风格的元数据 JSON
+func decodeMetadata(content []byte) (*Metadata, error) {
+	var raw struct {
+		Name       interface{} `json:"name"`
+		Image      string      `json:"image"`
+		ImageUrl   string      `json:"image_url"`
+		Attributes []struct {
+			TraitType interface{} `json:"trait_type"`
+			Value     interface{} `json:"value"`
+		} `json:"attributes"`
+	}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, errors.Wrap(err, "failed on unmarshal metadata")
+	}
+
+	metadata := Metadata{
+		Name:  stringify(raw.Name),
+		Image: raw.Image,
+	}
+	if metadata.Image == "" {
+		metadata.Image = raw.ImageUrl
+	}
+	for _, attr := range raw.Attributes {
+		if attr.TraitType == nil || attr.Value == nil {
+			continue
+		}
+		metadata.Attributes = append(metadata.Attributes, Attribute{
+			TraitType: stringify(attr.TraitType),
+			Value:     stringify(attr.Value),
+		})
+	}
+	return &metadata, nil
+}
+
+func stringify(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
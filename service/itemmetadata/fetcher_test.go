@@ -0,0 +1,44 @@
+package itemmetadata
+
+import (
+	"testing"
+)
+
+func TestResolveUri(t *testing.T) {
+	gateway := "https://gateway.example/ipfs/"
+	cases := map[string]string{
+		"ipfs://QmHash/1.json":      "https://gateway.example/ipfs/QmHash/1.json",
+		"ipfs://ipfs/QmHash/1.json": "https://gateway.example/ipfs/QmHash/1.json",
+		"https://meta.example/1":    "https://meta.example/1",
+	}
+	for uri, expected := range cases {
+		if got := resolveUri(uri, gateway); got != expected {
+			t.Errorf("resolveUri(%s): expected %s, got %s", uri, expected, got)
+		}
+	}
+}
+
+func TestErc1155Uri(t *testing.T) {
+	got := erc1155Uri("https://meta.example/{id}.json", "314592")
+	expected := "https://meta.example/000000000000000000000000000000000000000000000000000000000004cce0.json"
+	if got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestDecodeMetadata(t *testing.T) {
+	content := []byte(`{"name":"Token #1","image_url":"ipfs://QmImage","attributes":[{"trait_type":"Background","value":"Blue"},{"trait_type":"Level","value":5},{"value":"no trait type"}]}`)
+	metadata, err := decodeMetadata(content)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if metadata.Name != "Token #1" || metadata.Image != "ipfs://QmImage" {
+		t.Errorf("Unexpected metadata: %+v", metadata)
+	}
+	if len(metadata.Attributes) != 2 {
+		t.Fatalf("Expected 2 attributes, got %d", len(metadata.Attributes))
+	}
+	if metadata.Attributes[1].TraitType != "Level" || metadata.Attributes[1].Value != "5" {
+		t.Errorf("Unexpected attribute: %+v", metadata.Attributes[1])
+	}
+}
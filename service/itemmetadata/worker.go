@@ -0,0 +1,355 @@
+package itemmetadata
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/chain/chainclient"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/retry"
+	"github.com/yaoxc/EasySwapBase/stores/gdb"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/zeromicro/go-zero/core/threading"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/rarity"
+)
+
+const (
+	metadataQueueSize    = 10000
+	drainPollInterval    = 100 * time.Millisecond
+	defaultIpfsGateway   = "https://ipfs.io/ipfs/"
+	defaultHttpTimeout   = 10 // in seconds
+	defaultMaxRetries    = 3
+	defaultHostRateLimit = 5
+	defaultWorkerNum     = 4
+	retryInterval        = 2 * time.Second
+	maxNameLength        = 128
+	maxUriLength         = 512
+	maxTraitLength       = 128
+	maxTraitValueLength  = 512
+)
+
+const tokenUriAbi = `[
+{"inputs":[{"internalType":"uint256","name":"tokenId","type":"uint256"}],"name":"tokenURI","outputs":[{"internalType":"string","name":"","type":"string"}],"stateMutability":"view","type":"function"},
+{"inputs":[{"internalType":"uint256","name":"id","type":"uint256"}],"name":"uri","outputs":[{"internalType":"string","name":"","type":"string"}],"stateMutability":"view","type":"function"}
+]`
+
+type itemKey struct {
+	CollectionAddress string
+	TokenId           string
+}
+
+// Worker 新 item 的元数据抓取器：解析 tokenURI/uri，拉取 JSON，写入 name、image_uri 和 trait
+type Worker struct {
+	ctx         context.Context
+	cfg         config.MetadataCfg
+	db          *gorm.DB
+	chainClient chainclient.ChainClient
+	chain       string
+	project     string
+	parsedAbi   abi.ABI
+	httpClient  *http.Client
+	limiter     *hostLimiter
+	rarity      *rarity.Calculator
+
+	queue   chan itemKey
+	pending map[itemKey]bool
+	lock    *sync.Mutex
+}
+
+func New(ctx context.Context, cfg config.MetadataCfg, db *gorm.DB, chainClient chainclient.ChainClient, chain string, project string, rarityCalculator *rarity.Calculator) (*Worker, error) {
+	if cfg.IpfsGateway == "" {
+		cfg.IpfsGateway = defaultIpfsGateway
+	}
+	if cfg.HttpTimeout <= 0 {
+		cfg.HttpTimeout = defaultHttpTimeout
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+	if cfg.HostRateLimit <= 0 {
+		cfg.HostRateLimit = defaultHostRateLimit
+	}
+	if cfg.WorkerNum <= 0 {
+		cfg.WorkerNum = defaultWorkerNum
+	}
+
+	parsedAbi, err := abi.JSON(strings.NewReader(tokenUriAbi))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on parse token uri abi")
+	}
+
+	return &Worker{
+		ctx:         ctx,
+		cfg:         cfg,
+		db:          db,
+		chainClient: chainClient,
+		chain:       chain,
+		project:     project,
+		parsedAbi:   parsedAbi,
+		httpClient:  &http.Client{Timeout: time.Duration(cfg.HttpTimeout) * time.Second},
+		limiter:     newHostLimiter(cfg.HostRateLimit),
+		rarity:      rarityCalculator,
+		queue:       make(chan itemKey, metadataQueueSize),
+		pending:     make(map[itemKey]bool),
+		lock:        &sync.Mutex{},
+	}, nil
+}
+
+func (w *Worker) Start() {
+	threading.GoSafe(w.loadPendingItems)
+	for i := 0; i < w.cfg.WorkerNum; i++ {
+		threading.GoSafe(w.fetchLoop)
+	}
+}
+
+// Submit 提交一个需要抓取元数据的 item，队列满时丢弃，重启后由 loadPendingItems 补偿
+func (w *Worker) Submit(collectionAddress string, tokenId string) {
+	if w == nil { // 重放等离线场景不抓取
+		return
+	}
+	key := itemKey{CollectionAddress: strings.ToLower(collectionAddress), TokenId: tokenId}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.pending[key] {
+		return
+	}
+
+	select {
+	case w.queue <- key:
+		w.pending[key] = true
+	default:
+		xzap.WithContext(w.ctx).Warn("item metadata queue is full",
+			zap.String("collection_address", key.CollectionAddress),
+			zap.String("token_id", key.TokenId))
+	}
+}
+
+// Drain 等待队列中已提交的 item 全部抓取完成，ctx 结束时返回剩余数量；
+// 未抓取的 item 重启后由 loadPendingItems 补偿
+func (w *Worker) Drain(ctx context.Context) error {
+	if w == nil {
+		return nil
+	}
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		w.lock.Lock()
+		remaining := len(w.pending)
+		w.lock.Unlock()
+		if remaining == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "%d items left in metadata queue", remaining)
+		case <-ticker.C:
+		}
+	}
+}
+
+// loadPendingItems 启动时加载还没有 ob_item_external 记录的 item
+func (w *Worker) loadPendingItems() {
+	var items []itemKey
+	sql := fmt.Sprintf(`SELECT ci.collection_address as collection_address, ci.token_id as token_id
+FROM %s as ci
+         left join %s ce on ce.collection_address = ci.collection_address and ce.token_id = ci.token_id
+WHERE ce.id is null limit %d`, gdb.GetMultiProjectItemTableName(w.project, w.chain), gdb.GetMultiProjectItemExternalTableName(w.project, w.chain), metadataQueueSize/2)
+	if err := w.db.WithContext(w.ctx).Raw(sql).Scan(&items).Error; err != nil {
+		xzap.WithContext(w.ctx).Error("failed on load pending metadata items", zap.Error(err))
+		return
+	}
+
+	for _, item := range items {
+		w.Submit(item.CollectionAddress, item.TokenId)
+	}
+}
+
+func (w *Worker) fetchLoop() {
+	for {
+		select {
+		case <-w.ctx.Done():
+			xzap.WithContext(w.ctx).Info("item metadata fetchLoop stopped due to context cancellation")
+			return
+		case key := <-w.queue:
+			if err := w.Refresh(key.CollectionAddress, key.TokenId); err != nil {
+				xzap.WithContext(w.ctx).Error("failed on refresh item metadata",
+					zap.String("collection_address", key.CollectionAddress),
+					zap.String("token_id", key.TokenId),
+					zap.Error(err))
+			}
+
+			w.lock.Lock()
+			delete(w.pending, key)
+			w.lock.Unlock()
+		}
+	}
+}
+
+// Refresh 抓取并保存单个 item 的元数据，失败时按配置次数重试
+func (w *Worker) Refresh(collectionAddress string, tokenId string) error {
+	var tokenUri string
+	var metadata *Metadata
+	err := retry.Retry(func(attempt uint) error {
+		var err error
+		if tokenUri == "" {
+			if tokenUri, err = w.tokenUri(collectionAddress, tokenId); err != nil {
+				return err
+			}
+		}
+
+		body, err := w.fetchMetadata(tokenUri)
+		if err != nil {
+			return err
+		}
+		metadata, err = decodeMetadata(body)
+		return err
+	}, retry.Limit(uint(w.cfg.MaxRetries)), retry.Wait(retryInterval))
+	if err != nil {
+		w.markFailed(collectionAddress, tokenId, tokenUri)
+		return errors.Wrap(err, "failed on fetch item metadata")
+	}
+
+	if err := w.persistMetadata(collectionAddress, tokenId, tokenUri, metadata); err != nil {
+		return err
+	}
+	// trait 已变化，collection 需要重算稀有度
+	w.rarity.MarkDirty(collectionAddress)
+	return nil
+}
+
+// tokenUri 先按 ERC721 tokenURI 查询，失败后按 ERC1155 uri 查询
+func (w *Worker) tokenUri(collectionAddress string, tokenId string) (string, error) {
+	id, ok := parseBigInt(tokenId)
+	if !ok {
+		return "", errors.New(fmt.Sprintf("invalid token id: %s", tokenId))
+	}
+
+	uri, err := w.callString(collectionAddress, "tokenURI", id)
+	if err == nil {
+		return uri, nil
+	}
+	uri, err = w.callString(collectionAddress, "uri", id)
+	if err != nil {
+		return "", errors.Wrap(err, "failed on get token uri")
+	}
+	return erc1155Uri(uri, tokenId), nil
+}
+
+func (w *Worker) callString(address string, method string, args ...interface{}) (string, error) {
+	data, err := w.parsedAbi.Pack(method, args...)
+	if err != nil {
+		return "", errors.Wrap(err, fmt.Sprintf("failed on pack %s", method))
+	}
+
+	to := common.HexToAddress(address)
+	respData, err := w.chainClient.CallContract(w.ctx, ethereum.CallMsg{To: &to, Data: data}, nil)
+	if err != nil {
+		return "", errors.Wrap(err, fmt.Sprintf("failed on call %s", method))
+	}
+
+	res, err := w.parsedAbi.Unpack(method, respData)
+	if err != nil || len(res) == 0 {
+		return "", errors.New(fmt.Sprintf("failed on unpack %s", method))
+	}
+	return res[0].(string), nil
+}
+
+// persistMetadata 在一个事务中更新 item 名称、外部信息和全部 trait
+func (w *Worker) persistMetadata(collectionAddress string, tokenId string, tokenUri string, metadata *Metadata) error {
+	return w.db.WithContext(w.ctx).Transaction(func(tx *gorm.DB) error {
+		if metadata.Name != "" {
+			if err := tx.Table(gdb.GetMultiProjectItemTableName(w.project, w.chain)).
+				Where("collection_address = ? and token_id = ?", collectionAddress, tokenId).
+				Update("name", truncate(metadata.Name, maxNameLength)).Error; err != nil {
+				return errors.Wrap(err, "failed on update item name")
+			}
+		}
+
+		external := multi.ItemExternal{
+			CollectionAddress: collectionAddress,
+			TokenId:           tokenId,
+			MetaDataUri:       truncate(tokenUri, maxUriLength),
+			ImageUri:          truncate(resolveUri(metadata.Image, w.cfg.IpfsGateway), maxUriLength),
+			UploadStatus:      multi.OK,
+		}
+		if err := tx.Table(gdb.GetMultiProjectItemExternalTableName(w.project, w.chain)).
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "collection_address"}, {Name: "token_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"meta_data_uri", "image_uri", "upload_status", "update_time"}),
+			}).Create(&external).Error; err != nil {
+			return errors.Wrap(err, "failed on upsert item external")
+		}
+
+		if err := tx.Table(gdb.GetMultiProjectItemTraitTableName(w.project, w.chain)).
+			Where("collection_address = ? and token_id = ?", collectionAddress, tokenId).
+			Delete(&multi.ItemTrait{}).Error; err != nil {
+			return errors.Wrap(err, "failed on delete item traits")
+		}
+		if len(metadata.Attributes) == 0 {
+			return nil
+		}
+
+		traits := make([]multi.ItemTrait, 0, len(metadata.Attributes))
+		for _, attr := range metadata.Attributes {
+			traits = append(traits, multi.ItemTrait{
+				CollectionAddress: collectionAddress,
+				TokenId:           tokenId,
+				Trait:             truncate(attr.TraitType, maxTraitLength),
+				TraitValue:        truncate(attr.Value, maxTraitValueLength),
+			})
+		}
+		if err := tx.Table(gdb.GetMultiProjectItemTraitTableName(w.project, w.chain)).
+			Create(&traits).Error; err != nil {
+			return errors.Wrap(err, "failed on create item traits")
+		}
+		return nil
+	})
+}
+
+// markFailed 记录抓取失败，避免重启后反复重试同一个 item
+func (w *Worker) markFailed(collectionAddress string, tokenId string, tokenUri string) {
+	external := multi.ItemExternal{
+		CollectionAddress: collectionAddress,
+		TokenId:           tokenId,
+		MetaDataUri:       truncate(tokenUri, maxUriLength),
+		UploadStatus:      multi.FetchMetadataFailed,
+	}
+	if err := w.db.WithContext(w.ctx).Table(gdb.GetMultiProjectItemExternalTableName(w.project, w.chain)).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "collection_address"}, {Name: "token_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"meta_data_uri", "upload_status", "update_time"}),
+		}).Create(&external).Error; err != nil {
+		xzap.WithContext(w.ctx).Error("failed on mark item metadata failed",
+			zap.String("collection_address", collectionAddress),
+			zap.String("token_id", tokenId),
+			zap.Error(err))
+	}
+}
+
+func parseBigInt(s string) (*big.Int, bool) {
+	return new(big.Int).SetString(s, 10)
+}
+
+// truncate 按字符截断，避免超过列长度
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) > length {
+		return string(runes[:length])
+	}
+	return s
+}
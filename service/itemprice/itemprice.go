@@ -0,0 +1,181 @@
+package itemprice
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/stores/gdb"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/yaoxc/EasySwapSync/service/comm"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+)
+
+type listing struct {
+	CollectionAddress string
+	TokenId           string
+	CurrencyAddress   string
+	Price             decimal.Decimal
+	EventTime         int64
+}
+
+type sale struct {
+	CollectionAddress string
+	TokenId           string
+	CurrencyAddress   string
+	Price             decimal.Decimal
+}
+
+// cheapestListings 把 listing 价格折合成 ETH 后取每个 item 的最低价，价格相同时取最早的挂单，
+// 返回的 Price 为折合 ETH 的价格，未配置的币种不参与比较
+func cheapestListings(listings []listing, currencies *currency.Registry) []listing {
+	index := make(map[string]int)
+	cheapest := make([]listing, 0)
+	for _, l := range listings {
+		ethPrice, err := currencies.ToEth(l.CurrencyAddress, l.Price)
+		if err != nil {
+			continue
+		}
+		l.Price = ethPrice
+		key := strings.ToLower(l.CollectionAddress) + ":" + l.TokenId
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(cheapest)
+			cheapest = append(cheapest, l)
+			continue
+		}
+		if l.Price.LessThan(cheapest[i].Price) || (l.Price.Equal(cheapest[i].Price) && l.EventTime < cheapest[i].EventTime) {
+			cheapest[i] = l
+		}
+	}
+	return cheapest
+}
+
+// RefreshListing 按当前有效 listing 重新计算 item 的 list_price/list_time，没有有效 listing 时置空；
+// 不同币种的 listing 折合成 ETH 后比较，list_price 记录折合 ETH 的价格。
+// tx 由调用方传入，保证与订单状态变更在同一个事务中
+func RefreshListing(tx *gorm.DB, project string, chain string, currencies *currency.Registry, collectionAddress string, tokenId string) error {
+	var listings []listing
+	sql := fmt.Sprintf(`SELECT ci.collection_address as collection_address, ci.token_id as token_id, co.currency_address as currency_address, co.price as price, co.event_time as event_time
+FROM %s as ci
+         join %s co on co.collection_address = ci.collection_address and co.token_id = ci.token_id
+WHERE ci.collection_address = ? and ci.token_id = ? and (%s)`,
+		gdb.GetMultiProjectItemTableName(project, chain),
+		gdb.GetMultiProjectOrderTableName(project, chain),
+		comm.ActiveListingCondition)
+	args := append([]interface{}{strings.ToLower(collectionAddress), tokenId}, comm.ActiveListingArgs()...)
+	if err := tx.Raw(sql, args...).Scan(&listings).Error; err != nil {
+		return errors.Wrap(err, "failed on query item cheapest listing")
+	}
+
+	updates := map[string]interface{}{"list_price": nil, "list_time": nil}
+	if cheapest := cheapestListings(listings, currencies); len(cheapest) > 0 {
+		updates = map[string]interface{}{"list_price": cheapest[0].Price, "list_time": cheapest[0].EventTime}
+	}
+	if err := tx.Table(gdb.GetMultiProjectItemTableName(project, chain)).
+		Where("collection_address = ? and token_id = ?", strings.ToLower(collectionAddress), tokenId).
+		Updates(updates).Error; err != nil {
+		return errors.Wrap(err, "failed on update item list price")
+	}
+	return nil
+}
+
+// UpdateSalePrice 记录 item 最近一次成交价，与 list_price 一致折合成 ETH 记录；
+// 未配置的币种无法折合，保留原有 sale_price
+func UpdateSalePrice(tx *gorm.DB, project string, chain string, currencies *currency.Registry, collectionAddress string, tokenId string, currencyAddress string, price decimal.Decimal) error {
+	ethPrice, err := currencies.ToEth(currencyAddress, price)
+	if err != nil {
+		return nil
+	}
+	if err := tx.Table(gdb.GetMultiProjectItemTableName(project, chain)).
+		Where("collection_address = ? and token_id = ?", strings.ToLower(collectionAddress), tokenId).
+		Update("sale_price", ethPrice).Error; err != nil {
+		return errors.Wrap(err, "failed on update item sale price")
+	}
+	return nil
+}
+
+// RefreshExpiredListings 重新计算在 (since, until] 区间内过期的 listing 对应 item 的 list_price/list_time，
+// expire_time 为 0 的 listing 永不过期，返回刷新的 item 数量
+func RefreshExpiredListings(ctx context.Context, db *gorm.DB, project string, chain string, currencies *currency.Registry, since int64, until int64) (int, error) {
+	var items []sale
+	if err := db.WithContext(ctx).Table(gdb.GetMultiProjectOrderTableName(project, chain)).
+		Select("distinct collection_address, token_id").
+		Where("order_type = ? and expire_time > 0 and expire_time > ? and expire_time <= ?", multi.ListingOrder, since, until).
+		Scan(&items).Error; err != nil {
+		return 0, errors.Wrap(err, "failed on query expired listings")
+	}
+
+	for _, item := range items {
+		if err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return RefreshListing(tx, project, chain, currencies, item.CollectionAddress, item.TokenId)
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return len(items), nil
+}
+
+// Rebuild 根据 ob_order 与 ob_activity 重建全部 item 的 list_price、list_time 和 sale_price，
+// list_price 与 RefreshListing 一致按折合 ETH 的最低价计算，sale_price 与 UpdateSalePrice 一致折合成 ETH
+func Rebuild(ctx context.Context, db *gorm.DB, project string, chain string, currencies *currency.Registry) error {
+	itemTable := gdb.GetMultiProjectItemTableName(project, chain)
+
+	var listings []listing
+	sql := fmt.Sprintf(`SELECT ci.collection_address as collection_address, ci.token_id as token_id, co.currency_address as currency_address, co.price as price, co.event_time as event_time
+FROM %s as ci
+         join %s co on co.collection_address = ci.collection_address and co.token_id = ci.token_id
+WHERE (%s)`,
+		itemTable, gdb.GetMultiProjectOrderTableName(project, chain), comm.ActiveListingCondition)
+	if err := db.WithContext(ctx).Raw(sql, comm.ActiveListingArgs()...).Scan(&listings).Error; err != nil {
+		return errors.Wrap(err, "failed on query active listings")
+	}
+	cheapest := cheapestListings(listings, currencies)
+
+	var sales []sale
+	sql = fmt.Sprintf(`SELECT collection_address, token_id, currency_address, price
+FROM %s
+WHERE activity_type = ?
+ORDER BY collection_address, token_id, block_number desc, id desc`, gdb.GetMultiProjectActivityTableName(project, chain))
+	if err := db.WithContext(ctx).Raw(sql, multi.Sale).Scan(&sales).Error; err != nil {
+		return errors.Wrap(err, "failed on query sale activities")
+	}
+	latestSales := make([]sale, 0)
+	for i, s := range sales {
+		if i == 0 || !strings.EqualFold(s.CollectionAddress, sales[i-1].CollectionAddress) || s.TokenId != sales[i-1].TokenId {
+			latestSales = append(latestSales, s)
+		}
+	}
+
+	if err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(fmt.Sprintf(`UPDATE %s SET list_price = NULL, list_time = NULL, sale_price = NULL`, itemTable)).Error; err != nil {
+			return errors.Wrap(err, "failed on reset item prices")
+		}
+		for _, l := range cheapest {
+			if err := tx.Table(itemTable).
+				Where("collection_address = ? and token_id = ?", l.CollectionAddress, l.TokenId).
+				Updates(map[string]interface{}{"list_price": l.Price, "list_time": l.EventTime}).Error; err != nil {
+				return errors.Wrap(err, "failed on update item list price")
+			}
+		}
+		for _, s := range latestSales {
+			if err := UpdateSalePrice(tx, project, chain, currencies, s.CollectionAddress, s.TokenId, s.CurrencyAddress, s.Price); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	xzap.WithContext(ctx).Info("item prices rebuilt",
+		zap.Int("listed_items", len(cheapest)),
+		zap.Int("sold_items", len(latestSales)))
+	return nil
+}
@@ -0,0 +1,44 @@
+package itemprice
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+)
+
+func TestCheapestListings(t *testing.T) {
+	currencies, err := currency.New(config.ContractCfg{
+		EthAddress: "0x0000000000000000000000000000000000000000",
+	}, []config.CurrencyCfg{
+		{Address: "0x5FbDB2315678afecb367f032d93F642f64180aa3", Symbol: "USDC", Decimals: 6, EthRate: "0.0004"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listings := []listing{
+		// 1.5 ETH
+		{CollectionAddress: "0xabc", TokenId: "1", CurrencyAddress: "0x0000000000000000000000000000000000000000", Price: decimal.RequireFromString("1500000000000000000"), EventTime: 1},
+		// 2500 USDC = 1 ETH，基础单位数值远小于 wei 但折合后更贵的挂单不能胜出
+		{CollectionAddress: "0xabc", TokenId: "1", CurrencyAddress: "0x5fbdb2315678afecb367f032d93f642f64180aa3", Price: decimal.RequireFromString("5000000000"), EventTime: 2},
+		{CollectionAddress: "0xabc", TokenId: "2", CurrencyAddress: "0x5fbdb2315678afecb367f032d93f642f64180aa3", Price: decimal.RequireFromString("2500000000"), EventTime: 3},
+		{CollectionAddress: "0xabc", TokenId: "2", CurrencyAddress: "0x0000000000000000000000000000000000000000", Price: decimal.RequireFromString("1000000000000000000"), EventTime: 1},
+		// 未配置的币种不参与比较
+		{CollectionAddress: "0xabc", TokenId: "3", CurrencyAddress: "0x1111111111111111111111111111111111111111", Price: decimal.NewFromInt(1), EventTime: 1},
+	}
+	cheapest := cheapestListings(listings, currencies)
+
+	if len(cheapest) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(cheapest))
+	}
+	if cheapest[0].TokenId != "1" || cheapest[0].EventTime != 1 || cheapest[0].Price.String() != "1500000000000000000" {
+		t.Errorf("Unexpected cheapest listing of token 1: %+v", cheapest[0])
+	}
+	// 折合后价格相同时取最早的挂单
+	if cheapest[1].TokenId != "2" || cheapest[1].EventTime != 1 || cheapest[1].Price.String() != "1000000000000000000" {
+		t.Errorf("Unexpected cheapest listing of token 2: %+v", cheapest[1])
+	}
+}
@@ -0,0 +1,236 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/stores/xkv"
+	"go.uber.org/zap"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/metrics"
+)
+
+const (
+	DefaultLeaseTtl = 15 // in seconds
+
+	leaderKeyPre = "cache:es:%s:sync:leader:%s"
+	fenceKeyPre  = "cache:es:%s:sync:leader:%s:fence"
+)
+
+// acquireScript 抢占租约成功时递增并返回 fencing token，失败返回 0
+const acquireScript = `if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0`
+
+// renewScript 租约仍属于自己时续期
+const renewScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`
+
+// releaseScript 租约仍属于自己时释放
+const releaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+
+var (
+	// ErrNotLeader 当前实例没有有效租约，不能提交同步游标
+	ErrNotLeader = errors.New("not the leader")
+	// ErrFenced 已有更新的 leader 写入过同步游标，当前实例的提交被拒绝
+	ErrFenced = errors.New("checkpoint fenced by newer leader")
+)
+
+// scripter 选举用到的 Redis 命令，便于测试时替换
+type scripter interface {
+	EvalCtx(ctx context.Context, script string, keys []string, args ...any) (any, error)
+	GetCtx(ctx context.Context, key string) (string, error)
+}
+
+// Elector 基于 Redis 租约的 leader 选举，每条链每个合约一个租约。
+// 每次当选都会得到递增的 fencing token，提交同步游标时带上 token，
+// 暂停后恢复的旧 leader 即使还以为自己持有租约，也无法覆盖新 leader 的游标
+type Elector struct {
+	ctx      context.Context
+	redis    scripter
+	chain    string
+	key      string
+	fenceKey string
+	id       string
+	ttl      time.Duration
+
+	token      int64     // 当选时得到的 fencing token，0 表示不是 leader
+	leaseUntil time.Time // 按本地时钟保守估计的租约到期时间
+	lock       *sync.RWMutex
+	quit       chan struct{}
+	done       chan struct{}
+	stopOnce   *sync.Once
+}
+
+func New(ctx context.Context, kv *xkv.Store, cfg config.LeaderCfg, chain string, contract string) *Elector {
+	ttl := cfg.LeaseTtl
+	if ttl <= 0 {
+		ttl = DefaultLeaseTtl
+	}
+	hostname, _ := os.Hostname()
+	contract = strings.ToLower(contract)
+	e := &Elector{
+		ctx:      ctx,
+		chain:    chain,
+		key:      fmt.Sprintf(leaderKeyPre, chain, contract),
+		fenceKey: fmt.Sprintf(fenceKeyPre, chain, contract),
+		id:       fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano()),
+		ttl:      time.Duration(ttl) * time.Second,
+		lock:     &sync.RWMutex{},
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+		stopOnce: &sync.Once{},
+	}
+	if kv != nil {
+		e.redis = kv.Redis
+	}
+	return e
+}
+
+// Fence 检查提交游标时带的 token 是否已被更新的 leader 超过，committed 为游标上记录的 token
+func Fence(token, committed int64) error {
+	if token > 0 && committed > token {
+		return errors.Wrapf(ErrFenced, "token %d, committed token %d", token, committed)
+	}
+	return nil
+}
+
+// Token 返回当前的 fencing token 以及租约是否仍然有效，e 为 nil（未开启选举）时总是 leader，token 为 0
+func (e *Elector) Token() (int64, bool) {
+	if e == nil {
+		return 0, true
+	}
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	if e.token == 0 || !time.Now().Before(e.leaseUntil) {
+		return 0, false
+	}
+	return e.token, true
+}
+
+// IsLeader 当前实例是否持有有效租约
+func (e *Elector) IsLeader() bool {
+	_, ok := e.Token()
+	return ok
+}
+
+// Holder 返回当前持有租约的实例 id，没有 leader 时返回空字符串
+func (e *Elector) Holder() (string, error) {
+	holder, err := e.redis.GetCtx(e.ctx, e.key)
+	if err != nil {
+		return "", errors.Wrap(err, "failed on get leader lease holder")
+	}
+	return holder, nil
+}
+
+// Run 持续竞选和续期，直到 Stop 或 ctx 取消，退出时释放租约
+func (e *Elector) Run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		e.tick()
+		select {
+		case <-e.ctx.Done():
+			e.release()
+			return
+		case <-e.quit:
+			e.release()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop 停止竞选并释放租约，等待 Run 返回
+func (e *Elector) Stop() {
+	if e == nil {
+		return
+	}
+	e.stopOnce.Do(func() {
+		close(e.quit)
+	})
+	<-e.done
+}
+
+func (e *Elector) tick() {
+	start := time.Now()
+	e.lock.RLock()
+	token := e.token
+	e.lock.RUnlock()
+
+	if token > 0 {
+		renewed, err := e.eval(renewScript, []string{e.key}, e.id, e.ttl.Milliseconds())
+		if err != nil {
+			// 网络抖动时保留本地租约，到期后 Token 自然失效
+			xzap.WithContext(e.ctx).Warn("failed on renew leader lease", zap.String("key", e.key), zap.Error(err))
+			return
+		}
+		if renewed == 0 {
+			e.setLeader(0, time.Time{})
+			xzap.WithContext(e.ctx).Warn("leader lease lost", zap.String("key", e.key), zap.Int64("token", token))
+			return
+		}
+		e.setLeader(token, start.Add(e.ttl))
+		return
+	}
+
+	token, err := e.eval(acquireScript, []string{e.key, e.fenceKey}, e.id, e.ttl.Milliseconds())
+	if err != nil {
+		xzap.WithContext(e.ctx).Warn("failed on acquire leader lease", zap.String("key", e.key), zap.Error(err))
+		return
+	}
+	if token > 0 {
+		e.setLeader(token, start.Add(e.ttl))
+		xzap.WithContext(e.ctx).Info("elected as leader", zap.String("key", e.key), zap.Int64("token", token))
+	}
+}
+
+func (e *Elector) release() {
+	e.lock.RLock()
+	token := e.token
+	e.lock.RUnlock()
+	if token == 0 {
+		return
+	}
+	e.setLeader(0, time.Time{})
+	// ctx 可能已取消，释放使用独立的 ctx
+	if _, err := e.redis.EvalCtx(context.Background(), releaseScript, []string{e.key}, e.id); err != nil {
+		xzap.WithContext(e.ctx).Warn("failed on release leader lease", zap.String("key", e.key), zap.Error(err))
+		return
+	}
+	xzap.WithContext(e.ctx).Info("leader lease released", zap.String("key", e.key), zap.Int64("token", token))
+}
+
+func (e *Elector) setLeader(token int64, leaseUntil time.Time) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.token = token
+	e.leaseUntil = leaseUntil
+	metrics.SetLeader(e.chain, token > 0)
+}
+
+func (e *Elector) eval(script string, keys []string, args ...any) (int64, error) {
+	val, err := e.redis.EvalCtx(e.ctx, script, keys, args...)
+	if err != nil {
+		return 0, err
+	}
+	result, ok := val.(int64)
+	if !ok {
+		return 0, errors.Errorf("unexpected script result %v", val)
+	}
+	return result, nil
+}
@@ -0,0 +1,207 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"go.uber.org/zap"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+)
+
+// fakeRedis 在内存中模拟选举脚本的语义
+type fakeRedis struct {
+	lock   sync.Mutex
+	values map[string]string
+	fences map[string]int64
+	err    error
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: make(map[string]string), fences: make(map[string]int64)}
+}
+
+func (f *fakeRedis) EvalCtx(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	id := args[0].(string)
+	holder, held := f.values[keys[0]]
+	switch script {
+	case acquireScript:
+		if held {
+			return int64(0), nil
+		}
+		f.values[keys[0]] = id
+		f.fences[keys[1]]++
+		return f.fences[keys[1]], nil
+	case renewScript:
+		if holder != id {
+			return int64(0), nil
+		}
+		return int64(1), nil
+	case releaseScript:
+		if holder != id {
+			return int64(0), nil
+		}
+		delete(f.values, keys[0])
+		return int64(1), nil
+	}
+	return nil, errors.Errorf("unknown script %q", script)
+}
+
+func (f *fakeRedis) GetCtx(ctx context.Context, key string) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.values[key], nil
+}
+
+// expire 模拟租约在 Redis 中过期
+func (f *fakeRedis) expire(key string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.values, key)
+}
+
+func newTestElector(ctx context.Context, redis *fakeRedis) *Elector {
+	e := New(xzap.ToContext(ctx, zap.NewNop()), nil, config.LeaderCfg{}, "sepolia", "0xABCD")
+	e.redis = redis
+	return e
+}
+
+func TestElectorAcquireAndRenew(t *testing.T) {
+	redis := newFakeRedis()
+	e := newTestElector(context.Background(), redis)
+	if e.IsLeader() {
+		t.Fatal("expected no leadership before first tick")
+	}
+
+	e.tick()
+	token, ok := e.Token()
+	if !ok || token != 1 {
+		t.Fatalf("expected token 1 after acquire, got %d %v", token, ok)
+	}
+	if holder, _ := e.Holder(); holder != e.id {
+		t.Errorf("expected holder %s, got %s", e.id, holder)
+	}
+
+	// 续期保持原 token，并延长本地租约
+	before := e.leaseUntil
+	time.Sleep(time.Millisecond)
+	e.tick()
+	token, ok = e.Token()
+	if !ok || token != 1 {
+		t.Fatalf("expected token 1 after renew, got %d %v", token, ok)
+	}
+	if !e.leaseUntil.After(before) {
+		t.Errorf("expected lease extended, got %v <= %v", e.leaseUntil, before)
+	}
+}
+
+func TestElectorSecondInstanceWaits(t *testing.T) {
+	redis := newFakeRedis()
+	first := newTestElector(context.Background(), redis)
+	second := newTestElector(context.Background(), redis)
+	second.id = first.id + "-2"
+
+	first.tick()
+	second.tick()
+	if !first.IsLeader() || second.IsLeader() {
+		t.Fatalf("expected only first to lead, got first=%v second=%v", first.IsLeader(), second.IsLeader())
+	}
+}
+
+func TestElectorLeaseLost(t *testing.T) {
+	redis := newFakeRedis()
+	first := newTestElector(context.Background(), redis)
+	second := newTestElector(context.Background(), redis)
+	second.id = first.id + "-2"
+
+	first.tick()
+	// first 暂停期间租约过期，second 当选并得到更大的 token
+	redis.expire(first.key)
+	second.tick()
+	token, ok := second.Token()
+	if !ok || token != 2 {
+		t.Fatalf("expected second elected with token 2, got %d %v", token, ok)
+	}
+
+	// first 恢复后续期失败，放弃 leader
+	first.tick()
+	if first.IsLeader() {
+		t.Fatal("expected first to lose leadership after failed renew")
+	}
+	if err := Fence(1, token); !errors.Is(err, ErrFenced) {
+		t.Errorf("expected stale token fenced, got %v", err)
+	}
+}
+
+func TestElectorKeepsLeaseOnRenewError(t *testing.T) {
+	redis := newFakeRedis()
+	e := newTestElector(context.Background(), redis)
+	e.tick()
+
+	// 网络抖动不立即放弃租约，由本地到期时间兜底
+	redis.err = errors.New("connection reset")
+	e.tick()
+	if !e.IsLeader() {
+		t.Fatal("expected leadership kept on renew error")
+	}
+	e.setLeader(1, time.Now().Add(-time.Millisecond))
+	if e.IsLeader() {
+		t.Fatal("expected leadership expired after local lease deadline")
+	}
+}
+
+func TestElectorReleaseOnStop(t *testing.T) {
+	redis := newFakeRedis()
+	e := newTestElector(context.Background(), redis)
+	go e.Run()
+
+	deadline := time.Now().Add(time.Second)
+	for !e.IsLeader() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !e.IsLeader() {
+		t.Fatal("expected elected after Run")
+	}
+	e.Stop()
+	if e.IsLeader() {
+		t.Error("expected leadership released after Stop")
+	}
+	if holder, _ := e.Holder(); holder != "" {
+		t.Errorf("expected lease deleted after Stop, got holder %s", holder)
+	}
+}
+
+func TestFence(t *testing.T) {
+	cases := []struct {
+		token, committed int64
+		fenced           bool
+	}{
+		{token: 0, committed: 5},
+		{token: 3, committed: 0},
+		{token: 3, committed: 3},
+		{token: 3, committed: 4, fenced: true},
+	}
+	for _, c := range cases {
+		err := Fence(c.token, c.committed)
+		if errors.Is(err, ErrFenced) != c.fenced {
+			t.Errorf("Fence(%d, %d) = %v, fenced %v", c.token, c.committed, err, c.fenced)
+		}
+	}
+}
+
+func TestNilElectorAlwaysLeads(t *testing.T) {
+	var e *Elector
+	if token, ok := e.Token(); !ok || token != 0 {
+		t.Fatalf("expected nil elector to lead with token 0, got %d %v", token, ok)
+	}
+	e.Stop()
+}
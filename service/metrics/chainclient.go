@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/yaoxc/EasySwapBase/chain/chainclient"
+	logTypes "github.com/yaoxc/EasySwapBase/chain/types"
+)
+
+// instrumentedChainClient 记录每次 RPC 调用的耗时和错误
+type instrumentedChainClient struct {
+	chainclient.ChainClient
+	chain string
+}
+
+// InstrumentChainClient 给 chainClient 加上 RPC 指标
+func InstrumentChainClient(client chainclient.ChainClient, chain string) chainclient.ChainClient {
+	return &instrumentedChainClient{ChainClient: client, chain: chain}
+}
+
+func (c *instrumentedChainClient) FilterLogs(ctx context.Context, q logTypes.FilterQuery) ([]interface{}, error) {
+	start := time.Now()
+	logs, err := c.ChainClient.FilterLogs(ctx, q)
+	ObserveRPC(c.chain, "eth_getLogs", start, err)
+	return logs, err
+}
+
+func (c *instrumentedChainClient) BlockTimeByNumber(ctx context.Context, blockNumber *big.Int) (uint64, error) {
+	start := time.Now()
+	blockTime, err := c.ChainClient.BlockTimeByNumber(ctx, blockNumber)
+	ObserveRPC(c.chain, "eth_getBlockByNumber", start, err)
+	return blockTime, err
+}
+
+func (c *instrumentedChainClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	start := time.Now()
+	out, err := c.ChainClient.CallContract(ctx, msg, blockNumber)
+	ObserveRPC(c.chain, "eth_call", start, err)
+	return out, err
+}
+
+func (c *instrumentedChainClient) CallContractByChain(ctx context.Context, param logTypes.CallParam) (interface{}, error) {
+	start := time.Now()
+	out, err := c.ChainClient.CallContractByChain(ctx, param)
+	ObserveRPC(c.chain, "eth_call", start, err)
+	return out, err
+}
+
+func (c *instrumentedChainClient) BlockNumber() (uint64, error) {
+	start := time.Now()
+	blockNumber, err := c.ChainClient.BlockNumber()
+	ObserveRPC(c.chain, "eth_blockNumber", start, err)
+	return blockNumber, err
+}
+
+func (c *instrumentedChainClient) BlockWithTxs(ctx context.Context, blockNumber uint64) (interface{}, error) {
+	start := time.Now()
+	block, err := c.ChainClient.BlockWithTxs(ctx, blockNumber)
+	ObserveRPC(c.chain, "eth_getBlockByNumber", start, err)
+	return block, err
+}
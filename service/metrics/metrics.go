@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace   = "easyswap_sync"
+	MetricsPath = "/metrics"
+)
+
+var (
+	headBlock = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "head_block",
+		Help:      "Latest block number of the chain.",
+	}, []string{"chain"})
+	lastIndexedBlock = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_indexed_block",
+		Help:      "Next block the orderbook indexer will sync from.",
+	}, []string{"chain"})
+	lagBlocks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "lag_blocks",
+		Help:      "Blocks between the chain head and the orderbook indexer.",
+	}, []string{"chain"})
+	logsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logs_processed_total",
+		Help:      "Contract logs processed by event type.",
+	}, []string{"chain", "event"})
+	handlerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "handler_errors_total",
+		Help:      "Event handler errors by event type.",
+	}, []string{"chain", "event"})
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_duration_seconds",
+		Help:      "Chain RPC latency by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"chain", "method"})
+	rpcErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_errors_total",
+		Help:      "Chain RPC errors by method.",
+	}, []string{"chain", "method"})
+	dbWriteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_write_duration_seconds",
+		Help:      "Database write latency by operation and table.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "table"})
+	leader = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "leader",
+		Help:      "Whether this instance holds the indexing leader lease (1) or not (0).",
+	}, []string{"chain"})
+	floorJobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "floor_job_duration_seconds",
+		Help:      "Duration of the collection floor price jobs.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	}, []string{"chain", "job"})
+	webhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts by result (delivered, retry, dead).",
+	}, []string{"chain", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(headBlock, lastIndexedBlock, lagBlocks, logsProcessed, handlerErrors,
+		rpcDuration, rpcErrors, dbWriteDuration, floorJobDuration, leader, webhookDeliveries)
+}
+
+// SetHeadBlock 记录链头高度
+func SetHeadBlock(chain string, head uint64) {
+	headBlock.WithLabelValues(chain).Set(float64(head))
+}
+
+// SetLastIndexedBlock 记录同步游标，并按最近一次的链头计算落后的区块数
+func SetLastIndexedBlock(chain string, head uint64, lastIndexed uint64) {
+	lastIndexedBlock.WithLabelValues(chain).Set(float64(lastIndexed))
+	lag := float64(0)
+	if head > lastIndexed {
+		lag = float64(head - lastIndexed)
+	}
+	lagBlocks.WithLabelValues(chain).Set(lag)
+}
+
+// LogProcessed 记录处理过的日志，err 不为空时同时计入处理错误
+func LogProcessed(chain string, event string, err error) {
+	logsProcessed.WithLabelValues(chain, event).Inc()
+	if err != nil {
+		handlerErrors.WithLabelValues(chain, event).Inc()
+	}
+}
+
+// ObserveRPC 记录一次 RPC 调用的耗时和错误
+func ObserveRPC(chain string, method string, start time.Time, err error) {
+	rpcDuration.WithLabelValues(chain, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		rpcErrors.WithLabelValues(chain, method).Inc()
+	}
+}
+
+// ObserveDBWrite 记录一次数据库写入的耗时
+func ObserveDBWrite(operation string, table string, start time.Time) {
+	dbWriteDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+}
+
+// ObserveFloorJob 记录一次地板价任务的耗时
+func ObserveFloorJob(chain string, job string, start time.Time) {
+	floorJobDuration.WithLabelValues(chain, job).Observe(time.Since(start).Seconds())
+}
+
+// SetLeader 记录当前实例是否为 leader
+func SetLeader(chain string, isLeader bool) {
+	value := float64(0)
+	if isLeader {
+		value = 1
+	}
+	leader.WithLabelValues(chain).Set(value)
+}
+
+// WebhookDelivered 记录一次 webhook 推送的结果
+func WebhookDelivered(chain string, result string) {
+	webhookDeliveries.WithLabelValues(chain, result).Inc()
+}
+
+// Handler Prometheus 指标的 HTTP 处理器
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"go.uber.org/zap"
+
+	"github.com/yaoxc/EasySwapSync/service/health"
+	"github.com/yaoxc/EasySwapSync/service/metrics"
+)
+
+const (
+	DefaultLoopDeadline    = 300 // in seconds，需大于同步循环追平后的休眠间隔
+	DefaultShutdownTimeout = 30  // in seconds
+	httpShutdownTimeout    = 5 * time.Second
+
+	LoopsPath = "/loops"
+)
+
+// MonitorHandler 监控 HTTP 接口：/metrics（开启 metrics_enable 时）、/healthz、/readyz 和常驻循环状态 /loops
+func (s *Service) MonitorHandler() http.Handler {
+	mux := http.NewServeMux()
+	if s.config.Monitor.MetricsEnable {
+		mux.Handle(metrics.MetricsPath, metrics.Handler())
+	}
+	mux.HandleFunc(LoopsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.supervisor.Status())
+	})
+	health.Register(mux, map[string]health.Check{
+		"loops": s.checkLoops,
+	}, map[string]health.Check{
+		"db":          s.checkDB,
+		"redis":       s.checkRedis,
+		"indexer_lag": s.checkIndexerLag,
+		"shutdown":    s.checkShutdown,
+	})
+	return mux
+}
+
+// ServeMonitor 在 http_port 上提供监控接口，ctx 取消后关闭
+func (s *Service) ServeMonitor(ctx context.Context) error {
+	return ServeHTTP(ctx, &http.Server{
+		Addr:    fmt.Sprintf("0.0.0.0:%d", s.config.Monitor.HttpPort),
+		Handler: s.MonitorHandler(),
+	})
+}
+
+// ServeHTTP 运行 server 直到 ctx 取消，取消后等待进行中的请求完成再返回
+func ServeHTTP(ctx context.Context, server *http.Server) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			xzap.WithContext(ctx).Warn("failed on shutdown http server", zap.String("addr", server.Addr), zap.Error(err))
+		}
+	}()
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.Wrapf(err, "failed on serve %s", server.Addr)
+	}
+	<-done
+	return nil
+}
+
+// checkLoops 常驻循环已放弃重启或超过 loop_deadline 没有心跳时失败，等待重启的循环不算失败
+func (s *Service) checkLoops(ctx context.Context) error {
+	if err := s.supervisor.Check(); err != nil {
+		return err
+	}
+	deadline := s.config.Monitor.LoopDeadline
+	if deadline <= 0 {
+		deadline = DefaultLoopDeadline
+	}
+	return s.heartbeat.Check(time.Duration(deadline) * time.Second)
+}
+
+func (s *Service) checkShutdown(ctx context.Context) error {
+	if s.stopping.Load() {
+		return errors.New("shutting down")
+	}
+	return nil
+}
+
+func (s *Service) checkDB(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return errors.Wrap(err, "failed on get sql db")
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (s *Service) checkRedis(ctx context.Context) error {
+	if !s.kvStore.Redis.PingCtx(ctx) {
+		return errors.New("redis ping failed")
+	}
+	return nil
+}
+
+// checkIndexerLag 订单簿同步落后链头超过 max_indexer_lag 个区块时失败，max_indexer_lag 为 0 或不是 leader 时不检查
+func (s *Service) checkIndexerLag(ctx context.Context) error {
+	if s.orderbookIndexer == nil || s.config.Monitor.MaxIndexerLag == 0 || !s.elector.IsLeader() {
+		return nil
+	}
+	head, next := s.orderbookIndexer.Progress()
+	if head == 0 {
+		return errors.New("orderbook indexer has not started")
+	}
+	if head > next && head-next > s.config.Monitor.MaxIndexerLag {
+		return errors.Errorf("orderbook indexer lags %d blocks behind head %d", head-next, head)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package orderbookindexer
+
+import (
+	_ "embed"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+)
+
+// DefaultAbiVersion 内置订单簿合约 ABI 的版本号，未配置 ABI 文件的合约和区块区间使用它解码
+const DefaultAbiVersion = "v1"
+
+//go:embed abi/EasySwapOrderBook_v1.json
+var contractAbi string
+
+// ContractAbi 某个合约在 [FromBlock, ToBlock] 区间内生效的 ABI，ToBlock 为 0 表示一直生效
+type ContractAbi struct {
+	Version   string
+	FromBlock uint64
+	ToBlock   uint64
+	Abi       abi.ABI
+}
+
+func (c ContractAbi) covers(blockNumber uint64) bool {
+	return blockNumber >= c.FromBlock && (c.ToBlock == 0 || blockNumber <= c.ToBlock)
+}
+
+// AbiBook 按合约地址和区块高度选择解码用的 ABI 版本
+type AbiBook struct {
+	versions map[common.Address][]ContractAbi
+	fallback ContractAbi
+}
+
+// LoadAbiBook 读取并解析配置的 ABI 文件，文件不存在、解析失败或同一合约的区块区间重叠都直接返回错误
+func LoadAbiBook(abiCfgs []config.AbiCfg) (*AbiBook, error) {
+	defaultAbi, err := abi.JSON(strings.NewReader(contractAbi))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on parse default contract abi")
+	}
+	book := &AbiBook{
+		versions: make(map[common.Address][]ContractAbi),
+		fallback: ContractAbi{Version: DefaultAbiVersion, Abi: defaultAbi},
+	}
+
+	for _, abiCfg := range abiCfgs {
+		if !common.IsHexAddress(abiCfg.Address) {
+			return nil, errors.Errorf("invalid abi contract address: %s", abiCfg.Address)
+		}
+		if abiCfg.Version == "" {
+			return nil, errors.Errorf("missing abi version for %s", abiCfg.Address)
+		}
+		if abiCfg.ToBlock != 0 && abiCfg.ToBlock < abiCfg.FromBlock {
+			return nil, errors.Errorf("invalid block range of abi %s for %s", abiCfg.Version, abiCfg.Address)
+		}
+		content, err := os.ReadFile(abiCfg.Path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed on read abi file %s", abiCfg.Path)
+		}
+		parsedAbi, err := abi.JSON(strings.NewReader(string(content)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed on parse abi file %s", abiCfg.Path)
+		}
+
+		address := common.HexToAddress(abiCfg.Address)
+		book.versions[address] = append(book.versions[address], ContractAbi{
+			Version:   abiCfg.Version,
+			FromBlock: abiCfg.FromBlock,
+			ToBlock:   abiCfg.ToBlock,
+			Abi:       parsedAbi,
+		})
+	}
+
+	for address, versions := range book.versions {
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i].FromBlock < versions[j].FromBlock
+		})
+		for i := 1; i < len(versions); i++ {
+			if versions[i-1].ToBlock == 0 || versions[i-1].ToBlock >= versions[i].FromBlock {
+				return nil, errors.Errorf("abi %s overlaps %s for %s", versions[i].Version, versions[i-1].Version, address.Hex())
+			}
+		}
+	}
+	return book, nil
+}
+
+// Lookup 返回合约在指定区块生效的 ABI，没有配置覆盖该区块的版本时使用内置 ABI
+func (b *AbiBook) Lookup(address common.Address, blockNumber uint64) ContractAbi {
+	for _, version := range b.versions[address] {
+		if version.covers(blockNumber) {
+			return version
+		}
+	}
+	return b.fallback
+}
+
+// Versions 返回合约所有可能用到的 ABI 版本（含内置版本），用于登记各版本的事件 topic
+func (b *AbiBook) Versions(address common.Address) []ContractAbi {
+	return append([]ContractAbi{b.fallback}, b.versions[address]...)
+}
+
+// unpackEvent 解码事件的非 indexed 参数并按字段名填充 out，返回解码出的全部参数。
+// 新版合约在事件或订单结构中新增的参数（如 currency）out 中没有对应字段时忽略，由调用方从返回值读取；
+// out 中的字段在 ABI 里不存在时返回错误
+func unpackEvent(contractAbi abi.ABI, name string, data []byte, out interface{}) (map[string]interface{}, error) {
+	event, ok := contractAbi.Events[name]
+	if !ok {
+		return nil, errors.Errorf("event %s not found in abi", name)
+	}
+	values := make(map[string]interface{})
+	if err := contractAbi.UnpackIntoMap(values, name, data); err != nil {
+		return nil, err
+	}
+	fields := make(map[string]interface{}, len(values))
+	for _, input := range event.Inputs.NonIndexed() {
+		fields[abi.ToCamelCase(input.Name)] = values[input.Name]
+	}
+
+	dst := reflect.ValueOf(out).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i).Name
+		value, ok := fields[field]
+		if !ok {
+			return nil, errors.Errorf("argument %s of event %s not found in abi", field, name)
+		}
+		if err := copyByName(dst.Field(i), reflect.ValueOf(value)); err != nil {
+			return nil, errors.Wrapf(err, "failed on copy argument %s of event %s", field, name)
+		}
+	}
+	return values, nil
+}
+
+// copyByName 把 src 赋给 dst，结构体按字段名逐个复制（dst 字段有 abi tag 时按 tag 中的参数名），src 中多出的字段忽略
+func copyByName(dst reflect.Value, src reflect.Value) error {
+	if dst.Kind() == reflect.Struct && src.Kind() == reflect.Struct {
+		for i := 0; i < dst.NumField(); i++ {
+			field := dst.Type().Field(i).Name
+			if tag := dst.Type().Field(i).Tag.Get("abi"); tag != "" {
+				field = abi.ToCamelCase(tag)
+			}
+			value := src.FieldByName(field)
+			if !value.IsValid() {
+				return errors.Errorf("field %s not found", field)
+			}
+			if err := copyByName(dst.Field(i), value); err != nil {
+				return errors.Wrapf(err, "failed on copy field %s", field)
+			}
+		}
+		return nil
+	}
+	if !src.Type().ConvertibleTo(dst.Type()) {
+		return errors.Errorf("can not assign %s to %s", src.Type(), dst.Type())
+	}
+	dst.Set(src.Convert(dst.Type()))
+	return nil
+}
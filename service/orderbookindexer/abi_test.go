@@ -0,0 +1,72 @@
+package orderbookindexer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+)
+
+const testDex = "0x5560e1c2E0260c2274e400d80C30CDC4B92dC8ac"
+
+func writeAbi(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "abi.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadAbiBook(t *testing.T) {
+	path := writeAbi(t, contractAbi)
+	book, err := LoadAbiBook([]config.AbiCfg{
+		{Address: testDex, Version: "v3", Path: path, FromBlock: 300},
+		{Address: testDex, Version: "v2", Path: path, FromBlock: 100, ToBlock: 299},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dex := common.HexToAddress(testDex)
+	cases := map[uint64]string{
+		50:   DefaultAbiVersion,
+		100:  "v2",
+		299:  "v2",
+		300:  "v3",
+		9999: "v3",
+	}
+	for blockNumber, want := range cases {
+		if got := book.Lookup(dex, blockNumber).Version; got != want {
+			t.Fatalf("block %d uses abi %s, want %s", blockNumber, got, want)
+		}
+	}
+	if got := book.Lookup(common.HexToAddress("0x01"), 200).Version; got != DefaultAbiVersion {
+		t.Fatalf("unconfigured contract uses abi %s", got)
+	}
+	if versions := book.Versions(dex); len(versions) != 3 {
+		t.Fatalf("unexpected versions %d", len(versions))
+	}
+}
+
+func TestLoadAbiBookFailFast(t *testing.T) {
+	path := writeAbi(t, contractAbi)
+	cases := map[string][]config.AbiCfg{
+		"parse error":   {{Address: testDex, Version: "v2", Path: writeAbi(t, "[{")}},
+		"missing file":  {{Address: testDex, Version: "v2", Path: filepath.Join(t.TempDir(), "none.json")}},
+		"bad address":   {{Address: "0x123", Version: "v2", Path: path}},
+		"empty version": {{Address: testDex, Path: path}},
+		"bad range":     {{Address: testDex, Version: "v2", Path: path, FromBlock: 10, ToBlock: 5}},
+		"overlap": {
+			{Address: testDex, Version: "v2", Path: path, FromBlock: 100},
+			{Address: testDex, Version: "v3", Path: path, FromBlock: 200},
+		},
+	}
+	for name, abiCfgs := range cases {
+		if _, err := LoadAbiBook(abiCfgs); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
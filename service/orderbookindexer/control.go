@@ -0,0 +1,205 @@
+package orderbookindexer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/base"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"go.uber.org/zap"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/comm"
+	"github.com/yaoxc/EasySwapSync/service/metrics"
+)
+
+var (
+	// ErrNotPaused 修改游标前需要先暂停同步
+	ErrNotPaused = errors.New("orderbook indexing is not paused")
+	// ErrCursorAheadOfHead 游标不能快进到链头之后
+	ErrCursorAheadOfHead = errors.New("cursor is ahead of chain head")
+	// ErrCursorBeforeDeployment 游标早于订单簿合约的部署区块
+	ErrCursorBeforeDeployment = errors.New("cursor is before contract deployment block")
+)
+
+// CursorCleanup MoveCursor 清理的派生数据行数
+type CursorCleanup struct {
+	Activities int64 `json:"activities"`
+	Upgrades   int64 `json:"upgrades"`
+}
+
+// pauseKeyPre 暂停标记的 Redis key，按链区分，所有实例共用
+const pauseKeyPre = "cache:es:%s:sync:paused"
+
+// Pause 请求暂停订单簿同步，暂停标记写入 Redis，进程重启或 leader 切换后同步循环仍保持暂停。
+// 同步循环提交完当前区块区间后停下，用 Paused 确认
+func (s *Service) Pause() error {
+	if s.kv != nil {
+		if err := s.kv.Set(s.pauseKey(), "1"); err != nil {
+			return errors.Wrap(err, "failed on persist pause flag")
+		}
+	}
+	s.pauseLock.Lock()
+	defer s.pauseLock.Unlock()
+	s.pauseRequested = true
+	return nil
+}
+
+// Resume 恢复订单簿同步，删除 Redis 中的暂停标记。暂停期间游标可能被 sync cursor 命令修改，
+// 同步循环恢复后重新从数据库读取游标
+func (s *Service) Resume() error {
+	if s.kv != nil {
+		if _, err := s.kv.Del(s.pauseKey()); err != nil {
+			return errors.Wrap(err, "failed on delete pause flag")
+		}
+	}
+	s.pauseLock.Lock()
+	defer s.pauseLock.Unlock()
+	s.pauseRequested = false
+	s.reloadCursor.Store(true)
+	return nil
+}
+
+// PauseRequested 读取 Redis 中的暂停标记，供不运行同步循环的命令行确认 daemon 已被暂停
+func (s *Service) PauseRequested() (bool, error) {
+	if s.kv == nil {
+		return false, nil
+	}
+	requested, err := s.kv.Exists(s.pauseKey())
+	if err != nil {
+		return false, errors.Wrap(err, "failed on load pause flag")
+	}
+	return requested, nil
+}
+
+// Paused 已请求暂停且同步循环已停在区间边界
+func (s *Service) Paused() bool {
+	s.pauseLock.Lock()
+	defer s.pauseLock.Unlock()
+	return s.pauseRequested && s.idle
+}
+
+// enterPause 同步循环每轮开始时调用，从 Redis 读取暂停标记，已请求暂停时返回 true。
+// 读取失败时沿用上一次的状态
+func (s *Service) enterPause() bool {
+	var requested, loaded bool
+	if s.kv != nil {
+		var err error
+		if requested, err = s.kv.Exists(s.pauseKey()); err != nil {
+			xzap.WithContext(s.ctx).Warn("failed on load pause flag", zap.Error(err))
+		} else {
+			loaded = true
+		}
+	}
+
+	s.pauseLock.Lock()
+	defer s.pauseLock.Unlock()
+	if loaded {
+		s.pauseRequested = requested
+	}
+	s.idle = s.pauseRequested
+	return s.idle
+}
+
+func (s *Service) pauseKey() string {
+	return fmt.Sprintf(pauseKeyPre, s.chain)
+}
+
+// MoveCursor 把同步游标回退或快进到 nextBlock，需先暂停同步。cleanup 时删除 nextBlock 及之后区块
+// 产生的活动和合约升级记录，恢复同步后由处理器重新生成。订单不会回滚到旧状态，成交记录也保留：
+// 它是撮合事件的重放保护，删掉后重新同步会再次扣减买单剩余数量、改写 owner 和 sale_price 并重复推送成交
+func (s *Service) MoveCursor(nextBlock uint64, cleanup bool) (CursorCleanup, error) {
+	var result CursorCleanup
+	if !s.Paused() {
+		return result, ErrNotPaused
+	}
+	if head := s.headBlock.Load(); head > 0 && nextBlock > head+1 {
+		return result, errors.Wrapf(ErrCursorAheadOfHead, "next block %d, head %d", nextBlock, head)
+	}
+	if startBlock, ok := s.cfg.ChainCfg.StartBlock(s.cfg.ContractCfg.DexAddress); ok && nextBlock < startBlock {
+		return result, errors.Wrapf(ErrCursorBeforeDeployment, "next block %d, deployment block %d", nextBlock, startBlock)
+	}
+
+	// 先移动游标再清理：清理失败时重新同步也会补回数据，反过来则会丢数据
+	if err := s.SetCursor(nextBlock); err != nil {
+		return result, err
+	}
+	s.reloadCursor.Store(true)
+	s.nextBlock.Store(nextBlock)
+	xzap.WithContext(s.ctx).Info("orderbook event sync block number moved",
+		zap.Uint64("next_block", nextBlock), zap.Bool("cleanup", cleanup))
+	if !cleanup {
+		return result, nil
+	}
+	return s.CleanupDerivedRows(nextBlock)
+}
+
+type derivedTable struct {
+	name  string
+	count *int64
+}
+
+// derivedTables 由订单簿事件派生、可按区块号清理且重新同步能原样补回的表，行数记录到 result 对应字段
+func (s *Service) derivedTables(result *CursorCleanup) []derivedTable {
+	return []derivedTable{
+		{multi.ActivityTableName(s.chain), &result.Activities},
+		{model.ContractUpgradeTableName(s.chain), &result.Upgrades},
+	}
+}
+
+// CountDerivedRows 统计 nextBlock 及之后区块产生的派生数据行数，用于移动游标前预览
+func (s *Service) CountDerivedRows(nextBlock uint64) (CursorCleanup, error) {
+	var result CursorCleanup
+	for _, table := range s.derivedTables(&result) {
+		if err := s.db.WithContext(s.ctx).Table(table.name).
+			Where("block_number >= ?", nextBlock).
+			Count(table.count).Error; err != nil {
+			return result, errors.Wrapf(err, "failed on count %s", table.name)
+		}
+	}
+	return result, nil
+}
+
+// CleanupDerivedRows 删除 nextBlock 及之后区块产生的活动和合约升级记录
+func (s *Service) CleanupDerivedRows(nextBlock uint64) (CursorCleanup, error) {
+	var result CursorCleanup
+	for _, table := range s.derivedTables(&result) {
+		deleted := s.db.WithContext(s.ctx).Exec(fmt.Sprintf(`DELETE FROM %s WHERE block_number >= ?`, table.name), nextBlock)
+		if deleted.Error != nil {
+			return result, errors.Wrapf(deleted.Error, "failed on clean up %s", table.name)
+		}
+		*table.count = deleted.RowsAffected
+	}
+	return result, nil
+}
+
+// UpdateFloorPrices 重新计算所有 collection 的地板价并记录变化，地板价循环和管理接口共用
+func (s *Service) UpdateFloorPrices() error {
+	s.floorLock.Lock()
+	defer s.floorLock.Unlock()
+
+	start := time.Now()
+	floorPrices, err := s.QueryCollectionsFloorPrice()
+	if err != nil {
+		return errors.Wrap(err, "failed on query collections floor change")
+	}
+	if err := s.persistCollectionsFloorChange(floorPrices); err != nil {
+		return errors.Wrap(err, "failed on persist collections floor price")
+	}
+	metrics.ObserveFloorJob(s.chain, "floor_price", start)
+
+	// 记录最近一次计算时间，供 sync status 查看
+	if err := s.db.WithContext(s.ctx).Table(base.IndexedStatusTableName()).
+		Where("chain_id = ? and index_type = ?", s.chainId, comm.CollectionFloorChangeIndexType).
+		Update("last_indexed_time", start.Unix()).Error; err != nil {
+		return errors.Wrap(err, "failed on update collection floor change index status")
+	}
+	return nil
+}
+
+// Chain 链名称
+func (s *Service) Chain() string {
+	return s.chain
+}
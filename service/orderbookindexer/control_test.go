@@ -0,0 +1,177 @@
+package orderbookindexer
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+
+	"github.com/yaoxc/EasySwapSync/model"
+)
+
+// v1Order 内置 ABI 中没有 currency 字段的订单结构
+type v1Order struct {
+	Side     uint8
+	SaleKind uint8
+	Maker    common.Address
+	Nft      testAsset
+	Price    *big.Int
+	Expiry   uint64
+	Salt     uint64
+}
+
+func TestMoveCursorCleanupResync(t *testing.T) {
+	s := newTestService(t)
+	parsedAbi, err := abi.JSON(strings.NewReader(contractAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dex := common.HexToAddress(testDexAddress)
+	buyer := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	seller := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	collection := common.HexToAddress("0x3333333333333333333333333333333333333333")
+	bidKey := common.HexToHash("0x01")
+	listKey := common.HexToHash("0x02")
+	bid := v1Order{
+		Side:     Bid,
+		SaleKind: FixForCollection,
+		Maker:    buyer,
+		Nft:      testAsset{TokenId: big.NewInt(0), Collection: collection, Amount: big.NewInt(3)},
+		Price:    big.NewInt(1e18),
+		Expiry:   4102444800,
+		Salt:     1,
+	}
+	s.blockTimes[100] = 1700000000
+	s.blockTimes[200] = 1700001000
+
+	makeLog := ethereumTypes.Log{
+		Address: dex,
+		Topics: []common.Hash{
+			parsedAbi.Events[LogMakeEvent].ID,
+			common.BigToHash(big.NewInt(Bid)),
+			common.BigToHash(big.NewInt(FixForCollection)),
+			common.BytesToHash(buyer.Bytes()),
+		},
+		Data:        packEvent(t, parsedAbi, LogMakeEvent, bidKey, bid.Nft, bid.Price, bid.Expiry, bid.Salt),
+		BlockNumber: 100,
+		TxHash:      common.HexToHash("0xaa"),
+	}
+	if err := s.handleLog(makeLog); err != nil {
+		t.Fatal(err)
+	}
+
+	// 卖家把 token 5 卖给集合买单
+	take := v1Order{
+		Side:     List,
+		SaleKind: FixForItem,
+		Maker:    seller,
+		Nft:      testAsset{TokenId: big.NewInt(5), Collection: collection, Amount: big.NewInt(1)},
+		Price:    bid.Price,
+		Expiry:   bid.Expiry,
+		Salt:     2,
+	}
+	matchLog := ethereumTypes.Log{
+		Address:     dex,
+		Topics:      []common.Hash{parsedAbi.Events[LogMatchEvent].ID, bidKey, listKey},
+		Data:        packEvent(t, parsedAbi, LogMatchEvent, bid, take, bid.Price),
+		BlockNumber: 200,
+		TxHash:      common.HexToHash("0xbb"),
+		Index:       3,
+	}
+	if err := s.handleLog(matchLog); err != nil {
+		t.Fatal(err)
+	}
+	assertRemaining := func(want int64) {
+		t.Helper()
+		var order multi.Order
+		if err := s.db.Table(multi.OrderTableName(testChain)).Where("order_id = ?", bidKey.Hex()).First(&order).Error; err != nil {
+			t.Fatal(err)
+		}
+		if order.QuantityRemaining != want || order.OrderStatus != multi.OrderStatusActive {
+			t.Fatalf("bid quantity remaining %d status %d, want %d active", order.QuantityRemaining, order.OrderStatus, want)
+		}
+	}
+	assertRemaining(2)
+
+	if err := s.SetCursor(300); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	s.enterPause()
+	cleanup, err := s.MoveCursor(200, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cleanup.Activities != 1 {
+		t.Fatalf("expected the sale activity cleaned up, got %+v", cleanup)
+	}
+	if next, err := s.LoadCursor(); err != nil || next != 200 {
+		t.Fatalf("expected cursor 200, got %d %v", next, err)
+	}
+
+	// 重新同步撮合事件：成交记录还在，买单剩余数量不会再次扣减，成交活动补回
+	if err := s.handleLog(matchLog); err != nil {
+		t.Fatal(err)
+	}
+	assertRemaining(2)
+	var fills, sales int64
+	if err := s.db.Table(model.OrderFillTableName(testChain)).Count(&fills).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.Table(multi.ActivityTableName(testChain)).Where("activity_type = ?", multi.Sale).Count(&sales).Error; err != nil {
+		t.Fatal(err)
+	}
+	if fills != 2 || sales != 1 {
+		t.Fatalf("expected 2 fills and 1 sale after resync, got %d fills %d sales", fills, sales)
+	}
+}
+
+func TestPausePersisted(t *testing.T) {
+	s := newTestService(t)
+	if err := s.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Paused() {
+		t.Fatal("expected not paused before the sync loop reaches a boundary")
+	}
+	if !s.enterPause() || !s.Paused() {
+		t.Fatal("expected paused after the sync loop reaches a boundary")
+	}
+
+	// 模拟进程重启或其他实例当选：内存中的状态丢失，从 Redis 读回暂停标记
+	s.pauseRequested, s.idle = false, false
+	if !s.enterPause() {
+		t.Fatal("expected pause flag loaded from redis")
+	}
+
+	if err := s.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if s.enterPause() || s.Paused() {
+		t.Fatal("expected resumed")
+	}
+}
+
+func TestResumeReloadsCursor(t *testing.T) {
+	s := newTestService(t)
+	if err := s.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	s.enterPause()
+	// 暂停期间 sync cursor 命令直接修改数据库中的游标，恢复后同步循环需要重新读取
+	if requested, err := s.PauseRequested(); err != nil || !requested {
+		t.Fatalf("expected pause flag visible to the cli, got %v %v", requested, err)
+	}
+	if err := s.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if !s.reloadCursor.Load() {
+		t.Fatal("expected cursor reload after resume")
+	}
+}
@@ -0,0 +1,39 @@
+package orderbookindexer
+
+import (
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"go.uber.org/zap"
+)
+
+// makeCurrency 从 unpackEvent 解码出的 LogMake 参数中读取订单的计价代币，事件中没有 currency 字段（旧版合约）时按 ETH 处理
+func (s *Service) makeCurrency(values map[string]interface{}) string {
+	return s.checkCurrency(values["currency"])
+}
+
+// matchCurrency 从 unpackEvent 解码出的 LogMatch 参数中读取 makeOrder 的计价代币，撮合双方币种一致
+func (s *Service) matchCurrency(values map[string]interface{}) string {
+	order := reflect.ValueOf(values["makeOrder"])
+	if order.Kind() != reflect.Struct {
+		return s.currencies.Eth()
+	}
+	field := order.FieldByName("Currency")
+	if !field.IsValid() {
+		return s.currencies.Eth()
+	}
+	return s.checkCurrency(field.Interface())
+}
+
+func (s *Service) checkCurrency(value interface{}) string {
+	address, ok := value.(common.Address)
+	if !ok {
+		return s.currencies.Eth()
+	}
+	if _, ok := s.currencies.Get(address.String()); !ok {
+		xzap.WithContext(s.ctx).Warn("order currency is not configured, excluded from eth normalized stats",
+			zap.String("currency", address.String()))
+	}
+	return address.String()
+}
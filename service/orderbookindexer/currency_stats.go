@@ -0,0 +1,144 @@
+package orderbookindexer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/stores/gdb"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/comm"
+)
+
+// currencyPrice collection 在某个计价代币下的价格或交易量
+type currencyPrice struct {
+	CollectionAddress string
+	CurrencyAddress   string
+	Price             decimal.Decimal
+	SaleCount         int64
+}
+
+func currencyKey(collection string, currency string) string {
+	return strings.ToLower(collection) + ":" + strings.ToLower(currency)
+}
+
+// queryCurrencyFloorPrices 按 collection、计价代币分组查询有效 listing 的最低价
+func (s *Service) queryCurrencyFloorPrices() ([]currencyPrice, error) {
+	var floorPrices []currencyPrice
+	sql := fmt.Sprintf(`SELECT co.collection_address as collection_address, co.currency_address as currency_address, min(co.price) as price
+FROM %s as ci
+         left join %s co on co.collection_address = ci.collection_address and co.token_id = ci.token_id
+WHERE (%s) group by co.collection_address, co.currency_address`,
+		gdb.GetMultiProjectItemTableName(s.cfg.ProjectCfg.Name, s.chain),
+		gdb.GetMultiProjectOrderTableName(s.cfg.ProjectCfg.Name, s.chain),
+		comm.ActiveListingCondition)
+	if err := s.db.WithContext(s.ctx).Raw(sql, comm.ActiveListingArgs()...).Scan(&floorPrices).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on get collection currency floor price")
+	}
+	return floorPrices, nil
+}
+
+// lowestEthPrices 把各币种地板价折合成 ETH 后取每个 collection 的最低值，未配置的币种不参与比较
+func (s *Service) lowestEthPrices(prices []currencyPrice) []multi.CollectionFloorPrice {
+	lowest := make(map[string]int)
+	floorPrices := make([]multi.CollectionFloorPrice, 0)
+	for _, p := range prices {
+		ethPrice, err := s.currencies.ToEth(p.CurrencyAddress, p.Price)
+		if err != nil {
+			continue
+		}
+		collection := strings.ToLower(p.CollectionAddress)
+		if i, ok := lowest[collection]; ok {
+			if ethPrice.LessThan(floorPrices[i].Price) {
+				floorPrices[i].Price = ethPrice
+			}
+			continue
+		}
+		lowest[collection] = len(floorPrices)
+		floorPrices = append(floorPrices, multi.CollectionFloorPrice{
+			CollectionAddress: p.CollectionAddress,
+			Price:             ethPrice,
+		})
+	}
+	return floorPrices
+}
+
+// persistCurrencyStats 重新统计各 collection 每个计价代币的地板价、交易量及其折合 ETH 的值
+func (s *Service) persistCurrencyStats() error {
+	floorPrices, err := s.queryCurrencyFloorPrices()
+	if err != nil {
+		return err
+	}
+
+	var volumes []currencyPrice
+	if err := s.db.WithContext(s.ctx).Table(multi.ActivityTableName(s.chain)).
+		Select("collection_address, currency_address, sum(price) as price, count(*) as sale_count").
+		Where("activity_type = ?", multi.Sale).
+		Group("collection_address, currency_address").
+		Scan(&volumes).Error; err != nil {
+		return errors.Wrap(err, "failed on get collection currency volume")
+	}
+
+	statsMap := make(map[string]*model.CollectionCurrencyStats)
+	stats := make([]*model.CollectionCurrencyStats, 0)
+	getStats := func(collection string, currency string) *model.CollectionCurrencyStats {
+		key := currencyKey(collection, currency)
+		if stat, ok := statsMap[key]; ok {
+			return stat
+		}
+		stat := &model.CollectionCurrencyStats{
+			CollectionAddress: strings.ToLower(collection),
+			CurrencyAddress:   strings.ToLower(currency),
+		}
+		statsMap[key] = stat
+		stats = append(stats, stat)
+		return stat
+	}
+	for _, p := range floorPrices {
+		stat := getStats(p.CollectionAddress, p.CurrencyAddress)
+		price := p.Price
+		stat.FloorPrice = &price
+		if ethPrice, err := s.currencies.ToEth(p.CurrencyAddress, p.Price); err == nil {
+			stat.FloorPriceEth = &ethPrice
+		}
+	}
+	for _, v := range volumes {
+		stat := getStats(v.CollectionAddress, v.CurrencyAddress)
+		stat.VolumeTotal = v.Price
+		stat.SaleCount = v.SaleCount
+		if ethVolume, err := s.currencies.ToEth(v.CurrencyAddress, v.Price); err == nil {
+			stat.VolumeTotalEth = ethVolume
+		}
+	}
+
+	if err := s.db.WithContext(s.ctx).Transaction(func(tx *gorm.DB) error {
+		// 没有有效listing的币种地板价置空
+		if err := tx.Table(model.CollectionCurrencyStatsTableName(s.chain)).
+			Where("floor_price is not null").
+			Updates(map[string]interface{}{"floor_price": nil, "floor_price_eth": nil}).Error; err != nil {
+			return errors.Wrap(err, "failed on reset currency floor price")
+		}
+		if len(stats) == 0 {
+			return nil
+		}
+		if err := tx.Table(model.CollectionCurrencyStatsTableName(s.chain)).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "collection_address"}, {Name: "currency_address"}},
+			DoUpdates: clause.AssignmentColumns([]string{"floor_price", "floor_price_eth", "volume_total", "volume_total_eth", "sale_count", "update_time"}),
+		}).CreateInBatches(stats, comm.DBBatchSizeLimit).Error; err != nil {
+			return errors.Wrap(err, "failed on persist currency stats")
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	xzap.WithContext(s.ctx).Info("collection currency stats refreshed", zap.Int("rows", len(stats)))
+	return nil
+}
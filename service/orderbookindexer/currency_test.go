@@ -0,0 +1,287 @@
+package orderbookindexer
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"go.uber.org/zap"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+)
+
+const testV2FromBlock = 100
+
+// currencyAbi 在内置 ABI 的基础上给 LogMake 和订单结构加上 currency 字段，模拟支持 WETH/ERC20 计价的新版合约
+func currencyAbi(t *testing.T) string {
+	var entries []map[string]interface{}
+	if err := json.Unmarshal([]byte(contractAbi), &entries); err != nil {
+		t.Fatal(err)
+	}
+	currency := map[string]interface{}{"internalType": "address", "name": "currency", "type": "address"}
+	for _, entry := range entries {
+		if entry["type"] != "event" {
+			continue
+		}
+		inputs := entry["inputs"].([]interface{})
+		switch entry["name"] {
+		case LogMakeEvent:
+			entry["inputs"] = append(inputs, map[string]interface{}{
+				"indexed": false, "internalType": "address", "name": "currency", "type": "address",
+			})
+		case LogMatchEvent:
+			for _, input := range inputs {
+				input := input.(map[string]interface{})
+				if input["type"] == "tuple" {
+					input["components"] = append(input["components"].([]interface{}), currency)
+				}
+			}
+		}
+	}
+	content, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// packEvent 按 ABI 编码事件的非 indexed 参数
+func packEvent(t *testing.T, parsedAbi abi.ABI, name string, args ...interface{}) []byte {
+	data, err := parsedAbi.Events[name].Inputs.NonIndexed().Pack(args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+type testAsset struct {
+	TokenId    *big.Int
+	Collection common.Address
+	Amount     *big.Int
+}
+
+type testOrder struct {
+	Side     uint8
+	SaleKind uint8
+	Maker    common.Address
+	Nft      testAsset
+	Price    *big.Int
+	Expiry   uint64
+	Salt     uint64
+	Currency common.Address
+}
+
+func TestHandleEventsWithCurrency(t *testing.T) {
+	v2Abi := currencyAbi(t)
+	s := newTestService(t, config.AbiCfg{
+		Address:   testDexAddress,
+		Version:   "v2",
+		Path:      writeAbi(t, v2Abi),
+		FromBlock: testV2FromBlock,
+	})
+	parsedAbi, err := abi.JSON(strings.NewReader(v2Abi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dex := common.HexToAddress(testDexAddress)
+	weth := common.HexToAddress(testWethAddress)
+	seller := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	buyer := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	collection := common.HexToAddress("0x3333333333333333333333333333333333333333")
+	listKey := common.HexToHash("0x01")
+	bidKey := common.HexToHash("0x02")
+	listing := testOrder{
+		Side:     List,
+		SaleKind: FixForItem,
+		Maker:    seller,
+		Nft:      testAsset{TokenId: big.NewInt(7), Collection: collection, Amount: big.NewInt(1)},
+		Price:    big.NewInt(1e18),
+		Expiry:   4102444800,
+		Salt:     1,
+		Currency: weth,
+	}
+	s.blockTimes[testV2FromBlock] = 1700000000
+
+	makeLog := ethereumTypes.Log{
+		Address: dex,
+		Topics: []common.Hash{
+			parsedAbi.Events[LogMakeEvent].ID,
+			common.BigToHash(big.NewInt(int64(listing.Side))),
+			common.BigToHash(big.NewInt(int64(listing.SaleKind))),
+			common.BytesToHash(seller.Bytes()),
+		},
+		Data:        packEvent(t, parsedAbi, LogMakeEvent, listKey, listing.Nft, listing.Price, listing.Expiry, listing.Salt, weth),
+		BlockNumber: testV2FromBlock,
+		TxHash:      common.HexToHash("0xaa"),
+		Index:       0,
+	}
+	if err := s.handleLog(makeLog); err != nil {
+		t.Fatal(err)
+	}
+
+	var order multi.Order
+	if err := s.db.Table(multi.OrderTableName(testChain)).Where("order_id = ?", listKey.Hex()).First(&order).Error; err != nil {
+		t.Fatal(err)
+	}
+	if order.CurrencyAddress != weth.String() {
+		t.Errorf("order currency = %s, want %s", order.CurrencyAddress, weth.String())
+	}
+	var listActivity multi.Activity
+	if err := s.db.Table(multi.ActivityTableName(testChain)).Where("activity_type = ?", multi.Listing).First(&listActivity).Error; err != nil {
+		t.Fatal(err)
+	}
+	if listActivity.CurrencyAddress != weth.String() {
+		t.Errorf("listing activity currency = %s, want %s", listActivity.CurrencyAddress, weth.String())
+	}
+
+	bid := listing
+	bid.Side, bid.Maker, bid.Salt = Bid, buyer, 2
+	matchLog := ethereumTypes.Log{
+		Address:     dex,
+		Topics:      []common.Hash{parsedAbi.Events[LogMatchEvent].ID, listKey, bidKey},
+		Data:        packEvent(t, parsedAbi, LogMatchEvent, listing, bid, listing.Price),
+		BlockNumber: testV2FromBlock,
+		TxHash:      common.HexToHash("0xbb"),
+		Index:       1,
+	}
+	if err := s.handleLog(matchLog); err != nil {
+		t.Fatal(err)
+	}
+	var sale multi.Activity
+	if err := s.db.Table(multi.ActivityTableName(testChain)).Where("activity_type = ?", multi.Sale).First(&sale).Error; err != nil {
+		t.Fatal(err)
+	}
+	if sale.CurrencyAddress != weth.String() {
+		t.Errorf("sale activity currency = %s, want %s", sale.CurrencyAddress, weth.String())
+	}
+}
+
+func TestHandleMakeEventBeforeCurrencyUpgrade(t *testing.T) {
+	s := newTestService(t, config.AbiCfg{
+		Address:   testDexAddress,
+		Version:   "v2",
+		Path:      writeAbi(t, currencyAbi(t)),
+		FromBlock: testV2FromBlock,
+	})
+	parsedAbi, err := abi.JSON(strings.NewReader(contractAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 升级前的区块按内置 ABI 解码，没有 currency 字段时按 ETH 记录
+	orderKey := common.HexToHash("0x03")
+	seller := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	nft := testAsset{TokenId: big.NewInt(8), Collection: common.HexToAddress("0x3333333333333333333333333333333333333333"), Amount: big.NewInt(1)}
+	s.blockTimes[testV2FromBlock-1] = 1700000000
+	log := ethereumTypes.Log{
+		Address: common.HexToAddress(testDexAddress),
+		Topics: []common.Hash{
+			parsedAbi.Events[LogMakeEvent].ID,
+			common.BigToHash(big.NewInt(List)),
+			common.BigToHash(big.NewInt(FixForItem)),
+			common.BytesToHash(seller.Bytes()),
+		},
+		Data:        packEvent(t, parsedAbi, LogMakeEvent, orderKey, nft, big.NewInt(1e18), uint64(4102444800), uint64(1)),
+		BlockNumber: testV2FromBlock - 1,
+		TxHash:      common.HexToHash("0xcc"),
+	}
+	if err := s.handleLog(log); err != nil {
+		t.Fatal(err)
+	}
+
+	var order multi.Order
+	if err := s.db.Table(multi.OrderTableName(testChain)).Where("order_id = ?", orderKey.Hex()).First(&order).Error; err != nil {
+		t.Fatal(err)
+	}
+	if order.CurrencyAddress != common.HexToAddress(testEthAddress).String() {
+		t.Errorf("order currency = %s, want eth", order.CurrencyAddress)
+	}
+}
+
+func TestUnpackEventWithCurrency(t *testing.T) {
+	v1Abi, err := abi.JSON(strings.NewReader(contractAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2Abi, err := abi.JSON(strings.NewReader(currencyAbi(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	currencies, err := currency.New(config.ContractCfg{EthAddress: testEthAddress, WethAddress: testWethAddress}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{ctx: xzap.ToContext(context.Background(), zap.NewNop()), currencies: currencies}
+
+	weth := common.HexToAddress(testWethAddress)
+	listing := testOrder{
+		Side:     List,
+		SaleKind: FixForItem,
+		Maker:    common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Nft:      testAsset{TokenId: big.NewInt(7), Collection: common.HexToAddress("0x3333333333333333333333333333333333333333"), Amount: big.NewInt(1)},
+		Price:    big.NewInt(1e18),
+		Expiry:   4102444800,
+		Salt:     1,
+		Currency: weth,
+	}
+
+	// 新版合约的 LogMake 多出 currency 字段，解码不受影响并读出计价代币
+	var makeEvent struct {
+		OrderKey [32]byte
+		Nft      struct {
+			TokenId        *big.Int
+			CollectionAddr common.Address `abi:"collection"`
+			Amount         *big.Int
+		}
+		Price  *big.Int
+		Expiry uint64
+		Salt   uint64
+	}
+	data := packEvent(t, v2Abi, LogMakeEvent, common.HexToHash("0x01"), listing.Nft, listing.Price, listing.Expiry, listing.Salt, weth)
+	values, err := unpackEvent(v2Abi, LogMakeEvent, data, &makeEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if makeEvent.Nft.CollectionAddr != listing.Nft.Collection || makeEvent.Price.Cmp(listing.Price) != 0 {
+		t.Errorf("unexpected make event %+v", makeEvent)
+	}
+	if got := s.makeCurrency(values); got != weth.String() {
+		t.Errorf("make currency = %s, want %s", got, weth.String())
+	}
+
+	// 旧版合约没有 currency 字段时按 ETH 处理
+	data = packEvent(t, v1Abi, LogMakeEvent, common.HexToHash("0x01"), listing.Nft, listing.Price, listing.Expiry, listing.Salt)
+	if values, err = unpackEvent(v1Abi, LogMakeEvent, data, &makeEvent); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.makeCurrency(values); got != currencies.Eth() {
+		t.Errorf("make currency = %s, want eth", got)
+	}
+
+	var matchEvent struct {
+		MakeOrder Order
+		TakeOrder Order
+		FillPrice *big.Int
+	}
+	bid := listing
+	bid.Side, bid.Salt = Bid, 2
+	data = packEvent(t, v2Abi, LogMatchEvent, listing, bid, listing.Price)
+	if values, err = unpackEvent(v2Abi, LogMatchEvent, data, &matchEvent); err != nil {
+		t.Fatal(err)
+	}
+	if matchEvent.MakeOrder.Nft.CollectionAddr != listing.Nft.Collection || matchEvent.FillPrice.Cmp(listing.Price) != 0 {
+		t.Errorf("unexpected match event %+v", matchEvent)
+	}
+	if got := s.matchCurrency(values); got != weth.String() {
+		t.Errorf("match currency = %s, want %s", got, weth.String())
+	}
+}
@@ -0,0 +1,152 @@
+package orderbookindexer
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/base"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+
+	"github.com/yaoxc/EasySwapSync/service/comm"
+	"github.com/yaoxc/EasySwapSync/service/leader"
+)
+
+// CursorIndexTypes 订单簿同步循环和地板价循环启动时要求存在的同步记录
+var CursorIndexTypes = []int32{EventIndexType, comm.CollectionFloorChangeIndexType}
+
+// LoadCursor 读取订单簿事件同步的下一个起始区块
+func (s *Service) LoadCursor() (uint64, error) {
+	var indexedStatus base.IndexedStatus
+	if err := s.db.WithContext(s.ctx).Table(base.IndexedStatusTableName()).
+		Where("chain_id = ? and index_type = ?", s.chainId, EventIndexType).
+		First(&indexedStatus).Error; err != nil {
+		return 0, errors.Wrap(err, "failed on get listing index status")
+	}
+	return uint64(indexedStatus.LastIndexedBlock), nil
+}
+
+// commitCursor 提交同步游标。开启 leader 选举时只有 leader 能提交，并带上 fencing token：
+// 已有 token 更大的 leader 提交过时返回 leader.ErrFenced，暂停后恢复的旧 leader 无法覆盖新游标
+func (s *Service) commitCursor(nextBlock uint64) error {
+	token, ok := s.elector.Token()
+	if !ok {
+		return leader.ErrNotLeader
+	}
+	db := s.db.WithContext(s.ctx).Table(base.IndexedStatusTableName()).
+		Where("chain_id = ? and index_type = ?", s.chainId, EventIndexType)
+	updates := map[string]interface{}{"last_indexed_block": nextBlock}
+	if token > 0 {
+		db = db.Where("fence_token <= ?", token)
+		updates["fence_token"] = token
+	}
+	result := db.Updates(updates)
+	if result.Error != nil {
+		return errors.Wrap(result.Error, "failed on update orderbook event sync block number")
+	}
+	if result.RowsAffected > 0 || token == 0 {
+		return nil
+	}
+
+	// 值未变化时 RowsAffected 也为 0，确认是否被更新的 leader 隔离
+	var fences []int64
+	if err := s.db.WithContext(s.ctx).Table(base.IndexedStatusTableName()).
+		Where("chain_id = ? and index_type = ?", s.chainId, EventIndexType).
+		Pluck("fence_token", &fences).Error; err != nil {
+		return errors.Wrap(err, "failed on query orderbook event sync fence token")
+	}
+	if len(fences) == 0 {
+		return nil
+	}
+	return leader.Fence(token, fences[0])
+}
+
+// SetCursor 设置订单簿事件同步的下一个起始区块，没有同步记录时新建
+func (s *Service) SetCursor(nextBlock uint64) error {
+	if err := s.commitCursor(nextBlock); err != nil {
+		return err
+	}
+	var count int64
+	if err := s.db.WithContext(s.ctx).Table(base.IndexedStatusTableName()).
+		Where("chain_id = ? and index_type = ?", s.chainId, EventIndexType).
+		Count(&count).Error; err != nil {
+		return errors.Wrap(err, "failed on query orderbook event sync status")
+	}
+	if count > 0 {
+		return nil
+	}
+	if err := s.db.WithContext(s.ctx).Table(base.IndexedStatusTableName()).Create(&base.IndexedStatus{
+		ChainId:          int(s.chainId),
+		LastIndexedBlock: int64(nextBlock),
+		IndexType:        EventIndexType,
+	}).Error; err != nil {
+		return errors.Wrap(err, "failed on create orderbook event sync status")
+	}
+	return nil
+}
+
+// EnsureCursors 为缺失的同步记录建行，订单簿事件从 startBlock 开始同步，返回新建的 index_type
+func (s *Service) EnsureCursors(startBlock uint64) ([]int32, error) {
+	var existing []int32
+	if err := s.db.WithContext(s.ctx).Table(base.IndexedStatusTableName()).
+		Where("chain_id = ? and index_type in ?", s.chainId, CursorIndexTypes).
+		Pluck("index_type", &existing).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query index status")
+	}
+
+	var created []int32
+	for _, indexType := range CursorIndexTypes {
+		if slices.Contains(existing, indexType) {
+			continue
+		}
+		status := &base.IndexedStatus{
+			ChainId:   int(s.chainId),
+			IndexType: indexType,
+		}
+		if indexType == EventIndexType {
+			status.LastIndexedBlock = int64(startBlock)
+		}
+		// (chain_id, index_type) 唯一，其他实例已建行时跳过
+		result := s.db.WithContext(s.ctx).Table(base.IndexedStatusTableName()).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(status)
+		if result.Error != nil {
+			return created, errors.Wrapf(result.Error, "failed on create index status, index type %d", indexType)
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		xzap.WithContext(s.ctx).Info("index status created",
+			zap.Int32("index_type", indexType), zap.Int64("last_indexed_block", status.LastIndexedBlock))
+		created = append(created, indexType)
+	}
+	return created, nil
+}
+
+// PrepareCursors 启动同步循环前检查同步记录：配置了订单簿合约的部署区块时，缺失的记录从部署区块开始建行；
+// 订单簿事件游标早于部署区块或超过链头时返回错误，避免漏同步或跳过区块
+func (s *Service) PrepareCursors() error {
+	startBlock, deployed := s.cfg.ChainCfg.StartBlock(s.cfg.ContractCfg.DexAddress)
+	if deployed {
+		if _, err := s.EnsureCursors(startBlock); err != nil {
+			return err
+		}
+	}
+
+	cursor, err := s.LoadCursor()
+	if err != nil {
+		return errors.Wrap(err, "run `sync init` or configure chain_cfg.deployments")
+	}
+	if deployed && cursor < startBlock {
+		return errors.Wrapf(ErrCursorBeforeDeployment, "next block %d, deployment block %d", cursor, startBlock)
+	}
+	head, err := s.chainClient.BlockNumber()
+	if err != nil {
+		return errors.Wrap(err, "failed on get current block number")
+	}
+	if cursor > head+1 {
+		return errors.Wrapf(ErrCursorAheadOfHead, "next block %d, head %d", cursor, head)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package orderbookindexer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/leader"
+)
+
+func TestCommitCursorRequiresLeader(t *testing.T) {
+	s := &Service{
+		ctx:     context.Background(),
+		elector: leader.New(context.Background(), nil, config.LeaderCfg{}, "sepolia", "0x1111111111111111111111111111111111111111"),
+	}
+	// 还没当选时不访问数据库，直接拒绝提交
+	if err := s.commitCursor(100); !errors.Is(err, leader.ErrNotLeader) {
+		t.Fatalf("expected ErrNotLeader, got %v", err)
+	}
+}
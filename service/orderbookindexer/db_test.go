@@ -0,0 +1,107 @@
+package orderbookindexer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/ordermanager"
+	"github.com/yaoxc/EasySwapBase/stores/gdb"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/base"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/yaoxc/EasySwapBase/stores/xkv"
+	"github.com/zeromicro/go-zero/core/stores/kv"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/config"
+)
+
+const (
+	testChain       = "sepolia"
+	testDexAddress  = "0x7d29d1860bD4d3A74bBD9a03C9B043d375311dCb"
+	testEthAddress  = "0x0000000000000000000000000000000000000000"
+	testWethAddress = "0xfFf9976782d46CC05630D1f6eBAb18b2324d6B14"
+)
+
+// testTables 处理器用到的表，只保留测试需要的列和唯一键，金额按文本保存避免精度损失
+var testTables = map[string]string{
+	multi.OrderTableName(testChain): `id integer primary key autoincrement, marketplace_id integer, collection_address text, token_id text,
+order_id text unique, order_status integer default 0, event_time integer, expire_time integer, currency_address text, price text,
+maker text, taker text, quantity_remaining integer, size integer, order_type integer, salt integer,
+create_time integer, update_time integer, tx_hash text, block_hash text, log_index integer`,
+	multi.ItemTableName(testChain): `id integer primary key autoincrement, chain_id integer, collection_address text, token_id text,
+name text, owner text, creator text, supply integer, list_price text, list_time integer, sale_price text, views integer,
+create_time integer, update_time integer, unique (collection_address, token_id)`,
+	multi.ActivityTableName(testChain): `id integer primary key autoincrement, activity_type integer, maker text, taker text,
+marketplace_id integer, collection_address text, token_id text, currency_address text, price text, sell_price text, buy_price text,
+block_number integer, tx_hash text, event_time integer, create_time integer, update_time integer, block_hash text, log_index integer,
+unique (tx_hash, log_index, collection_address, token_id, activity_type)`,
+	model.OrderFillTableName(testChain): `id integer primary key autoincrement, order_id text, tx_hash text, log_index integer,
+collection_address text, token_id text, quantity integer, price text, counterparty text, block_number integer, event_time integer,
+create_time integer, update_time integer, unique (order_id, tx_hash, log_index)`,
+	model.ContractUpgradeTableName(testChain): `id integer primary key autoincrement, contract_address text, implementation text,
+block_number integer, tx_hash text, log_index integer, abi_version text, compatible integer, create_time integer, update_time integer,
+unique (tx_hash, log_index)`,
+	base.IndexedStatusTableName(): `id integer primary key autoincrement, chain_id integer, last_indexed_block integer,
+last_indexed_time integer, fence_token integer default 0, index_type integer, create_time integer, update_time integer,
+unique (chain_id, index_type)`,
+}
+
+// newTestDB 创建内存 SQLite 数据库并建好 testTables
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库按连接隔离，只用一个连接
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	for table, columns := range testTables {
+		if err := db.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", table, columns)).Error; err != nil {
+			t.Fatalf("failed on create %s: %v", table, err)
+		}
+	}
+	return db
+}
+
+// newTestKv 创建使用 miniredis 的 KV 存储，订单管理队列和价格更新队列写入其中
+func newTestKv(t *testing.T) *xkv.Store {
+	server := miniredis.RunT(t)
+	return xkv.NewStore(kv.KvConf{{
+		RedisConf: redis.RedisConf{Host: server.Addr(), Type: redis.NodeType},
+		Weight:    100,
+	}})
+}
+
+// newTestService 创建使用内存数据库和 miniredis 的订单簿索引器，不连接 RPC，处理的日志需预先写入 blockTimes
+func newTestService(t *testing.T, abis ...config.AbiCfg) *Service {
+	cfg := &config.Config{
+		ChainCfg: config.ChainCfg{Name: testChain, ID: 11155111},
+		ContractCfg: config.ContractCfg{
+			EthAddress:  testEthAddress,
+			WethAddress: testWethAddress,
+			DexAddress:  testDexAddress,
+			Abis:        abis,
+		},
+		ProjectCfg: config.ProjectCfg{Name: gdb.OrderBookDexProject},
+	}
+	ctx := xzap.ToContext(context.Background(), zap.NewNop())
+	db := newTestDB(t)
+	kvStore := newTestKv(t)
+	orderManager := ordermanager.New(ctx, db, kvStore, testChain, cfg.ProjectCfg.Name)
+	s, err := New(ctx, cfg, db, kvStore, nil, cfg.ChainCfg.ID, testChain, orderManager, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
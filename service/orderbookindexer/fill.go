@@ -0,0 +1,27 @@
+package orderbookindexer
+
+import (
+	"math/big"
+	"strings"
+
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/shopspring/decimal"
+
+	"github.com/yaoxc/EasySwapSync/model"
+)
+
+// newOrderFill 构造订单在一次 LogMatch 中的成交记录，每次撮合成交一个单位
+func newOrderFill(log ethereumTypes.Log, orderId string, collection string, tokenId string, fillPrice *big.Int, counterparty string, blockTime uint64) model.OrderFill {
+	return model.OrderFill{
+		OrderId:           orderId,
+		TxHash:            log.TxHash.String(),
+		LogIndex:          int64(log.Index),
+		CollectionAddress: strings.ToLower(collection),
+		TokenId:           tokenId,
+		Quantity:          1,
+		Price:             decimal.NewFromBigInt(fillPrice, 0),
+		Counterparty:      counterparty,
+		BlockNumber:       int64(log.BlockNumber),
+		EventTime:         int64(blockTime),
+	}
+}
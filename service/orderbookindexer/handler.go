@@ -0,0 +1,174 @@
+package orderbookindexer
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"go.uber.org/zap"
+
+	"github.com/yaoxc/EasySwapSync/service/metrics"
+)
+
+// 订单簿合约事件名，topic 由 ABI 计算得到
+const (
+	LogMakeEvent   = "LogMake"
+	LogCancelEvent = "LogCancel"
+	LogMatchEvent  = "LogMatch"
+)
+
+// EventHandler 处理某个合约地址上某一类事件的日志
+type EventHandler interface {
+	Name() string
+	Handle(log ethereumTypes.Log) error
+}
+
+// eventHandlerFunc 把普通函数包装成 EventHandler
+type eventHandlerFunc struct {
+	name   string
+	handle func(log ethereumTypes.Log) error
+}
+
+func (h eventHandlerFunc) Name() string {
+	return h.name
+}
+
+func (h eventHandlerFunc) Handle(log ethereumTypes.Log) error {
+	return h.handle(log)
+}
+
+// NewEventHandler 用名字和处理函数构造 EventHandler
+func NewEventHandler(name string, handle func(log ethereumTypes.Log) error) EventHandler {
+	return eventHandlerFunc{name: name, handle: handle}
+}
+
+type handlerKey struct {
+	address common.Address
+	topic   common.Hash
+}
+
+// HandlerRegistry 按 (合约地址, topic0) 登记事件处理器
+type HandlerRegistry struct {
+	handlers map[handlerKey]EventHandler
+	lock     *sync.RWMutex
+}
+
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{
+		handlers: make(map[handlerKey]EventHandler),
+		lock:     &sync.RWMutex{},
+	}
+}
+
+// Register 登记处理器，同一地址同一 topic 只允许一个处理器
+func (r *HandlerRegistry) Register(address common.Address, topic common.Hash, handler EventHandler) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	key := handlerKey{address: address, topic: topic}
+	if exist, ok := r.handlers[key]; ok {
+		return errors.Errorf("handler %s already registered for %s topic %s", exist.Name(), address.Hex(), topic.Hex())
+	}
+	r.handlers[key] = handler
+	return nil
+}
+
+// Lookup 查找日志对应的处理器，匿名事件或未登记的 topic 返回 false
+func (r *HandlerRegistry) Lookup(log ethereumTypes.Log) (EventHandler, bool) {
+	if len(log.Topics) == 0 {
+		return nil, false
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	handler, ok := r.handlers[handlerKey{address: log.Address, topic: log.Topics[0]}]
+	return handler, ok
+}
+
+// Addresses 返回登记过处理器的合约地址，用于构造日志过滤条件
+func (r *HandlerRegistry) Addresses() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	seen := make(map[common.Address]bool)
+	addresses := make([]string, 0)
+	for key := range r.handlers {
+		if seen[key.address] {
+			continue
+		}
+		seen[key.address] = true
+		addresses = append(addresses, key.address.Hex())
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// EventTopic 从 ABI 计算事件的 topic0
+func EventTopic(contractAbi abi.ABI, eventName string) (common.Hash, error) {
+	event, ok := contractAbi.Events[eventName]
+	if !ok {
+		return common.Hash{}, errors.Errorf("event %s not found in abi", eventName)
+	}
+	return event.ID, nil
+}
+
+// RegisterHandler 为指定合约上的事件登记额外的处理器，topic 由传入的 ABI 计算，
+// 需在 Start 之前调用，通过 service.New 创建时使用 service.WithHandler
+func (s *Service) RegisterHandler(address common.Address, contractAbi abi.ABI, eventName string, handler EventHandler) error {
+	topic, err := EventTopic(contractAbi, eventName)
+	if err != nil {
+		return err
+	}
+	return s.handlers.Register(address, topic, handler)
+}
+
+// registerOrderBookHandlers 登记订单簿合约的 Make/Cancel/Match 处理器，
+// 合约各 ABI 版本的事件签名不同时分别登记对应的 topic
+func (s *Service) registerOrderBookHandlers(address common.Address) error {
+	handlers := map[string]func(log ethereumTypes.Log) error{
+		LogMakeEvent:   s.handleMakeEvent,
+		LogCancelEvent: s.handleCancelEvent,
+		LogMatchEvent:  s.handleMatchEvent,
+	}
+	registered := make(map[common.Hash]bool)
+	for _, version := range s.abis.Versions(address) {
+		for eventName, handle := range handlers {
+			topic, err := EventTopic(version.Abi, eventName)
+			if err != nil {
+				return errors.Wrapf(err, "abi version %s", version.Version)
+			}
+			if registered[topic] {
+				continue
+			}
+			registered[topic] = true
+
+			if err := s.handlers.Register(address, topic, NewEventHandler(eventName, handle)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// handleLog 根据 (合约地址, topic) 分发日志到登记的处理器，
+// 处理器的错误只记录日志，只有需要暂停同步（ErrIndexingPaused）或稍后重试（ErrRetryLater）时才返回
+func (s *Service) handleLog(ethLog ethereumTypes.Log) error {
+	handler, ok := s.handlers.Lookup(ethLog)
+	if !ok {
+		return nil
+	}
+	err := handler.Handle(ethLog)
+	metrics.LogProcessed(s.chain, handler.Name(), err)
+	if err != nil {
+		if errors.Is(err, ErrIndexingPaused) || errors.Is(err, ErrRetryLater) {
+			return err
+		}
+		xzap.WithContext(s.ctx).Error("failed on handle log",
+			zap.String("handler", handler.Name()),
+			zap.String("tx_hash", ethLog.TxHash.String()),
+			zap.Uint("log_index", ethLog.Index),
+			zap.Error(err))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package orderbookindexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestEventTopicFromAbi(t *testing.T) {
+	parsedAbi, err := abi.JSON(strings.NewReader(contractAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		LogMakeEvent:   "0xfc37f2ff950f95913eb7182357ba3c14df60ef354bc7d6ab1ba2815f249fffe6",
+		LogCancelEvent: "0x0ac8bb53fac566d7afc05d8b4df11d7690a7b27bdc40b54e4060f9b21fb849bd",
+		LogMatchEvent:  "0xf629aecab94607bc43ce4aebd564bf6e61c7327226a797b002de724b9944b20e",
+	}
+	for eventName, want := range cases {
+		topic, err := EventTopic(parsedAbi, eventName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if topic != common.HexToHash(want) {
+			t.Fatalf("%s topic = %s, want %s", eventName, topic.Hex(), want)
+		}
+	}
+	if _, err := EventTopic(parsedAbi, "LogUnknown"); err == nil {
+		t.Fatal("expected error for unknown event")
+	}
+}
+
+func TestHandlerRegistry(t *testing.T) {
+	dex := common.HexToAddress("0x5560e1c2E0260c2274e400d80C30CDC4B92dC8ac")
+	other := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	topic := common.HexToHash("0x01")
+
+	var handled []string
+	registry := NewHandlerRegistry()
+	if err := registry.Register(dex, topic, NewEventHandler("dex", func(log ethereumTypes.Log) error {
+		handled = append(handled, "dex")
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(dex, topic, NewEventHandler("dup", nil)); err == nil {
+		t.Fatal("expected duplicate registration to fail")
+	}
+
+	if _, ok := registry.Lookup(ethereumTypes.Log{Address: other, Topics: []common.Hash{topic}}); ok {
+		t.Fatal("handler should be keyed by contract address")
+	}
+	if _, ok := registry.Lookup(ethereumTypes.Log{Address: dex}); ok {
+		t.Fatal("anonymous log should not match")
+	}
+	handler, ok := registry.Lookup(ethereumTypes.Log{Address: dex, Topics: []common.Hash{topic}})
+	if !ok || handler.Name() != "dex" {
+		t.Fatalf("unexpected handler %v", handler)
+	}
+	if err := handler.Handle(ethereumTypes.Log{}); err != nil || len(handled) != 1 {
+		t.Fatalf("handler not invoked, err %v", err)
+	}
+
+	if addresses := registry.Addresses(); len(addresses) != 1 || addresses[0] != dex.Hex() {
+		t.Fatalf("unexpected addresses %v", addresses)
+	}
+}
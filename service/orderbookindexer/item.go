@@ -0,0 +1,31 @@
+package orderbookindexer
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ensureItem 确保 ob_item 中存在该 token 的记录，返回是否为首次创建；
+// 首次创建的 item 由调用方在事务提交后提交元数据抓取
+func (s *Service) ensureItem(tx *gorm.DB, collection string, tokenId string, owner string) (bool, error) {
+	item := multi.Item{
+		ChainId:           int(s.chainId),
+		CollectionAddress: strings.ToLower(collection),
+		TokenId:           tokenId,
+		Owner:             strings.ToLower(owner),
+		Creator:           ZeroAddress,
+		Supply:            1,
+	}
+	result := tx.Table(multi.ItemTableName(s.chain)).Clauses(clause.OnConflict{
+		DoNothing: true,
+	}).Create(&item)
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "failed on create item")
+	}
+
+	return result.RowsAffected > 0, nil
+}
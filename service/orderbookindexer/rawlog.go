@@ -0,0 +1,180 @@
+package orderbookindexer
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/comm"
+)
+
+// ErrArchiveIncomplete 原始日志归档没有从合约部署区块开始，全量重放会丢失归档之前的历史
+var ErrArchiveIncomplete = errors.New("raw log archive does not start at the contract deployment block")
+
+// SetReplayMode 设置为重放模式，需在 Replay 之前调用：处理逻辑不变，
+// 但不向订单管理器队列和价格更新队列推送事件，避免历史事件进入线上 Redis 队列
+func (s *Service) SetReplayMode(replaying bool) {
+	s.replaying = replaying
+}
+
+// blockTime 获取区块时间，优先使用本批日志归档时已查询的时间，重放时不会访问 RPC
+func (s *Service) blockTime(blockNumber uint64) (uint64, error) {
+	if blockTime, ok := s.blockTimes[blockNumber]; ok {
+		return blockTime, nil
+	}
+	if s.chainClient == nil {
+		return 0, errors.Errorf("block time of %d not found", blockNumber)
+	}
+	blockTime, err := s.chainClient.BlockTimeByNumber(s.ctx, big.NewInt(int64(blockNumber)))
+	if err != nil {
+		return 0, err
+	}
+	s.blockTimes[blockNumber] = blockTime
+	return blockTime, nil
+}
+
+// archiveLogs 把本批拉取到的原始日志连同区块时间写入 ob_raw_log，重复写入时忽略
+func (s *Service) archiveLogs(logs []ethereumTypes.Log) error {
+	s.blockTimes = make(map[uint64]uint64)
+	rawLogs := make([]model.RawLog, 0, len(logs))
+	for _, log := range logs {
+		blockTime, err := s.blockTime(log.BlockNumber)
+		if err != nil {
+			return errors.Wrap(err, "failed on get block time")
+		}
+		rawLogs = append(rawLogs, toRawLog(log, blockTime))
+	}
+	if len(rawLogs) == 0 {
+		return nil
+	}
+
+	if err := s.db.WithContext(s.ctx).Table(model.RawLogTableName(s.chain)).Clauses(clause.OnConflict{
+		DoNothing: true,
+	}).CreateInBatches(rawLogs, comm.DBBatchSizeLimit).Error; err != nil {
+		return errors.Wrap(err, "failed on archive raw logs")
+	}
+	return nil
+}
+
+// Replay 按区块顺序从 ob_raw_log 重放 [fromBlock, toBlock] 内的日志，toBlock 为 0 表示到归档末尾，
+// 返回重放的日志数量。处理逻辑与同步循环相同，区块时间取自归档，不访问 RPC
+func (s *Service) Replay(fromBlock uint64, toBlock uint64) (int, error) {
+	var total int
+	var lastBlock, lastIndex int64 = int64(fromBlock), -1
+	for {
+		var rawLogs []model.RawLog
+		db := s.db.WithContext(s.ctx).Table(model.RawLogTableName(s.chain)).
+			Where("block_number > ? or (block_number = ? and log_index > ?)", lastBlock, lastBlock, lastIndex)
+		if toBlock > 0 {
+			db = db.Where("block_number <= ?", toBlock)
+		}
+		if err := db.Order("block_number, log_index").
+			Limit(comm.DBBatchSizeLimit).
+			Find(&rawLogs).Error; err != nil {
+			return total, errors.Wrap(err, "failed on query raw logs")
+		}
+
+		s.blockTimes = make(map[uint64]uint64)
+		for _, rawLog := range rawLogs {
+			log, err := fromRawLog(rawLog)
+			if err != nil {
+				return total, errors.Wrapf(err, "failed on decode raw log %d", rawLog.Id)
+			}
+			s.blockTimes[log.BlockNumber] = uint64(rawLog.BlockTime)
+			if err := s.handleLog(log); err != nil {
+				return total, err
+			}
+			lastBlock, lastIndex = rawLog.BlockNumber, rawLog.LogIndex
+		}
+		total += len(rawLogs)
+
+		if len(rawLogs) < comm.DBBatchSizeLimit {
+			return total, nil
+		}
+		xzap.WithContext(s.ctx).Info("replaying raw logs ...",
+			zap.Int64("block_number", lastBlock), zap.Int("replayed", total))
+	}
+}
+
+// WipeDerivedTables 清空由订单簿事件派生出的订单、活动和成交记录，用于全量重放前。
+// 归档必须从 chain_cfg.deployments 中 DexAddress 的部署区块开始（代理合约部署时的日志也会归档），
+// 否则返回 ErrArchiveIncomplete，不做任何删除
+func (s *Service) WipeDerivedTables() error {
+	startBlock, ok := s.cfg.ChainCfg.StartBlock(s.cfg.ContractCfg.DexAddress)
+	if !ok {
+		return errors.Wrap(ErrArchiveIncomplete, "chain_cfg.deployments start_block of the dex contract is required to verify the archive")
+	}
+	var firstBlock *int64
+	if err := s.db.WithContext(s.ctx).Table(model.RawLogTableName(s.chain)).
+		Select("min(block_number)").
+		Scan(&firstBlock).Error; err != nil {
+		return errors.Wrap(err, "failed on query first archived block")
+	}
+	if firstBlock == nil {
+		return errors.Wrap(ErrArchiveIncomplete, "archive is empty")
+	}
+	if uint64(*firstBlock) > startBlock {
+		return errors.Wrapf(ErrArchiveIncomplete, "archive starts at block %d, after deployment block %d", *firstBlock, startBlock)
+	}
+
+	for _, table := range []string{
+		multi.OrderTableName(s.chain),
+		multi.ActivityTableName(s.chain),
+		model.OrderFillTableName(s.chain),
+	} {
+		if err := s.db.WithContext(s.ctx).Exec("DELETE FROM " + table).Error; err != nil {
+			return errors.Wrapf(err, "failed on wipe %s", table)
+		}
+	}
+	return nil
+}
+
+func toRawLog(log ethereumTypes.Log, blockTime uint64) model.RawLog {
+	topics := make([]string, 0, len(log.Topics))
+	for _, topic := range log.Topics {
+		topics = append(topics, topic.String())
+	}
+	return model.RawLog{
+		Address:     log.Address.String(),
+		Topics:      strings.Join(topics, ","),
+		Data:        hex.EncodeToString(log.Data),
+		BlockNumber: int64(log.BlockNumber),
+		BlockHash:   log.BlockHash.String(),
+		BlockTime:   int64(blockTime),
+		TxHash:      log.TxHash.String(),
+		TxIndex:     int64(log.TxIndex),
+		LogIndex:    int64(log.Index),
+	}
+}
+
+func fromRawLog(rawLog model.RawLog) (ethereumTypes.Log, error) {
+	data, err := hex.DecodeString(rawLog.Data)
+	if err != nil {
+		return ethereumTypes.Log{}, err
+	}
+	var topics []common.Hash
+	if rawLog.Topics != "" {
+		for _, topic := range strings.Split(rawLog.Topics, ",") {
+			topics = append(topics, common.HexToHash(topic))
+		}
+	}
+	return ethereumTypes.Log{
+		Address:     common.HexToAddress(rawLog.Address),
+		Topics:      topics,
+		Data:        data,
+		BlockNumber: uint64(rawLog.BlockNumber),
+		BlockHash:   common.HexToHash(rawLog.BlockHash),
+		TxHash:      common.HexToHash(rawLog.TxHash),
+		TxIndex:     uint(rawLog.TxIndex),
+		Index:       uint(rawLog.LogIndex),
+	}, nil
+}
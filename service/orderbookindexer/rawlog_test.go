@@ -0,0 +1,53 @@
+package orderbookindexer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+)
+
+func TestRawLogRoundTrip(t *testing.T) {
+	log := ethereumTypes.Log{
+		Address: common.HexToAddress("0x5560e1c2E0260c2274e400d80C30CDC4B92dC8ac"),
+		Topics: []common.Hash{
+			common.HexToHash("0x0ac8bb53fac566d7afc05d8b4df11d7690a7b27bdc40b54e4060f9b21fb849bd"),
+			common.HexToHash("0x8f2c33b5a7d6e8f1c4b3a2918273645546372819aabbccddeeff001122334455"),
+			common.HexToHash("0x0000000000000000000000001111111111111111111111111111111111111111"),
+		},
+		Data:        []byte{0x01, 0x02, 0xff},
+		BlockNumber: 5123456,
+		BlockHash:   common.HexToHash("0xaa"),
+		TxHash:      common.HexToHash("0xbb"),
+		TxIndex:     3,
+		Index:       17,
+	}
+
+	rawLog := toRawLog(log, 1700000000)
+	if rawLog.BlockTime != 1700000000 || rawLog.LogIndex != 17 {
+		t.Fatalf("unexpected raw log %+v", rawLog)
+	}
+	got, err := fromRawLog(rawLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, log) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", got, log)
+	}
+}
+
+func TestWipeRequiresDeployment(t *testing.T) {
+	s := &Service{
+		ctx: context.Background(),
+		cfg: &config.Config{ContractCfg: config.ContractCfg{DexAddress: "0x1111111111111111111111111111111111111111"}},
+	}
+	// 未配置部署区块时无法确认归档是否完整，不做任何删除
+	if err := s.WipeDerivedTables(); !errors.Is(err, ErrArchiveIncomplete) {
+		t.Fatalf("expected ErrArchiveIncomplete, got %v", err)
+	}
+}
@@ -6,9 +6,10 @@ import (
 	"fmt"
 	"math/big"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 
 	// 给这个包起个短名字（只在当前文件有效）,名字叫做 ethereumTypes，后面代码里就可以用：
@@ -29,15 +30,22 @@ import (
 	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
 	"github.com/yaoxc/EasySwapBase/stores/xkv"
 
-	// go-zero 框架提供的 “并发工具箱”，只干一件事：
-	// 让开发者用一两行代码就能安全地启动、管理、恢复 goroutine，同时自带 panic 捕获、日志、限流、池化等兜底能力。
-	"github.com/zeromicro/go-zero/core/threading"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/collectionimporter"
 	"github.com/yaoxc/EasySwapSync/service/comm"
 	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+	"github.com/yaoxc/EasySwapSync/service/health"
+	"github.com/yaoxc/EasySwapSync/service/itemmetadata"
+	"github.com/yaoxc/EasySwapSync/service/itemprice"
+	"github.com/yaoxc/EasySwapSync/service/leader"
+	"github.com/yaoxc/EasySwapSync/service/metrics"
+	"github.com/yaoxc/EasySwapSync/service/supervisor"
+	"github.com/yaoxc/EasySwapSync/service/webhook"
 )
 
 // 在 Go 里，首字母小写 = 包内私有，首字母大写 = 包外可见
@@ -47,10 +55,6 @@ const (
 	EventIndexType   = 6
 	SleepInterval    = 50 // in seconds
 	SyncBlockPeriod  = 10
-	LogMakeTopic     = "0xfc37f2ff950f95913eb7182357ba3c14df60ef354bc7d6ab1ba2815f249fffe6"
-	LogCancelTopic   = "0x0ac8bb53fac566d7afc05d8b4df11d7690a7b27bdc40b54e4060f9b21fb849bd"
-	LogMatchTopic    = "0xf629aecab94607bc43ce4aebd564bf6e61c7327226a797b002de724b9944b20e"
-	contractAbi      = `[{"inputs":[],"name":"CannotFindNextEmptyKey","type":"error"},{"inputs":[],"name":"CannotFindPrevEmptyKey","type":"error"},{"inputs":[{"internalType":"OrderKey","name":"orderKey","type":"bytes32"}],"name":"CannotInsertDuplicateOrder","type":"error"},{"inputs":[],"name":"CannotInsertEmptyKey","type":"error"},{"inputs":[],"name":"CannotInsertExistingKey","type":"error"},{"inputs":[],"name":"CannotRemoveEmptyKey","type":"error"},{"inputs":[],"name":"CannotRemoveMissingKey","type":"error"},{"inputs":[],"name":"EnforcedPause","type":"error"},{"inputs":[],"name":"ExpectedPause","type":"error"},{"inputs":[],"name":"InvalidInitialization","type":"error"},{"inputs":[],"name":"NotInitializing","type":"error"},{"inputs":[{"internalType":"address","name":"owner","type":"address"}],"name":"OwnableInvalidOwner","type":"error"},{"inputs":[{"internalType":"address","name":"account","type":"address"}],"name":"OwnableUnauthorizedAccount","type":"error"},{"inputs":[],"name":"ReentrancyGuardReentrantCall","type":"error"},{"anonymous":false,"inputs":[{"indexed":false,"internalType":"uint256","name":"offset","type":"uint256"},{"indexed":false,"internalType":"bytes","name":"msg","type":"bytes"}],"name":"BatchMatchInnerError","type":"event"},{"anonymous":false,"inputs":[],"name":"EIP712DomainChanged","type":"event"},{"anonymous":false,"inputs":[{"indexed":false,"internalType":"uint64","name":"version","type":"uint64"}],"name":"Initialized","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"OrderKey","name":"orderKey","type":"bytes32"},{"indexed":true,"internalType":"address","name":"maker","type":"address"}],"name":"LogCancel","type":"event"},{"anonymous":false,"inputs":[{"indexed":false,"internalType":"OrderKey","name":"orderKey","type":"bytes32"},{"indexed":true,"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"indexed":true,"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"indexed":true,"internalType":"address","name":"maker","type":"address"},{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint96","name":"amount","type":"uint96"}],"indexed":false,"internalType":"structLibOrder.Asset","name":"nft","type":"tuple"},{"indexed":false,"internalType":"Price","name":"price","type":"uint128"},{"indexed":false,"internalType":"uint64","name":"expiry","type":"uint64"},{"indexed":false,"internalType":"uint64","name":"salt","type":"uint64"}],"name":"LogMake","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"OrderKey","name":"makeOrderKey","type":"bytes32"},{"indexed":true,"internalType":"OrderKey","name":"takeOrderKey","type":"bytes32"},{"components":[{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"internalType":"address","name":"maker","type":"address"},{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint96","name":"amount","type":"uint96"}],"internalType":"structLibOrder.Asset","name":"nft","type":"tuple"},{"internalType":"Price","name":"price","type":"uint128"},{"internalType":"uint64","name":"expiry","type":"uint64"},{"internalType":"uint64","name":"salt","type":"uint64"}],"indexed":false,"internalType":"structLibOrder.Order","name":"makeOrder","type":"tuple"},{"components":[{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"internalType":"address","name":"maker","type":"address"},{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint96","name":"amount","type":"uint96"}],"internalType":"structLibOrder.Asset","name":"nft","type":"tuple"},{"internalType":"Price","name":"price","type":"uint128"},{"internalType":"uint64","name":"expiry","type":"uint64"},{"internalType":"uint64","name":"salt","type":"uint64"}],"indexed":false,"internalType":"structLibOrder.Order","name":"takeOrder","type":"tuple"},{"indexed":false,"internalType":"uint128","name":"fillPrice","type":"uint128"}],"name":"LogMatch","type":"event"},{"anonymous":false,"inputs":[{"indexed":false,"internalType":"OrderKey","name":"orderKey","type":"bytes32"},{"indexed":false,"internalType":"uint64","name":"salt","type":"uint64"}],"name":"LogSkipOrder","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"uint128","name":"newProtocolShare","type":"uint128"}],"name":"LogUpdatedProtocolShare","type":"event"},{"anonymous":false,"inputs":[{"indexed":false,"internalType":"address","name":"recipient","type":"address"},{"indexed":false,"internalType":"uint256","name":"amount","type":"uint256"}],"name":"LogWithdrawETH","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"previousOwner","type":"address"},{"indexed":true,"internalType":"address","name":"newOwner","type":"address"}],"name":"OwnershipTransferred","type":"event"},{"anonymous":false,"inputs":[{"indexed":false,"internalType":"address","name":"account","type":"address"}],"name":"Paused","type":"event"},{"anonymous":false,"inputs":[{"indexed":false,"internalType":"address","name":"account","type":"address"}],"name":"Unpaused","type":"event"},{"inputs":[{"internalType":"OrderKey[]","name":"orderKeys","type":"bytes32[]"}],"name":"cancelOrders","outputs":[{"internalType":"bool[]","name":"successes","type":"bool[]"}],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"components":[{"internalType":"OrderKey","name":"oldOrderKey","type":"bytes32"},{"components":[{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"internalType":"address","name":"maker","type":"address"},{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint96","name":"amount","type":"uint96"}],"internalType":"structLibOrder.Asset","name":"nft","type":"tuple"},{"internalType":"Price","name":"price","type":"uint128"},{"internalType":"uint64","name":"expiry","type":"uint64"},{"internalType":"uint64","name":"salt","type":"uint64"}],"internalType":"structLibOrder.Order","name":"newOrder","type":"tuple"}],"internalType":"structLibOrder.EditDetail[]","name":"editDetails","type":"tuple[]"}],"name":"editOrders","outputs":[{"internalType":"OrderKey[]","name":"newOrderKeys","type":"bytes32[]"}],"stateMutability":"payable","type":"function"},{"inputs":[],"name":"eip712Domain","outputs":[{"internalType":"bytes1","name":"fields","type":"bytes1"},{"internalType":"string","name":"name","type":"string"},{"internalType":"string","name":"version","type":"string"},{"internalType":"uint256","name":"chainId","type":"uint256"},{"internalType":"address","name":"verifyingContract","type":"address"},{"internalType":"bytes32","name":"salt","type":"bytes32"},{"internalType":"uint256[]","name":"extensions","type":"uint256[]"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"OrderKey","name":"","type":"bytes32"}],"name":"filledAmount","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"}],"name":"getBestOrder","outputs":[{"components":[{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"internalType":"address","name":"maker","type":"address"},{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint96","name":"amount","type":"uint96"}],"internalType":"structLibOrder.Asset","name":"nft","type":"tuple"},{"internalType":"Price","name":"price","type":"uint128"},{"internalType":"uint64","name":"expiry","type":"uint64"},{"internalType":"uint64","name":"salt","type":"uint64"}],"internalType":"structLibOrder.Order","name":"orderResult","type":"tuple"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"collection","type":"address"},{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"}],"name":"getBestPrice","outputs":[{"internalType":"Price","name":"price","type":"uint128"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"collection","type":"address"},{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"Price","name":"price","type":"uint128"}],"name":"getNextBestPrice","outputs":[{"internalType":"Price","name":"nextBestPrice","type":"uint128"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"internalType":"uint256","name":"count","type":"uint256"},{"internalType":"Price","name":"price","type":"uint128"},{"internalType":"OrderKey","name":"firstOrderKey","type":"bytes32"}],"name":"getOrders","outputs":[{"components":[{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"internalType":"address","name":"maker","type":"address"},{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint96","name":"amount","type":"uint96"}],"internalType":"structLibOrder.Asset","name":"nft","type":"tuple"},{"internalType":"Price","name":"price","type":"uint128"},{"internalType":"uint64","name":"expiry","type":"uint64"},{"internalType":"uint64","name":"salt","type":"uint64"}],"internalType":"structLibOrder.Order[]","name":"resultOrders","type":"tuple[]"},{"internalType":"OrderKey","name":"nextOrderKey","type":"bytes32"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"uint128","name":"newProtocolShare","type":"uint128"},{"internalType":"address","name":"newVault","type":"address"},{"internalType":"string","name":"EIP712Name","type":"string"},{"internalType":"string","name":"EIP712Version","type":"string"}],"name":"initialize","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"components":[{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"internalType":"address","name":"maker","type":"address"},{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint96","name":"amount","type":"uint96"}],"internalType":"structLibOrder.Asset","name":"nft","type":"tuple"},{"internalType":"Price","name":"price","type":"uint128"},{"internalType":"uint64","name":"expiry","type":"uint64"},{"internalType":"uint64","name":"salt","type":"uint64"}],"internalType":"structLibOrder.Order[]","name":"newOrders","type":"tuple[]"}],"name":"makeOrders","outputs":[{"internalType":"OrderKey[]","name":"newOrderKeys","type":"bytes32[]"}],"stateMutability":"payable","type":"function"},{"inputs":[{"components":[{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"internalType":"address","name":"maker","type":"address"},{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint96","name":"amount","type":"uint96"}],"internalType":"structLibOrder.Asset","name":"nft","type":"tuple"},{"internalType":"Price","name":"price","type":"uint128"},{"internalType":"uint64","name":"expiry","type":"uint64"},{"internalType":"uint64","name":"salt","type":"uint64"}],"internalType":"structLibOrder.Order","name":"sellOrder","type":"tuple"},{"components":[{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"internalType":"address","name":"maker","type":"address"},{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint96","name":"amount","type":"uint96"}],"internalType":"structLibOrder.Asset","name":"nft","type":"tuple"},{"internalType":"Price","name":"price","type":"uint128"},{"internalType":"uint64","name":"expiry","type":"uint64"},{"internalType":"uint64","name":"salt","type":"uint64"}],"internalType":"structLibOrder.Order","name":"buyOrder","type":"tuple"}],"name":"matchOrder","outputs":[],"stateMutability":"payable","type":"function"},{"inputs":[{"components":[{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"internalType":"address","name":"maker","type":"address"},{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint96","name":"amount","type":"uint96"}],"internalType":"structLibOrder.Asset","name":"nft","type":"tuple"},{"internalType":"Price","name":"price","type":"uint128"},{"internalType":"uint64","name":"expiry","type":"uint64"},{"internalType":"uint64","name":"salt","type":"uint64"}],"internalType":"structLibOrder.Order","name":"sellOrder","type":"tuple"},{"components":[{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"internalType":"address","name":"maker","type":"address"},{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint96","name":"amount","type":"uint96"}],"internalType":"structLibOrder.Asset","name":"nft","type":"tuple"},{"internalType":"Price","name":"price","type":"uint128"},{"internalType":"uint64","name":"expiry","type":"uint64"},{"internalType":"uint64","name":"salt","type":"uint64"}],"internalType":"structLibOrder.Order","name":"buyOrder","type":"tuple"},{"internalType":"uint256","name":"msgValue","type":"uint256"}],"name":"matchOrderWithoutPayback","outputs":[{"internalType":"uint128","name":"costValue","type":"uint128"}],"stateMutability":"payable","type":"function"},{"inputs":[{"components":[{"components":[{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"internalType":"address","name":"maker","type":"address"},{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint96","name":"amount","type":"uint96"}],"internalType":"structLibOrder.Asset","name":"nft","type":"tuple"},{"internalType":"Price","name":"price","type":"uint128"},{"internalType":"uint64","name":"expiry","type":"uint64"},{"internalType":"uint64","name":"salt","type":"uint64"}],"internalType":"structLibOrder.Order","name":"sellOrder","type":"tuple"},{"components":[{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"internalType":"address","name":"maker","type":"address"},{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint96","name":"amount","type":"uint96"}],"internalType":"structLibOrder.Asset","name":"nft","type":"tuple"},{"internalType":"Price","name":"price","type":"uint128"},{"internalType":"uint64","name":"expiry","type":"uint64"},{"internalType":"uint64","name":"salt","type":"uint64"}],"internalType":"structLibOrder.Order","name":"buyOrder","type":"tuple"}],"internalType":"structLibOrder.MatchDetail[]","name":"matchDetails","type":"tuple[]"}],"name":"matchOrders","outputs":[{"internalType":"bool[]","name":"successes","type":"bool[]"}],"stateMutability":"payable","type":"function"},{"inputs":[{"internalType":"address","name":"","type":"address"},{"internalType":"enumLibOrder.Side","name":"","type":"uint8"},{"internalType":"Price","name":"","type":"uint128"}],"name":"orderQueues","outputs":[{"internalType":"OrderKey","name":"head","type":"bytes32"},{"internalType":"OrderKey","name":"tail","type":"bytes32"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"OrderKey","name":"","type":"bytes32"}],"name":"orders","outputs":[{"components":[{"internalType":"enumLibOrder.Side","name":"side","type":"uint8"},{"internalType":"enumLibOrder.SaleKind","name":"saleKind","type":"uint8"},{"internalType":"address","name":"maker","type":"address"},{"components":[{"internalType":"uint256","name":"tokenId","type":"uint256"},{"internalType":"address","name":"collection","type":"address"},{"internalType":"uint96","name":"amount","type":"uint96"}],"internalType":"structLibOrder.Asset","name":"nft","type":"tuple"},{"internalType":"Price","name":"price","type":"uint128"},{"internalType":"uint64","name":"expiry","type":"uint64"},{"internalType":"uint64","name":"salt","type":"uint64"}],"internalType":"structLibOrder.Order","name":"order","type":"tuple"},{"internalType":"OrderKey","name":"next","type":"bytes32"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"owner","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"pause","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[],"name":"paused","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"","type":"address"},{"internalType":"enumLibOrder.Side","name":"","type":"uint8"}],"name":"priceTrees","outputs":[{"internalType":"Price","name":"root","type":"uint128"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"protocolShare","outputs":[{"internalType":"uint128","name":"","type":"uint128"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"renounceOwnership","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"uint128","name":"newProtocolShare","type":"uint128"}],"name":"setProtocolShare","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"newVault","type":"address"}],"name":"setVault","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"newOwner","type":"address"}],"name":"transferOwnership","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[],"name":"unpause","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"recipient","type":"address"},{"internalType":"uint256","name":"amount","type":"uint256"}],"name":"withdrawETH","outputs":[],"stateMutability":"nonpayable","type":"function"},{"stateMutability":"payable","type":"receive"}]`
 	FixForCollection = 0
 	FixForItem       = 1
 	List             = 0
@@ -58,6 +62,12 @@ const (
 
 	HexPrefix   = "0x"
 	ZeroAddress = "0x0000000000000000000000000000000000000000"
+
+	LeaderCheckInterval = 5 // in seconds，非 leader 时检查是否当选的间隔
+	PauseCheckInterval  = 1 // in seconds，暂停时检查是否恢复的间隔
+
+	SyncLoopName  = "orderbook_sync"   // 订单簿事件同步循环
+	FloorLoopName = "collection_floor" // 藏品地板价维护循环
 )
 
 // Order 本身首字母大写 → 类型可导出；
@@ -77,7 +87,7 @@ type Order struct {
 	Maker    common.Address
 	Nft      struct {
 		TokenId        *big.Int
-		CollectionAddr common.Address
+		CollectionAddr common.Address `abi:"collection"`
 		Amount         *big.Int
 	}
 	Price  *big.Int
@@ -100,7 +110,32 @@ type Service struct {
 	chainClient  chainclient.ChainClient    // 区块链客户端，用于与区块链节点交互
 	chainId      int64                      // 链ID
 	chain        string                     // 链名称
-	parsedAbi    abi.ABI                    // 合约ABI对象，用于解析和编码合约数据
+	abis         *AbiBook                   // 按合约地址和区块高度选择的ABI，用于解析日志数据
+
+	collectionImporter *collectionimporter.Importer // 首次出现的collection导入器
+	metadataWorker     *itemmetadata.Worker         // 新item的元数据抓取器
+	currencies         *currency.Registry           // 支持的计价代币
+	blockTimes         map[uint64]uint64            // 当前批次日志的区块时间
+	handlers           *HandlerRegistry             // 按合约地址和 topic 登记的事件处理器
+
+	implementation          common.Address // 最近一次检查到的 DexAddress 实现合约
+	lastImplementationCheck time.Time
+
+	quit       chan struct{} // Stop 时关闭，同步循环处理完当前区块区间后退出
+	stopOnce   *sync.Once
+	heartbeat  *health.Heartbeat      // 常驻循环心跳，用于存活检查
+	supervisor *supervisor.Supervisor // 常驻循环退出后负责重启
+	elector    *leader.Elector        // 多副本部署时的 leader 选举，nil 表示不选举
+	webhooks   *webhook.Dispatcher    // 挂单、成交和取消事件的 webhook 推送，nil 表示不推送
+	replaying  bool                   // 重放归档日志时不向订单管理器和价格更新队列推送事件
+
+	pauseLock      *sync.Mutex
+	pauseRequested bool          // 管理接口请求暂停同步，同时保存在 Redis 中
+	idle           bool          // 同步循环已停在区间边界
+	reloadCursor   atomic.Bool   // 游标被管理接口修改，同步循环需要重新读取
+	floorLock      *sync.Mutex   // 地板价循环和管理接口不并发重算
+	headBlock      atomic.Uint64 // 最近一次查询到的链头高度
+	nextBlock      atomic.Uint64 // 下一个待同步的区块高度
 }
 
 // 声明并初始化一个包级可见的变量
@@ -128,10 +163,26 @@ var MultiChainMaxBlockDifference = map[string]uint64{
 }
 
 // New 是 Service 类型的构造函数，返回一个指向新创建的 Service 实例的指针
-// 【在New中，构造一个Service结构体的实例】
-func New(ctx context.Context, cfg *config.Config, db *gorm.DB, xkv *xkv.Store, chainClient chainclient.ChainClient, chainId int64, chain string, orderManager *ordermanager.OrderManager) *Service {
-	parsedAbi, _ := abi.JSON(strings.NewReader(contractAbi)) // 通过ABI实例化
-	return &Service{
+// 【在New中，构造一个Service结构体的实例】ABI 文件解析失败时直接返回错误
+func New(ctx context.Context, cfg *config.Config, db *gorm.DB, xkv *xkv.Store, chainClient chainclient.ChainClient, chainId int64, chain string, orderManager *ordermanager.OrderManager, collectionImporter *collectionimporter.Importer, metadataWorker *itemmetadata.Worker, currencies *currency.Registry) (*Service, error) {
+	var abiCfgs []config.AbiCfg
+	if cfg != nil {
+		abiCfgs = cfg.ContractCfg.Abis
+	}
+	abis, err := LoadAbiBook(abiCfgs) // 通过ABI实例化
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on load contract abi")
+	}
+	if currencies == nil { // 未传入时只支持 contract_cfg 中的 ETH/WETH
+		var contractCfg config.ContractCfg
+		if cfg != nil {
+			contractCfg = cfg.ContractCfg
+		}
+		if currencies, err = currency.New(contractCfg, nil); err != nil {
+			return nil, errors.Wrap(err, "failed on create currency registry")
+		}
+	}
+	s := &Service{
 		ctx:          ctx,
 		cfg:          cfg,
 		db:           db,
@@ -140,19 +191,74 @@ func New(ctx context.Context, cfg *config.Config, db *gorm.DB, xkv *xkv.Store, c
 		orderManager: orderManager,
 		chain:        chain,
 		chainId:      chainId,
-		parsedAbi:    parsedAbi,
+		abis:         abis,
+
+		collectionImporter: collectionImporter,
+		metadataWorker:     metadataWorker,
+		currencies:         currencies,
+		blockTimes:         make(map[uint64]uint64),
+		handlers:           NewHandlerRegistry(),
+		quit:               make(chan struct{}),
+		stopOnce:           &sync.Once{},
+		pauseLock:          &sync.Mutex{},
+		floorLock:          &sync.Mutex{},
+	}
+	if cfg != nil {
+		if err := s.registerOrderBookHandlers(common.HexToAddress(cfg.ContractCfg.DexAddress)); err != nil {
+			return nil, errors.Wrap(err, "failed on register order book handlers")
+		}
+		if err := s.registerUpgradeHandler(common.HexToAddress(cfg.ContractCfg.DexAddress)); err != nil {
+			return nil, errors.Wrap(err, "failed on register upgrade handler")
+		}
 	}
+	return s, nil
+}
+
+// SetHeartbeat 设置常驻循环的心跳记录，需在 Start 之前调用
+func (s *Service) SetHeartbeat(heartbeat *health.Heartbeat) {
+	s.heartbeat = heartbeat
+}
+
+// SetSupervisor 设置常驻循环的 supervisor，需在 Start 之前调用，未设置时循环退出后不再重启
+func (s *Service) SetSupervisor(supervisor *supervisor.Supervisor) {
+	s.supervisor = supervisor
+}
+
+// Stop 通知常驻循环退出：同步循环处理并提交完当前区块区间后返回，不会在区间中途中断
+func (s *Service) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.quit)
+	})
+}
+
+// sleep 等待 d，Stop 或 ctx 取消时提前返回
+func (s *Service) sleep(d time.Duration) {
+	select {
+	case <-s.ctx.Done():
+	case <-s.quit:
+	case <-time.After(d):
+	}
+}
+
+// SetElector 设置 leader 选举，需在 Start 之前调用，设置后只有 leader 同步订单簿和维护地板价
+func (s *Service) SetElector(elector *leader.Elector) {
+	s.elector = elector
+}
+
+// Progress 返回最近一次查询到的链头高度和下一个待同步的区块高度，同步循环首次查询链头之前 head 为 0
+func (s *Service) Progress() (head uint64, next uint64) {
+	return s.headBlock.Load(), s.nextBlock.Load()
 }
 
 // 给 Service 类型定义了一个 公开方法（首字母大写），外部可以 srv.Start() 调用
 // 这个 Start 方法是 orderbookindexer.Service（订单簿索引器）的启动入口
-// 通过 threading.GoSafe（go-zero 提供的「安全协程启动工具」），
+// 通过 supervisor（循环提前返回或 panic 时按指数退避重启，未设置时退化为 threading.GoSafe），
 // 异步启动两个`常驻后台`的循环任务，实现`订单簿数据`的同步和`藏品地板价`的维护
 func (s *Service) Start() {
 	// 1. 启动「订单簿事件同步循环」（常驻协程）
-	threading.GoSafe(s.SyncOrderBookEventLoop)
+	s.supervisor.Go(SyncLoopName, s.SyncOrderBookEventLoop)
 	// 2. 启动「藏品地板价维护循环」（常驻协程）
-	threading.GoSafe(s.UpKeepingCollectionFloorChangeLoop)
+	s.supervisor.Go(FloorLoopName, s.UpKeepingCollectionFloorChangeLoop)
 }
 
 // 订单簿事件同步核心循环：持续从链上拉取指定区块范围的订单相关日志（Make/Cancel/Match），解析并处理，同时记录同步进度
@@ -162,7 +268,7 @@ func (s *Service) SyncOrderBookEventLoop() {
 	if err := s.db.WithContext(s.ctx).Table(base.IndexedStatusTableName()).
 		Where("chain_id = ? and index_type = ?", s.chainId, EventIndexType).
 		First(&indexedStatus).Error; err != nil {
-		xzap.WithContext(s.ctx).Error("failed on get listing index status",
+		xzap.WithContext(s.ctx).Error("failed on get listing index status, run `sync init` to create it",
 			zap.Error(err))
 
 		fmt.Println("ERROR: ", err)
@@ -170,29 +276,61 @@ func (s *Service) SyncOrderBookEventLoop() {
 	}
 	fmt.Println("区块索引状态: ", indexedStatus)
 	lastSyncBlock := uint64(indexedStatus.LastIndexedBlock)
+	s.nextBlock.Store(lastSyncBlock)
 	fmt.Println("0 ---> 上次同步的区块高度: ", indexedStatus.LastIndexedBlock)
+	var leaderToken int64 // 同步 lastSyncBlock 时的 fencing token
 	for {
 		select {
 		case <-s.ctx.Done():
 			xzap.WithContext(s.ctx).Info("SyncOrderBookEventLoop stopped due to context cancellation")
 			return
+		case <-s.quit:
+			xzap.WithContext(s.ctx).Info("SyncOrderBookEventLoop stopped")
+			return
 		default:
 		}
+		s.heartbeat.Beat(SyncLoopName)
+
+		// 管理接口暂停同步时停在区间边界
+		if s.enterPause() {
+			s.sleep(PauseCheckInterval * time.Second)
+			continue
+		}
+
+		// 开启 leader 选举时只有 leader 同步；重新当选后游标可能已被其他实例推进，需要重新读取
+		token, isLeader := s.elector.Token()
+		if !isLeader {
+			s.sleep(LeaderCheckInterval * time.Second)
+			continue
+		}
+		if token != leaderToken || s.reloadCursor.Swap(false) {
+			cursor, err := s.LoadCursor()
+			if err != nil {
+				xzap.WithContext(s.ctx).Error("failed on reload orderbook event sync block number", zap.Error(err))
+				s.sleep(SleepInterval * time.Second)
+				continue
+			}
+			lastSyncBlock, leaderToken = cursor, token
+			s.nextBlock.Store(lastSyncBlock)
+		}
 
 		// 以轮询的方式获取当前区块高度
 		currentBlockNum, err := s.chainClient.BlockNumber()
 		if err != nil {
 			fmt.Println("failed on get current block number :", err)
 			xzap.WithContext(s.ctx).Error("failed on get current block number", zap.Error(err))
-			time.Sleep(SleepInterval * time.Second)
+			s.sleep(SleepInterval * time.Second)
 			continue
 		}
 
 		fmt.Println("查询到的currentBlockNum: ", currentBlockNum)
+		metrics.SetHeadBlock(s.chain, currentBlockNum)
+		s.headBlock.Store(currentBlockNum)
+		s.checkImplementation(currentBlockNum)
 		// 如果上次同步的区块高度大于当前区块高度，等待一段时间后再次轮询
 		// 留出区块间隔，避免同步到最新区块，确保数据稳定性【防止最新区块数据没有ch】
 		if lastSyncBlock > currentBlockNum-MultiChainMaxBlockDifference[s.chain] {
-			time.Sleep(SleepInterval * time.Second)
+			s.sleep(SleepInterval * time.Second)
 			continue
 		}
 
@@ -206,11 +344,11 @@ func (s *Service) SyncOrderBookEventLoop() {
 		// 构造过滤查询条件【***重点理解***】
 		// fromBlock: 起始区块高度
 		// toBlock: 结束区块高度
-		// addresses: 只关注指定合约地址的日志
+		// addresses: 只关注登记了处理器的合约地址的日志
 		query := types.FilterQuery{
 			FromBlock: new(big.Int).SetUint64(startBlock),
 			ToBlock:   new(big.Int).SetUint64(endBlock),
-			Addresses: []string{s.cfg.ContractCfg.DexAddress},
+			Addresses: s.handlers.Addresses(),
 		}
 
 		// 同时获取多个（SyncBlockPeriod）区块的日志
@@ -219,35 +357,59 @@ func (s *Service) SyncOrderBookEventLoop() {
 		if err != nil {
 			xzap.WithContext(s.ctx).Error("failed on get log", zap.Error(err))
 			fmt.Println("获取logs出错: ", err)
-			time.Sleep(SleepInterval * time.Second)
+			s.sleep(SleepInterval * time.Second)
 			continue
 		}
 		fmt.Println("获取到的logs数量: ", len(logs))
 
-		for _, log := range logs { // 遍历日志，根据不同的topic处理不同的事件
-			ethLog := log.(ethereumTypes.Log)
+		ethLogs := make([]ethereumTypes.Log, 0, len(logs))
+		for _, log := range logs {
+			ethLogs = append(ethLogs, log.(ethereumTypes.Log))
+		}
+		// 先归档原始日志，之后修复处理逻辑时可以直接从归档重放，无需重新拉取
+		if err := s.archiveLogs(ethLogs); err != nil {
+			xzap.WithContext(s.ctx).Error("failed on archive logs", zap.Error(err))
+			s.sleep(SleepInterval * time.Second)
+			continue
+		}
+
+		// 拉取日志期间可能失去租约，处理前再确认一次，避免和新 leader 重复发布事件
+		if token, isLeader := s.elector.Token(); !isLeader || token != leaderToken {
+			continue
+		}
+
+		paused := false
+		for _, ethLog := range ethLogs { // 遍历日志，根据不同的topic处理不同的事件
 			fmt.Println("ethLog日志==>  BlockNo: ", ethLog.BlockNumber, "| Address: ", ethLog.Address.String(), "|   Topics[0] : ", ethLog.Topics[0].String())
-			switch ethLog.Topics[0].String() {
-			case LogMakeTopic:
-				s.handleMakeEvent(ethLog)
-			case LogCancelTopic:
-				s.handleCancelEvent(ethLog)
-			case LogMatchTopic:
-				s.handleMatchEvent(ethLog)
-			default:
+			if err := s.handleLog(ethLog); err != nil {
+				// 合约升级后事件签名变化或链上查询失败，停在该区块，之后从该区块重新处理（处理逻辑幂等）
+				xzap.WithContext(s.ctx).Error("orderbook indexing paused", zap.Error(err))
+				endBlock = ethLog.BlockNumber - 1
+				paused = true
+				break
 			}
 		}
 
 		lastSyncBlock = endBlock + 1 // 更新最后同步的区块高度
-		if err := s.db.WithContext(s.ctx).Table(base.IndexedStatusTableName()).
-			Where("chain_id = ? and index_type = ?", s.chainId, EventIndexType).
-			Update("last_indexed_block", lastSyncBlock).Error; err != nil {
+		if err := s.commitCursor(lastSyncBlock); err != nil {
+			if errors.Is(err, leader.ErrNotLeader) || errors.Is(err, leader.ErrFenced) {
+				// 已不是 leader，放弃本区间，重新当选后从数据库读取游标
+				xzap.WithContext(s.ctx).Warn("orderbook event sync block number not committed", zap.Error(err))
+				leaderToken = 0
+				continue
+			}
 			xzap.WithContext(s.ctx).Error("failed on update orderbook event sync block number",
 				zap.Error(err))
 			fmt.Println("更新lastSyncBlock 出错 : ", err)
 			return
 		}
 		fmt.Println("更新后的lastSyncBlock = : ", lastSyncBlock)
+		metrics.SetLastIndexedBlock(s.chain, currentBlockNum, lastSyncBlock)
+		s.nextBlock.Store(lastSyncBlock)
+		if paused {
+			s.sleep(SleepInterval * time.Second)
+			continue
+		}
 
 		xzap.WithContext(s.ctx).Info("sync orderbook event ...",
 			zap.Uint64("start_block", startBlock),
@@ -256,7 +418,7 @@ func (s *Service) SyncOrderBookEventLoop() {
 }
 
 // 处理挂单事件
-func (s *Service) handleMakeEvent(log ethereumTypes.Log) {
+func (s *Service) handleMakeEvent(log ethereumTypes.Log) error {
 	/* Solidity 事件定义:
 		    // 挂新订单
 	    event LogMake(
@@ -286,7 +448,7 @@ func (s *Service) handleMakeEvent(log ethereumTypes.Log) {
 		OrderKey [32]byte
 		Nft      struct {
 			TokenId        *big.Int
-			CollectionAddr common.Address
+			CollectionAddr common.Address `abi:"collection"`
 			Amount         *big.Int
 		}
 		Price  *big.Int
@@ -295,10 +457,10 @@ func (s *Service) handleMakeEvent(log ethereumTypes.Log) {
 	}
 
 	// Unpack data
-	err := s.parsedAbi.UnpackIntoInterface(&event, "LogMake", log.Data) // 通过ABI解析日志数据
+	contractAbi := s.abis.Lookup(log.Address, log.BlockNumber)                  // 按发出日志的合约版本选择ABI
+	values, err := unpackEvent(contractAbi.Abi, LogMakeEvent, log.Data, &event) // 通过ABI解析日志数据，新版合约多出的字段不影响解码
 	if err != nil {
-		xzap.WithContext(s.ctx).Error("Error unpacking LogMake event:", zap.Error(err))
-		return
+		return errors.Wrapf(err, "failed on unpack LogMake event with abi %s", contractAbi.Version)
 	}
 	// Extract indexed fields from topics
 	side := uint8(new(big.Int).SetBytes(log.Topics[1].Bytes()).Uint64())
@@ -315,60 +477,113 @@ func (s *Service) handleMakeEvent(log ethereumTypes.Log) {
 	} else { // 卖单
 		orderType = multi.ListingOrder // 挂单
 	}
-	newOrder := multi.Order{
-		CollectionAddress: event.Nft.CollectionAddr.String(),
-		MarketplaceId:     multi.MarketOrderBook,
-		TokenId:           event.Nft.TokenId.String(),
-		OrderID:           HexPrefix + hex.EncodeToString(event.OrderKey[:]),
-		OrderStatus:       multi.OrderStatusActive,
-		EventTime:         time.Now().Unix(),
-		ExpireTime:        int64(event.Expiry),
-		CurrencyAddress:   s.cfg.ContractCfg.EthAddress,          // 支付代币合约地址
-		Price:             decimal.NewFromBigInt(event.Price, 0), // 出价
-		Maker:             maker.String(),                        // 挂单者地址
-		Taker:             ZeroAddress,                           // 买家地址
-		// 表示订单中尚未成交的NFT数量
-		QuantityRemaining: event.Nft.Amount.Int64(), // 剩余数量
-		Size:              event.Nft.Amount.Int64(), // 订单总数量
-		OrderType:         orderType,                // 订单类型
-		Salt:              int64(event.Salt),        // 随机数，防止订单ID冲突
-	}
-	// GORM框架中的"冲突处理"写法，用于保证数据唯一性，防止重复插入
-	// 原子性操作，避免并发问题
-	if err := s.db.WithContext(s.ctx).Table(multi.OrderTableName(s.chain)).Clauses(clause.OnConflict{
-		DoNothing: true,
-	}).Create(&newOrder).Error; err != nil { // 将订单信息存入数据库
-		xzap.WithContext(s.ctx).Error("failed on create order",
-			zap.Error(err))
+	// 新版合约的订单可以使用WETH/ERC20计价，旧版事件没有币种字段时按ETH处理
+	currencyAddress := s.makeCurrency(values)
+	newOrder := model.Order{
+		Order: multi.Order{
+			CollectionAddress: event.Nft.CollectionAddr.String(),
+			MarketplaceId:     multi.MarketOrderBook,
+			TokenId:           event.Nft.TokenId.String(),
+			OrderID:           HexPrefix + hex.EncodeToString(event.OrderKey[:]),
+			OrderStatus:       multi.OrderStatusActive,
+			EventTime:         time.Now().Unix(),
+			ExpireTime:        int64(event.Expiry),
+			CurrencyAddress:   currencyAddress,                       // 支付代币合约地址
+			Price:             decimal.NewFromBigInt(event.Price, 0), // 出价
+			Maker:             maker.String(),                        // 挂单者地址
+			Taker:             ZeroAddress,                           // 买家地址
+			// 表示订单中尚未成交的NFT数量
+			QuantityRemaining: event.Nft.Amount.Int64(), // 剩余数量
+			Size:              event.Nft.Amount.Int64(), // 订单总数量
+			OrderType:         orderType,                // 订单类型
+			Salt:              int64(event.Salt),        // 随机数，防止订单ID冲突
+		},
+		TxHash:    log.TxHash.String(),
+		BlockHash: log.BlockHash.String(),
+		LogIndex:  int64(log.Index),
 	}
 	// 记录活动日志，方便后续统计
-	blockTime, err := s.chainClient.BlockTimeByNumber(s.ctx, big.NewInt(int64(log.BlockNumber)))
+	blockTime, err := s.blockTime(log.BlockNumber)
 	if err != nil {
-		xzap.WithContext(s.ctx).Error("failed to get block time", zap.Error(err))
-		return
+		return errors.Wrap(err, "failed on get block time")
 	}
 	var activityType int
+	webhookEvent := webhook.EventListing
 	if side == Bid {
 		if saleKind == FixForCollection {
 			activityType = multi.CollectionBid // 集合买单
 		} else {
 			activityType = multi.ItemBid // 具体藏品买单(单品买单)
 		}
+		webhookEvent = webhook.EventOffer
 	} else {
 		activityType = multi.Listing // 挂单
 	}
-	newActivity := multi.Activity{ // 将订单信息存入活动表
-		ActivityType:      activityType,
-		Maker:             maker.String(),
-		Taker:             ZeroAddress,
-		MarketplaceID:     multi.MarketOrderBook,
-		CollectionAddress: event.Nft.CollectionAddr.String(),
-		TokenId:           event.Nft.TokenId.String(),
-		CurrencyAddress:   s.cfg.ContractCfg.EthAddress,
-		Price:             decimal.NewFromBigInt(event.Price, 0),
-		BlockNumber:       int64(log.BlockNumber),
-		TxHash:            log.TxHash.String(),
-		EventTime:         int64(blockTime), // 区块时间戳
+	newActivity := model.Activity{
+		Activity: multi.Activity{ // 将订单信息存入活动表
+			ActivityType:      activityType,
+			Maker:             maker.String(),
+			Taker:             ZeroAddress,
+			MarketplaceID:     multi.MarketOrderBook,
+			CollectionAddress: event.Nft.CollectionAddr.String(),
+			TokenId:           event.Nft.TokenId.String(),
+			CurrencyAddress:   currencyAddress,
+			Price:             decimal.NewFromBigInt(event.Price, 0),
+			BlockNumber:       int64(log.BlockNumber),
+			TxHash:            log.TxHash.String(),
+			EventTime:         int64(blockTime), // 区块时间戳
+		},
+		BlockHash: log.BlockHash.String(),
+		LogIndex:  int64(log.Index),
+	}
+
+	// 订单写入、item的list_price更新和webhook入队放在同一个事务中
+	var orderCreated, itemCreated bool
+	if err := s.db.WithContext(s.ctx).Transaction(func(tx *gorm.DB) error {
+		// GORM框架中的"冲突处理"写法，用于保证数据唯一性，防止重复插入
+		// 原子性操作，避免并发问题
+		result := tx.Table(multi.OrderTableName(s.chain)).Clauses(clause.OnConflict{
+			DoNothing: true,
+		}).Create(&newOrder) // 将订单信息存入数据库
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "failed on create order")
+		}
+		// 订单已存在说明该日志处理过（重放），不再重复推送订单管理队列和webhook
+		orderCreated = result.RowsAffected > 0
+		if orderCreated {
+			if err := s.publishWebhook(tx, webhookEvent, newOrder.OrderID, newActivity.Activity, log.Index); err != nil {
+				return err
+			}
+		}
+		// 集合买单不针对具体token，其余订单确保item存在，挂单时maker即为owner
+		if saleKind == FixForCollection {
+			return nil
+		}
+		var owner string
+		if side == List {
+			owner = maker.String()
+		}
+		var err error
+		if itemCreated, err = s.ensureItem(tx, newOrder.CollectionAddress, newOrder.TokenId, owner); err != nil {
+			return err
+		}
+		if side == List {
+			return itemprice.RefreshListing(tx, s.cfg.ProjectCfg.Name, s.chain, s.currencies, newOrder.CollectionAddress, newOrder.TokenId)
+		}
+		return nil
+	}); err != nil {
+		if errors.Is(err, ErrRetryLater) {
+			return err
+		}
+		// 事务已回滚
+		orderCreated, itemCreated = false, false
+		xzap.WithContext(s.ctx).Error("failed on create order",
+			zap.Error(err))
+	}
+	// 首次出现的collection交给导入器补全collection信息，新item交给元数据抓取器
+	s.collectionImporter.Submit(newOrder.CollectionAddress)
+	if itemCreated {
+		s.metadataWorker.Submit(newOrder.CollectionAddress, newOrder.TokenId)
 	}
 	// 插入活动信息
 	if err := s.db.WithContext(s.ctx).Table(multi.ActivityTableName(s.chain)).Clauses(clause.OnConflict{
@@ -378,7 +593,13 @@ func (s *Service) handleMakeEvent(log ethereumTypes.Log) {
 			zap.Error(err))
 	}
 
+	if !orderCreated {
+		return nil
+	}
 	// 挂单、取消订单，可能对nft的价格产生影响，所以放到队列中，稍后处理
+	if s.replaying {
+		return nil
+	}
 	if err := s.orderManager.AddToOrderManagerQueue(&multi.Order{ // 将订单信息存入订单管理队列
 		ExpireTime:        newOrder.ExpireTime,
 		OrderID:           newOrder.OrderID,
@@ -391,9 +612,10 @@ func (s *Service) handleMakeEvent(log ethereumTypes.Log) {
 			zap.Error(err),
 			zap.String("order_id", newOrder.OrderID))
 	}
+	return nil
 }
 
-func (s *Service) handleMatchEvent(log ethereumTypes.Log) {
+func (s *Service) handleMatchEvent(log ethereumTypes.Log) error {
 	/* Solidity 事件定义:
 		event LogMatch(
 	        OrderKey indexed makeOrderKey,
@@ -442,11 +664,13 @@ func (s *Service) handleMatchEvent(log ethereumTypes.Log) {
 		}
 	*/
 
-	err := s.parsedAbi.UnpackIntoInterface(&event, "LogMatch", log.Data)
+	contractAbi := s.abis.Lookup(log.Address, log.BlockNumber)
+	values, err := unpackEvent(contractAbi.Abi, LogMatchEvent, log.Data, &event)
 	if err != nil {
-		xzap.WithContext(s.ctx).Error("Error unpacking LogMatch event:", zap.Error(err))
-		return
+		return errors.Wrapf(err, "failed on unpack LogMatch event with abi %s", contractAbi.Version)
 	}
+	// 成交币种与 makeOrder 一致，item 的 sale_price 和成交活动都按它记录
+	currencyAddress := s.matchCurrency(values)
 
 	// 原始订单ID，从事件日志的第一个topic中解析得到
 	makeOrderId := HexPrefix + hex.EncodeToString(log.Topics[1].Bytes())
@@ -458,7 +682,7 @@ func (s *Service) handleMatchEvent(log ethereumTypes.Log) {
 	var from string                  // NFT转出方地址
 	var to string                    // NFT接收方地址
 	var sellOrderId string           // 卖方(卖NFT的)订单的唯一标识符，用于后续价格更新
-	var buyOrder multi.Order         // 买单的详细信息结构体，包含订单状态、数量等信息
+	var buyOrderId string            // 买方(买NFT的)订单的唯一标识符
 	if event.MakeOrder.Side == Bid { // 下单人是买单(买NFT)， 由卖方(卖NFT)发起交易撮合
 		owner = strings.ToLower(event.MakeOrder.Maker.String()) // NFT最终归属人
 		collection = event.TakeOrder.Nft.CollectionAddr.String()
@@ -466,50 +690,7 @@ func (s *Service) handleMatchEvent(log ethereumTypes.Log) {
 		from = event.TakeOrder.Maker.String()
 		to = event.MakeOrder.Maker.String()
 		sellOrderId = takeOrderId // 卖方(卖NFT的)订单的唯一标识符
-
-		// 更新卖方订单状态
-		// 卖NFT的订单，直接全部成交，状态改为已完成【原因见第17个文档】
-		if err := s.db.WithContext(s.ctx).Table(multi.OrderTableName(s.chain)).
-			Where("order_id = ?", takeOrderId).
-			Updates(map[string]interface{}{
-				"order_status":       multi.OrderStatusFilled,
-				"quantity_remaining": 0,
-				"taker":              to,
-			}).Error; err != nil {
-			xzap.WithContext(s.ctx).Error("failed on update order status",
-				zap.String("order_id", takeOrderId))
-			return
-		}
-
-		// 查询买方订单信息，不存在则无需更新，说明不是从平台前端发起的交易
-		if err := s.db.WithContext(s.ctx).Table(multi.OrderTableName(s.chain)).
-			Where("order_id = ?", makeOrderId).
-			First(&buyOrder).Error; err != nil {
-			xzap.WithContext(s.ctx).Error("failed on get buy order",
-				zap.Error(err))
-			return
-		}
-		// 更新买方订单的剩余数量
-		if buyOrder.QuantityRemaining > 1 {
-			if err := s.db.WithContext(s.ctx).Table(multi.OrderTableName(s.chain)).
-				Where("order_id = ?", makeOrderId).
-				Update("quantity_remaining", buyOrder.QuantityRemaining-1).Error; err != nil {
-				xzap.WithContext(s.ctx).Error("failed on update order quantity_remaining",
-					zap.String("order_id", makeOrderId))
-				return
-			}
-		} else {
-			if err := s.db.WithContext(s.ctx).Table(multi.OrderTableName(s.chain)).
-				Where("order_id = ?", makeOrderId).
-				Updates(map[string]interface{}{
-					"order_status":       multi.OrderStatusFilled,
-					"quantity_remaining": 0,
-				}).Error; err != nil {
-				xzap.WithContext(s.ctx).Error("failed on update order status",
-					zap.String("order_id", makeOrderId))
-				return
-			}
-		}
+		buyOrderId = makeOrderId
 	} else { // 卖单， takeOrder就是买方，发起交易撮合， 同理
 		owner = strings.ToLower(event.TakeOrder.Maker.String()) // NFT最终归属人
 		collection = event.MakeOrder.Nft.CollectionAddr.String()
@@ -517,66 +698,115 @@ func (s *Service) handleMatchEvent(log ethereumTypes.Log) {
 		from = event.MakeOrder.Maker.String()
 		to = event.TakeOrder.Maker.String()
 		sellOrderId = makeOrderId
+		buyOrderId = takeOrderId
+	}
+
+	blockTime, err := s.blockTime(log.BlockNumber)
+	if err != nil {
+		return errors.Wrap(err, "failed on get block time")
+	}
+
+	newActivity := model.Activity{
+		Activity: multi.Activity{
+			ActivityType:      multi.Sale,
+			Maker:             event.MakeOrder.Maker.String(),
+			Taker:             event.TakeOrder.Maker.String(),
+			MarketplaceID:     multi.MarketOrderBook,
+			CollectionAddress: collection,
+			TokenId:           tokenId,
+			CurrencyAddress:   currencyAddress,
+			Price:             decimal.NewFromBigInt(event.FillPrice, 0),
+			BlockNumber:       int64(log.BlockNumber),
+			TxHash:            log.TxHash.String(),
+			EventTime:         int64(blockTime),
+		},
+		BlockHash: log.BlockHash.String(),
+		LogIndex:  int64(log.Index),
+	}
+	// 成交事件的 maker 为卖方，taker 为买方
+	sale := newActivity.Activity
+	sale.Maker, sale.Taker = from, to
+
+	// 订单状态、成交记录、item的owner和价格字段与webhook入队在同一个事务中更新
+	var matchApplied, itemCreated bool
+	if err := s.db.WithContext(s.ctx).Transaction(func(tx *gorm.DB) error {
+		// 撮合双方的订单各记录一次成交，集合买单据此可知每次成交的token
+		fills := []model.OrderFill{
+			newOrderFill(log, makeOrderId, collection, tokenId, event.FillPrice, event.TakeOrder.Maker.String(), blockTime),
+			newOrderFill(log, takeOrderId, collection, tokenId, event.FillPrice, event.MakeOrder.Maker.String(), blockTime),
+		}
+		result := tx.Table(model.OrderFillTableName(s.chain)).Clauses(clause.OnConflict{
+			DoNothing: true,
+		}).Create(&fills)
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "failed on create order fills")
+		}
+		// 成交记录已存在说明该日志处理过（重放），订单剩余数量、owner等不再重复更新
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		matchApplied = true
 
-		if err := s.db.WithContext(s.ctx).Table(multi.OrderTableName(s.chain)).
-			Where("order_id = ?", makeOrderId).
+		// 更新卖方订单状态
+		// 卖NFT的订单，直接全部成交，状态改为已完成【原因见第17个文档】
+		if err := tx.Table(multi.OrderTableName(s.chain)).
+			Where("order_id = ?", sellOrderId).
 			Updates(map[string]interface{}{
 				"order_status":       multi.OrderStatusFilled,
 				"quantity_remaining": 0,
 				"taker":              to,
 			}).Error; err != nil {
-			xzap.WithContext(s.ctx).Error("failed on update order status",
-				zap.String("order_id", makeOrderId))
-			return
+			return errors.Wrap(err, "failed on update sell order status")
 		}
 
-		if err := s.db.WithContext(s.ctx).Table(multi.OrderTableName(s.chain)).
-			Where("order_id = ?", takeOrderId).
-			First(&buyOrder).Error; err != nil {
-			xzap.WithContext(s.ctx).Error("failed on get buy order",
-				zap.Error(err))
-			return
+		// 查询买方订单信息，不存在则无需更新，说明不是从平台前端发起的交易
+		var buyOrder multi.Order
+		err := tx.Table(multi.OrderTableName(s.chain)).
+			Where("order_id = ?", buyOrderId).
+			First(&buyOrder).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.Wrap(err, "failed on get buy order")
 		}
-		if buyOrder.QuantityRemaining > 1 {
-			if err := s.db.WithContext(s.ctx).Table(multi.OrderTableName(s.chain)).
-				Where("order_id = ?", takeOrderId).
-				Update("quantity_remaining", buyOrder.QuantityRemaining-1).Error; err != nil {
-				xzap.WithContext(s.ctx).Error("failed on update order quantity_remaining",
-					zap.String("order_id", takeOrderId))
-				return
-			}
-		} else {
-			if err := s.db.WithContext(s.ctx).Table(multi.OrderTableName(s.chain)).
-				Where("order_id = ?", takeOrderId).
-				Updates(map[string]interface{}{
+		if err == nil {
+			// 更新买方订单的剩余数量
+			updates := map[string]interface{}{"quantity_remaining": buyOrder.QuantityRemaining - 1}
+			if buyOrder.QuantityRemaining <= 1 {
+				updates = map[string]interface{}{
 					"order_status":       multi.OrderStatusFilled,
 					"quantity_remaining": 0,
-				}).Error; err != nil {
-				xzap.WithContext(s.ctx).Error("failed on update order status",
-					zap.String("order_id", takeOrderId))
-				return
+				}
+			}
+			if err := tx.Table(multi.OrderTableName(s.chain)).
+				Where("order_id = ?", buyOrderId).
+				Updates(updates).Error; err != nil {
+				return errors.Wrap(err, "failed on update buy order")
 			}
 		}
-	}
 
-	blockTime, err := s.chainClient.BlockTimeByNumber(s.ctx, big.NewInt(int64(log.BlockNumber)))
-	if err != nil {
-		xzap.WithContext(s.ctx).Error("failed to get block time", zap.Error(err))
-		return
+		// 更新NFT的所有者，item不存在时先创建
+		if itemCreated, err = s.ensureItem(tx, collection, tokenId, owner); err != nil {
+			return err
+		}
+		if err := tx.Table(multi.ItemTableName(s.chain)).
+			Where("collection_address = ? and token_id = ?", strings.ToLower(collection), tokenId).
+			Update("owner", owner).Error; err != nil {
+			return errors.Wrap(err, "failed to update item owner")
+		}
+		// 成交后owner变化，原有listing失效，重新计算list_price
+		if err := itemprice.UpdateSalePrice(tx, s.cfg.ProjectCfg.Name, s.chain, s.currencies, collection, tokenId, currencyAddress, decimal.NewFromBigInt(event.FillPrice, 0)); err != nil {
+			return err
+		}
+		if err := itemprice.RefreshListing(tx, s.cfg.ProjectCfg.Name, s.chain, s.currencies, collection, tokenId); err != nil {
+			return err
+		}
+		return s.publishWebhook(tx, webhook.EventSale, sellOrderId, sale, log.Index)
+	}); err != nil {
+		return errors.Wrapf(err, "failed on update match orders %s/%s", sellOrderId, buyOrderId)
 	}
-	newActivity := multi.Activity{
-		ActivityType:      multi.Sale,
-		Maker:             event.MakeOrder.Maker.String(),
-		Taker:             event.TakeOrder.Maker.String(),
-		MarketplaceID:     multi.MarketOrderBook,
-		CollectionAddress: collection,
-		TokenId:           tokenId,
-		CurrencyAddress:   s.cfg.ContractCfg.EthAddress,
-		Price:             decimal.NewFromBigInt(event.FillPrice, 0),
-		BlockNumber:       int64(log.BlockNumber),
-		TxHash:            log.TxHash.String(),
-		EventTime:         int64(blockTime),
+	if itemCreated {
+		s.metadataWorker.Submit(collection, tokenId)
 	}
+
 	if err := s.db.WithContext(s.ctx).Table(multi.ActivityTableName(s.chain)).Clauses(clause.OnConflict{
 		DoNothing: true,
 	}).Create(&newActivity).Error; err != nil {
@@ -584,15 +814,12 @@ func (s *Service) handleMatchEvent(log ethereumTypes.Log) {
 			zap.Error(err))
 	}
 
-	// 更新NFT的所有者
-	if err := s.db.WithContext(s.ctx).Table(multi.ItemTableName(s.chain)).
-		Where("collection_address = ? and token_id = ?", strings.ToLower(collection), tokenId).
-		Update("owner", owner).Error; err != nil {
-		xzap.WithContext(s.ctx).Error("failed to update item owner",
-			zap.Error(err))
-		return
+	if !matchApplied {
+		return nil
+	}
+	if s.replaying {
+		return nil
 	}
-
 	if err := ordermanager.AddUpdatePriceEvent(s.kv, &ordermanager.TradeEvent{ // 将交易信息存入价格更新队列
 		OrderId:        sellOrderId,
 		CollectionAddr: collection,
@@ -606,9 +833,10 @@ func (s *Service) handleMatchEvent(log ethereumTypes.Log) {
 			zap.String("type", "sale"),
 			zap.String("order_id", sellOrderId))
 	}
+	return nil
 }
 
-func (s *Service) handleCancelEvent(log ethereumTypes.Log) {
+func (s *Service) handleCancelEvent(log ethereumTypes.Log) error {
 	/*
 		Solidity 事件定义:
 		event LogCancel(OrderKey indexed orderKey, address indexed maker);
@@ -617,50 +845,58 @@ func (s *Service) handleCancelEvent(log ethereumTypes.Log) {
 	orderId := HexPrefix + hex.EncodeToString(log.Topics[1].Bytes())
 	//maker := common.BytesToAddress(log.Topics[2].Bytes())
 
-	// 更新订单状态为已取消
-	if err := s.db.WithContext(s.ctx).Table(multi.OrderTableName(s.chain)).
-		Where("order_id = ?", orderId).
-		Update("order_status", multi.OrderStatusCancelled).Error; err != nil {
-		xzap.WithContext(s.ctx).Error("failed on update order status",
-			zap.String("order_id", orderId))
-		return
+	blockTime, err := s.blockTime(log.BlockNumber)
+	if err != nil {
+		return errors.Wrap(err, "failed on get block time")
 	}
 
+	// 订单状态、item的list_price与webhook入队在同一个事务中更新
 	var cancelOrder multi.Order
-	if err := s.db.WithContext(s.ctx).Table(multi.OrderTableName(s.chain)).
-		Where("order_id = ?", orderId).
-		First(&cancelOrder).Error; err != nil {
-		xzap.WithContext(s.ctx).Error("failed on get cancel order",
-			zap.Error(err))
-		return
-	}
+	var cancelApplied, itemCreated bool
+	if err := s.db.WithContext(s.ctx).Transaction(func(tx *gorm.DB) error {
+		// 更新订单状态为已取消，订单已是取消状态说明该日志处理过（重放），不再重复推送
+		result := tx.Table(multi.OrderTableName(s.chain)).
+			Where("order_id = ? and order_status <> ?", orderId, multi.OrderStatusCancelled).
+			Update("order_status", multi.OrderStatusCancelled)
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "failed on update order status")
+		}
+		cancelApplied = result.RowsAffected > 0
 
-	blockTime, err := s.chainClient.BlockTimeByNumber(s.ctx, big.NewInt(int64(log.BlockNumber)))
-	if err != nil {
-		xzap.WithContext(s.ctx).Error("failed to get block time", zap.Error(err))
-		return
-	}
-	var activityType int
-	if cancelOrder.OrderType == multi.ListingOrder {
-		activityType = multi.CancelListing
-	} else if cancelOrder.OrderType == multi.CollectionBidOrder {
-		activityType = multi.CancelCollectionBid
-	} else {
-		activityType = multi.CancelItemBid
+		if err := tx.Table(multi.OrderTableName(s.chain)).
+			Where("order_id = ?", orderId).
+			First(&cancelOrder).Error; err != nil {
+			return errors.Wrap(err, "failed on get cancel order")
+		}
+		if cancelApplied {
+			if err := s.publishWebhook(tx, webhook.EventCancel, cancelOrder.OrderID, cancelActivity(log, cancelOrder, blockTime).Activity, log.Index); err != nil {
+				return err
+			}
+		}
+		if cancelOrder.OrderType == multi.CollectionBidOrder {
+			return nil
+		}
+
+		var owner string
+		if cancelOrder.OrderType == multi.ListingOrder {
+			owner = cancelOrder.Maker
+		}
+		var err error
+		if itemCreated, err = s.ensureItem(tx, cancelOrder.CollectionAddress, cancelOrder.TokenId, owner); err != nil {
+			return err
+		}
+		if cancelOrder.OrderType == multi.ListingOrder {
+			return itemprice.RefreshListing(tx, s.cfg.ProjectCfg.Name, s.chain, s.currencies, cancelOrder.CollectionAddress, cancelOrder.TokenId)
+		}
+		return nil
+	}); err != nil {
+		return errors.Wrapf(err, "failed on cancel order %s", orderId)
 	}
-	newActivity := multi.Activity{
-		ActivityType:      activityType,
-		Maker:             cancelOrder.Maker,
-		Taker:             ZeroAddress,
-		MarketplaceID:     multi.MarketOrderBook,
-		CollectionAddress: cancelOrder.CollectionAddress,
-		TokenId:           cancelOrder.TokenId,
-		CurrencyAddress:   s.cfg.ContractCfg.EthAddress,
-		Price:             cancelOrder.Price,
-		BlockNumber:       int64(log.BlockNumber),
-		TxHash:            log.TxHash.String(),
-		EventTime:         int64(blockTime),
+	if itemCreated {
+		s.metadataWorker.Submit(cancelOrder.CollectionAddress, cancelOrder.TokenId)
 	}
+
+	newActivity := cancelActivity(log, cancelOrder, blockTime)
 	if err := s.db.WithContext(s.ctx).Table(multi.ActivityTableName(s.chain)).Clauses(clause.OnConflict{
 		DoNothing: true,
 	}).Create(&newActivity).Error; err != nil {
@@ -668,6 +904,12 @@ func (s *Service) handleCancelEvent(log ethereumTypes.Log) {
 			zap.Error(err))
 	}
 
+	if !cancelApplied {
+		return nil
+	}
+	if s.replaying {
+		return nil
+	}
 	if err := ordermanager.AddUpdatePriceEvent(s.kv, &ordermanager.TradeEvent{
 		OrderId:        cancelOrder.OrderID,
 		CollectionAddr: cancelOrder.CollectionAddress,
@@ -679,6 +921,36 @@ func (s *Service) handleCancelEvent(log ethereumTypes.Log) {
 			zap.String("type", "cancel"),
 			zap.String("order_id", cancelOrder.OrderID))
 	}
+	return nil
+}
+
+// cancelActivity 取消订单对应的活动记录
+func cancelActivity(log ethereumTypes.Log, cancelOrder multi.Order, blockTime uint64) model.Activity {
+	var activityType int
+	if cancelOrder.OrderType == multi.ListingOrder {
+		activityType = multi.CancelListing
+	} else if cancelOrder.OrderType == multi.CollectionBidOrder {
+		activityType = multi.CancelCollectionBid
+	} else {
+		activityType = multi.CancelItemBid
+	}
+	return model.Activity{
+		Activity: multi.Activity{
+			ActivityType:      activityType,
+			Maker:             cancelOrder.Maker,
+			Taker:             ZeroAddress,
+			MarketplaceID:     multi.MarketOrderBook,
+			CollectionAddress: cancelOrder.CollectionAddress,
+			TokenId:           cancelOrder.TokenId,
+			CurrencyAddress:   cancelOrder.CurrencyAddress,
+			Price:             cancelOrder.Price,
+			BlockNumber:       int64(log.BlockNumber),
+			TxHash:            log.TxHash.String(),
+			EventTime:         int64(blockTime),
+		},
+		BlockHash: log.BlockHash.String(),
+		LogIndex:  int64(log.Index),
+	}
 }
 
 func (s *Service) UpKeepingCollectionFloorChangeLoop() {
@@ -686,13 +958,15 @@ func (s *Service) UpKeepingCollectionFloorChangeLoop() {
 	defer timer.Stop()
 	updateFloorPriceTimer := time.NewTicker(comm.MaxCollectionFloorTimeDifference * time.Second)
 	defer updateFloorPriceTimer.Stop()
+	currencyStatsTimer := time.NewTicker(comm.CollectionFloorSyncPeriod * time.Second)
+	defer currencyStatsTimer.Stop()
 
 	var indexedStatus base.IndexedStatus
 	if err := s.db.WithContext(s.ctx).Table(base.IndexedStatusTableName()).
 		Select("last_indexed_time").
 		Where("chain_id = ? and index_type = ?", s.chainId, comm.CollectionFloorChangeIndexType).
 		First(&indexedStatus).Error; err != nil {
-		xzap.WithContext(s.ctx).Error("failed on get collection floor change index status",
+		xzap.WithContext(s.ctx).Error("failed on get collection floor change index status, run `sync init` to create it",
 			zap.Error(err))
 		return
 	}
@@ -702,26 +976,40 @@ func (s *Service) UpKeepingCollectionFloorChangeLoop() {
 		case <-s.ctx.Done():
 			xzap.WithContext(s.ctx).Info("UpKeepingCollectionFloorChangeLoop stopped due to context cancellation")
 			return
+		case <-s.quit:
+			xzap.WithContext(s.ctx).Info("UpKeepingCollectionFloorChangeLoop stopped")
+			return
 		case <-timer.C:
+			if !s.elector.IsLeader() {
+				continue
+			}
+			start := time.Now()
 			if err := s.deleteExpireCollectionFloorChangeFromDatabase(); err != nil {
 				xzap.WithContext(s.ctx).Error("failed on delete expire collection floor change",
 					zap.Error(err))
 			}
+			metrics.ObserveFloorJob(s.chain, "cleanup", start)
 		case <-updateFloorPriceTimer.C:
+			s.heartbeat.Beat(FloorLoopName)
+			if !s.elector.IsLeader() {
+				continue
+			}
 			if s.cfg.ProjectCfg.Name == gdb.OrderBookDexProject {
-				floorPrices, err := s.QueryCollectionsFloorPrice()
-				if err != nil {
-					xzap.WithContext(s.ctx).Error("failed on query collections floor change",
+				if err := s.UpdateFloorPrices(); err != nil {
+					xzap.WithContext(s.ctx).Error("failed on update collections floor price",
 						zap.Error(err))
-					continue
-				}
-
-				if err := s.persistCollectionsFloorChange(floorPrices); err != nil {
-					xzap.WithContext(s.ctx).Error("failed on persist collections floor price",
-						zap.Error(err))
-					continue
 				}
 			}
+		case <-currencyStatsTimer.C:
+			if !s.elector.IsLeader() {
+				continue
+			}
+			start := time.Now()
+			if err := s.persistCurrencyStats(); err != nil {
+				xzap.WithContext(s.ctx).Error("failed on persist collection currency stats",
+					zap.Error(err))
+			}
+			metrics.ObserveFloorJob(s.chain, "currency_stats", start)
 		default:
 		}
 	}
@@ -737,23 +1025,15 @@ func (s *Service) deleteExpireCollectionFloorChangeFromDatabase() error {
 	return nil
 }
 
+// QueryCollectionsFloorPrice 查询各collection的地板价，不同计价代币的listing折合成ETH后取最低价
 func (s *Service) QueryCollectionsFloorPrice() ([]multi.CollectionFloorPrice, error) {
 	timestamp := time.Now().Unix()
 	timestampMilli := time.Now().UnixMilli()
-	var collectionFloorPrice []multi.CollectionFloorPrice
-	sql := fmt.Sprintf(`SELECT co.collection_address as collection_address,min(co.price) as price
-FROM %s as ci
-         left join %s co on co.collection_address = ci.collection_address and co.token_id = ci.token_id
-WHERE (co.order_type = ? and
-       co.order_status = ? and expire_time > ? and co.maker = ci.owner) group by co.collection_address`, gdb.GetMultiProjectItemTableName(s.cfg.ProjectCfg.Name, s.chain), gdb.GetMultiProjectOrderTableName(s.cfg.ProjectCfg.Name, s.chain))
-	if err := s.db.WithContext(s.ctx).Raw(
-		sql,
-		multi.ListingType,
-		multi.OrderStatusActive,
-		time.Now().Unix(),
-	).Scan(&collectionFloorPrice).Error; err != nil {
-		return nil, errors.Wrap(err, "failed on get collection floor price")
+	currencyFloorPrices, err := s.queryCurrencyFloorPrices()
+	if err != nil {
+		return nil, err
 	}
+	collectionFloorPrice := s.lowestEthPrices(currencyFloorPrices)
 
 	for i := 0; i < len(collectionFloorPrice); i++ {
 		collectionFloorPrice[i].EventTime = timestamp
@@ -27,7 +27,10 @@ func TestSyncEvent(t *testing.T) {
 		MaxOpenConns: 1500,
 	})
 	chainClient, _ := chainclient.New(10, "https://rpc.ankr.com/optimism/9c6c678ebcb56da1cb80f7632c7c02264831232c3d53453c7726a611e7ca36d7")
-	orderbookSyncer := New(ctx, nil, db, nil, chainClient, 10, "optimism", nil)
+	orderbookSyncer, err := New(ctx, nil, db, nil, chainClient, 10, "optimism", nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	query := types.FilterQuery{
 		FromBlock: new(big.Int).SetUint64(111819366),
@@ -35,20 +38,14 @@ func TestSyncEvent(t *testing.T) {
 		Addresses: []string{"0x7d29d1860bD4d3A74bBD9a03C9B043d375311dCb"},
 	}
 
+	if err := orderbookSyncer.registerOrderBookHandlers(common.HexToAddress(query.Addresses[0])); err != nil {
+		t.Fatal(err)
+	}
+
 	logs, _ := chainClient.FilterLogs(ctx, query)
 
 	for _, log := range logs {
-		ethLog := log.(ethereumTypes.Log)
-		switch ethLog.Topics[0].String() {
-		case LogMakeTopic:
-			orderbookSyncer.handleMakeEvent(ethLog)
-		case LogCancelTopic:
-			orderbookSyncer.handleCancelEvent(ethLog)
-		case LogMatchTopic:
-			orderbookSyncer.handleMatchEvent(ethLog)
-		default:
-
-		}
+		orderbookSyncer.handleLog(log.(ethereumTypes.Log))
 	}
 }
 
@@ -68,7 +65,10 @@ func TestHandleMakeEvent(t *testing.T) {
 		MaxOpenConns: 1500,
 	})
 	chainClient, _ := chainclient.New(10, "https://rpc.ankr.com/optimism/9c6c678ebcb56da1cb80f7632c7c02264831232c3d53453c7726a611e7ca36d7")
-	orderbookSyncer := New(ctx, nil, db, nil, chainClient, 10, "optimism", nil)
+	orderbookSyncer, err := New(ctx, nil, db, nil, chainClient, 10, "optimism", nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 	data, _ := hex.DecodeString("c773ae81bc9a186dc6c5d70a486730a6f734578ae1a0116acd0aaaf69250d2650000000000000000000000000000000000000000000000000000000000000000000000000000000000000000e7f1725e7734ce288f8367e1bb143e90bb3f05120000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000002386f26fc10000000000000000000000000000000000000000000000000000000000006558875d0000000000000000000000000000000000000000000000000000000000000001")
 	log := ethereumTypes.Log{
 		Address: common.HexToAddress("0x123"),
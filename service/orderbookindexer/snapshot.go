@@ -0,0 +1,191 @@
+package orderbookindexer
+
+import (
+	"encoding/hex"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yaoxc/EasySwapSync/model"
+)
+
+// snapshotOrder 合约 orders(orderKey) 的返回值
+type snapshotOrder struct {
+	Order struct {
+		Side     uint8
+		SaleKind uint8
+		Maker    common.Address
+		Nft      struct {
+			TokenId    *big.Int
+			Collection common.Address
+			Amount     *big.Int
+		}
+		Price  *big.Int
+		Expiry uint64
+		Salt   uint64
+	}
+	Next [32]byte
+}
+
+// Snapshot 在 blockNumber 区块按 collection/side 遍历合约中的订单簿（价格树 -> 价位队列 -> 订单链表），
+// 写入所有仍有效的订单，返回写入的订单数量，由调用方确认结果后再把同步游标设置到快照区块之后。
+// 快照订单没有对应的日志，tx_hash 等字段为空，重复执行时已存在的订单保持不变；
+// 合约订单中没有计价代币，快照订单一律按 ETH 计价
+func (s *Service) Snapshot(collections []string, blockNumber uint64) (int, error) {
+	if len(collections) == 0 {
+		return 0, errors.New("no collections to snapshot")
+	}
+	xzap.WithContext(s.ctx).Warn("snapshot orders are assumed to be ETH-priced, orders in other currencies get wrong prices",
+		zap.String("currency_address", s.currencies.Eth()))
+
+	snapshotBlock := new(big.Int).SetUint64(blockNumber)
+	blockTime, err := s.blockTime(blockNumber)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed on get snapshot block time")
+	}
+	dex := common.HexToAddress(s.cfg.ContractCfg.DexAddress)
+	contractAbi := s.abis.Lookup(dex, blockNumber).Abi
+
+	var total int
+	for _, collection := range collections {
+		collectionAddr := common.HexToAddress(collection)
+		for _, side := range []uint8{List, Bid} {
+			var root *big.Int
+			if err := s.callContract(contractAbi, dex, snapshotBlock, &root, "priceTrees", collectionAddr, side); err != nil {
+				return total, err
+			}
+			if root.Sign() == 0 {
+				continue // 价格树为空
+			}
+
+			var price *big.Int
+			if err := s.callContract(contractAbi, dex, snapshotBlock, &price, "getBestPrice", collectionAddr, side); err != nil {
+				return total, err
+			}
+			for price.Sign() != 0 {
+				var queue struct {
+					Head [32]byte
+					Tail [32]byte
+				}
+				if err := s.callContract(contractAbi, dex, snapshotBlock, &queue, "orderQueues", collectionAddr, side, price); err != nil {
+					return total, err
+				}
+				count, err := s.snapshotQueue(contractAbi, dex, snapshotBlock, queue.Head, blockTime)
+				if err != nil {
+					return total, err
+				}
+				total += count
+
+				var next *big.Int
+				if err := s.callContract(contractAbi, dex, snapshotBlock, &next, "getNextBestPrice", collectionAddr, side, price); err != nil {
+					return total, err
+				}
+				price = next
+			}
+		}
+		xzap.WithContext(s.ctx).Info("collection order book snapshotted",
+			zap.String("collection_address", collection), zap.Int("orders", total))
+	}
+	return total, nil
+}
+
+// snapshotQueue 沿价位队列的订单链表写入订单，已过期或已全部成交的订单跳过
+func (s *Service) snapshotQueue(contractAbi abi.ABI, dex common.Address, snapshotBlock *big.Int, head [32]byte, blockTime uint64) (int, error) {
+	var count int
+	visited := make(map[[32]byte]bool)
+	for orderKey := head; orderKey != ([32]byte{}) && !visited[orderKey]; {
+		visited[orderKey] = true
+		var result snapshotOrder
+		if err := s.callContract(contractAbi, dex, snapshotBlock, &result, "orders", orderKey); err != nil {
+			return count, err
+		}
+		var filled *big.Int
+		if err := s.callContract(contractAbi, dex, snapshotBlock, &filled, "filledAmount", orderKey); err != nil {
+			return count, err
+		}
+
+		order := result.Order
+		remaining := order.Nft.Amount.Int64() - filled.Int64()
+		live := remaining > 0 && (order.Expiry == 0 || order.Expiry > blockTime)
+		if live {
+			if err := s.insertSnapshotOrder(orderKey, result, remaining, blockTime); err != nil {
+				return count, err
+			}
+			count++
+		}
+		orderKey = result.Next
+	}
+	return count, nil
+}
+
+func (s *Service) insertSnapshotOrder(orderKey [32]byte, result snapshotOrder, remaining int64, blockTime uint64) error {
+	order := result.Order
+	orderType := int64(multi.ListingOrder)
+	if order.Side == Bid {
+		orderType = multi.ItemBidOrder
+		if order.SaleKind == FixForCollection {
+			orderType = multi.CollectionBidOrder
+		}
+	}
+	newOrder := model.Order{
+		Order: multi.Order{
+			CollectionAddress: order.Nft.Collection.String(),
+			MarketplaceId:     multi.MarketOrderBook,
+			TokenId:           order.Nft.TokenId.String(),
+			OrderID:           HexPrefix + hex.EncodeToString(orderKey[:]),
+			OrderStatus:       multi.OrderStatusActive,
+			EventTime:         int64(blockTime),
+			ExpireTime:        int64(order.Expiry),
+			CurrencyAddress:   s.currencies.Eth(),
+			Price:             decimal.NewFromBigInt(order.Price, 0),
+			Maker:             order.Maker.String(),
+			Taker:             ZeroAddress,
+			QuantityRemaining: remaining,
+			Size:              order.Nft.Amount.Int64(),
+			OrderType:         orderType,
+			Salt:              int64(order.Salt),
+		},
+	}
+	return s.db.WithContext(s.ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(multi.OrderTableName(s.chain)).Clauses(clause.OnConflict{
+			DoNothing: true,
+		}).Create(&newOrder).Error; err != nil {
+			return errors.Wrap(err, "failed on create snapshot order")
+		}
+		if orderType == multi.CollectionBidOrder {
+			return nil
+		}
+		// 与同步时一致，买单不确定 item 的持有人
+		var owner string
+		if orderType == multi.ListingOrder {
+			owner = order.Maker.String()
+		}
+		_, err := s.ensureItem(tx, newOrder.CollectionAddress, newOrder.TokenId, owner)
+		return err
+	})
+}
+
+// callContract 在指定区块调用订单簿合约的只读方法并解析结果到 result
+func (s *Service) callContract(contractAbi abi.ABI, contract common.Address, blockNumber *big.Int, result interface{}, method string, args ...interface{}) error {
+	data, err := contractAbi.Pack(method, args...)
+	if err != nil {
+		return errors.Wrapf(err, "failed on pack %s", method)
+	}
+	out, err := s.chainClient.CallContract(s.ctx, ethereum.CallMsg{To: &contract, Data: data}, blockNumber)
+	if err != nil {
+		return errors.Wrapf(err, "failed on call %s", method)
+	}
+	if err := contractAbi.UnpackIntoInterface(result, method, out); err != nil {
+		return errors.Wrapf(err, "failed on unpack %s", method)
+	}
+	return nil
+}
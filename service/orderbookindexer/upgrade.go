@@ -0,0 +1,172 @@
+package orderbookindexer
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+
+	"github.com/yaoxc/EasySwapSync/model"
+)
+
+const (
+	UpgradedEvent               = "Upgraded"
+	ImplementationCheckInterval = 600 // in seconds
+)
+
+// upgradedAbi EIP-1967 代理合约的 Upgraded 事件
+const upgradedAbi = `[{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"implementation","type":"address"}],"name":"Upgraded","type":"event"}]`
+
+// ImplementationSlot EIP-1967 实现合约地址的存储槽 bytes32(uint256(keccak256('eip1967.proxy.implementation')) - 1)
+var ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+
+// ErrIndexingPaused 合约升级后的事件签名与已配置的 ABI 不一致，同步暂停在升级区块，等待配置新版本 ABI
+var ErrIndexingPaused = errors.New("orderbook indexing paused")
+
+// ErrRetryLater 处理日志依赖的链上查询暂时失败，同步停在该日志所在区块，稍后从该区块重新处理
+var ErrRetryLater = errors.New("orderbook indexing retry later")
+
+// registerUpgradeHandler 登记代理合约 Upgraded 事件的处理器
+func (s *Service) registerUpgradeHandler(address common.Address) error {
+	proxyAbi, err := abi.JSON(strings.NewReader(upgradedAbi))
+	if err != nil {
+		return errors.Wrap(err, "failed on parse upgraded abi")
+	}
+	return s.RegisterHandler(address, proxyAbi, UpgradedEvent, NewEventHandler(UpgradedEvent, s.handleUpgradedEvent))
+}
+
+// handleUpgradedEvent 记录实现合约升级，新实现的事件签名与该区块生效的 ABI 不一致时返回 ErrIndexingPaused，
+// 读取实现合约字节码失败时无法判断，返回 ErrRetryLater，不能当作兼容继续同步
+func (s *Service) handleUpgradedEvent(log ethereumTypes.Log) error {
+	if len(log.Topics) < 2 {
+		return errors.New("invalid upgraded log")
+	}
+	implementation := common.BytesToAddress(log.Topics[1].Bytes())
+	contractAbi := s.abis.Lookup(log.Address, log.BlockNumber)
+
+	// 重放时不访问链，沿用同步时的判断
+	compatible := true
+	if s.chainClient != nil {
+		missing, err := s.missingEvents(implementation, contractAbi)
+		if err != nil {
+			return errors.Wrapf(ErrRetryLater, "failed on check implementation %s events: %v", implementation.String(), err)
+		}
+		compatible = len(missing) == 0
+		if !compatible {
+			xzap.WithContext(s.ctx).Error("ALERT: dex contract upgraded to implementation with unknown event layout, indexing paused",
+				zap.String("contract_address", log.Address.String()),
+				zap.String("implementation", implementation.String()),
+				zap.Uint64("block_number", log.BlockNumber),
+				zap.String("abi_version", contractAbi.Version),
+				zap.Strings("missing_events", missing),
+				zap.String("action", "configure contract_cfg.abis for the new implementation and restart"))
+		}
+	}
+
+	upgrade := model.ContractUpgrade{
+		ContractAddress: strings.ToLower(log.Address.String()),
+		Implementation:  strings.ToLower(implementation.String()),
+		BlockNumber:     int64(log.BlockNumber),
+		TxHash:          log.TxHash.String(),
+		LogIndex:        int64(log.Index),
+		AbiVersion:      contractAbi.Version,
+		Compatible:      compatible,
+	}
+	if err := s.db.WithContext(s.ctx).Table(model.ContractUpgradeTableName(s.chain)).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tx_hash"}, {Name: "log_index"}},
+		DoUpdates: clause.AssignmentColumns([]string{"abi_version", "compatible", "update_time"}),
+	}).Create(&upgrade).Error; err != nil {
+		return errors.Wrap(err, "failed on record contract upgrade")
+	}
+
+	if !compatible {
+		return errors.Wrapf(ErrIndexingPaused, "implementation %s at block %d", implementation.String(), log.BlockNumber)
+	}
+	xzap.WithContext(s.ctx).Info("dex contract upgraded",
+		zap.String("implementation", implementation.String()),
+		zap.Uint64("block_number", log.BlockNumber),
+		zap.String("abi_version", contractAbi.Version))
+	return nil
+}
+
+// missingEvents 返回 ABI 中订单簿事件的 topic 没有出现在实现合约字节码里的事件名。
+// solidity 把事件 topic 以 PUSH32 常量写入字节码，据此判断新实现的事件签名是否变化
+func (s *Service) missingEvents(implementation common.Address, contractAbi ContractAbi) ([]string, error) {
+	reader, ok := s.chainClient.Client().(ethereum.ChainStateReader)
+	if !ok {
+		return nil, errors.New("chain client does not support state reading")
+	}
+	code, err := reader.CodeAt(s.ctx, implementation, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on get implementation code")
+	}
+	if len(code) == 0 {
+		return nil, errors.Errorf("no code at implementation %s", implementation.String())
+	}
+	return MissingEvents(code, contractAbi.Abi, []string{LogMakeEvent, LogCancelEvent, LogMatchEvent})
+}
+
+// MissingEvents 返回 topic 不在字节码中的事件名
+func MissingEvents(code []byte, contractAbi abi.ABI, eventNames []string) ([]string, error) {
+	missing := make([]string, 0)
+	for _, eventName := range eventNames {
+		topic, err := EventTopic(contractAbi, eventName)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Contains(code, topic.Bytes()) {
+			missing = append(missing, eventName)
+		}
+	}
+	return missing, nil
+}
+
+// checkImplementation 读取 DexAddress 的 EIP-1967 实现槽，链头的实现与当前 ABI 不一致时提前告警，
+// 同步追到升级区块时才会真正暂停
+func (s *Service) checkImplementation(currentBlockNum uint64) {
+	if s.cfg == nil || time.Since(s.lastImplementationCheck) < ImplementationCheckInterval*time.Second {
+		return
+	}
+	s.lastImplementationCheck = time.Now()
+
+	reader, ok := s.chainClient.Client().(ethereum.ChainStateReader)
+	if !ok {
+		return
+	}
+	dex := common.HexToAddress(s.cfg.ContractCfg.DexAddress)
+	slot, err := reader.StorageAt(s.ctx, dex, ImplementationSlot, nil)
+	if err != nil {
+		xzap.WithContext(s.ctx).Warn("failed on read implementation slot", zap.Error(err))
+		return
+	}
+	implementation := common.BytesToAddress(slot)
+	if implementation == (common.Address{}) {
+		return // 不是代理合约
+	}
+	if implementation == s.implementation {
+		return
+	}
+
+	contractAbi := s.abis.Lookup(dex, currentBlockNum)
+	missing, err := s.missingEvents(implementation, contractAbi)
+	if err != nil {
+		xzap.WithContext(s.ctx).Warn("failed on check implementation events", zap.Error(err))
+		return
+	}
+	s.implementation = implementation
+	if len(missing) > 0 {
+		xzap.WithContext(s.ctx).Error("ALERT: dex implementation at chain head does not match configured abi",
+			zap.String("implementation", implementation.String()),
+			zap.Uint64("block_number", currentBlockNum),
+			zap.String("abi_version", contractAbi.Version),
+			zap.Strings("missing_events", missing))
+	}
+}
@@ -0,0 +1,132 @@
+package orderbookindexer
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/chain/chainclient"
+
+	"github.com/yaoxc/EasySwapSync/model"
+)
+
+func TestUpgradedTopic(t *testing.T) {
+	proxyAbi, err := abi.JSON(strings.NewReader(upgradedAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic, err := EventTopic(proxyAbi, UpgradedEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topic != common.HexToHash("0xbc7cd75a20ee27fd9adebab32041f755214dbc6bffa90cc0225b39da2e5c2d3b") {
+		t.Fatalf("unexpected upgraded topic %s", topic.Hex())
+	}
+}
+
+func TestMissingEvents(t *testing.T) {
+	parsedAbi, err := abi.JSON(strings.NewReader(contractAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventNames := []string{LogMakeEvent, LogCancelEvent, LogMatchEvent}
+
+	// PUSH32 <topic> ... LOG4
+	var code []byte
+	for _, eventName := range eventNames[:2] {
+		code = append(code, 0x7f)
+		code = append(code, parsedAbi.Events[eventName].ID.Bytes()...)
+		code = append(code, 0xa4)
+	}
+
+	missing, err := MissingEvents(code, parsedAbi, eventNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(missing, []string{LogMatchEvent}) {
+		t.Fatalf("unexpected missing events %v", missing)
+	}
+
+	if _, err := MissingEvents(code, parsedAbi, []string{"LogUnknown"}); err == nil {
+		t.Fatal("expected error for unknown event")
+	}
+}
+
+// fakeChainClient 只实现读取合约字节码，其余方法未实现
+type fakeChainClient struct {
+	chainclient.ChainClient
+	ethereum.ChainStateReader
+	code []byte
+	err  error
+}
+
+func (c *fakeChainClient) Client() interface{} {
+	return c
+}
+
+func (c *fakeChainClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return c.code, c.err
+}
+
+func TestHandleUpgradedEvent(t *testing.T) {
+	parsedAbi, err := abi.JSON(strings.NewReader(contractAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyAbi, err := abi.JSON(strings.NewReader(upgradedAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var code []byte
+	for _, eventName := range []string{LogMakeEvent, LogCancelEvent, LogMatchEvent} {
+		code = append(code, 0x7f)
+		code = append(code, parsedAbi.Events[eventName].ID.Bytes()...)
+	}
+	log := ethereumTypes.Log{
+		Address:     common.HexToAddress(testDexAddress),
+		Topics:      []common.Hash{proxyAbi.Events[UpgradedEvent].ID, common.HexToHash("0x1234")},
+		BlockNumber: 100,
+		TxHash:      common.HexToHash("0xaa"),
+	}
+
+	s := newTestService(t)
+	client := &fakeChainClient{err: errors.New("connection refused")}
+	s.chainClient = client
+
+	// 读取字节码失败时不能当作兼容继续同步，handleLog 需返回错误让同步停在该区块重试
+	err = s.handleLog(log)
+	if !errors.Is(err, ErrRetryLater) {
+		t.Fatalf("expected retry later, got %v", err)
+	}
+	var count int64
+	if err := s.db.Table(model.ContractUpgradeTableName(testChain)).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no upgrade recorded before check succeeds, got %d", count)
+	}
+
+	client.code, client.err = code, nil
+	if err := s.handleLog(log); err != nil {
+		t.Fatal(err)
+	}
+	var upgrade model.ContractUpgrade
+	if err := s.db.Table(model.ContractUpgradeTableName(testChain)).First(&upgrade).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !upgrade.Compatible {
+		t.Error("expected compatible upgrade after retry")
+	}
+
+	client.code = code[:33]
+	if err := s.handleLog(log); !errors.Is(err, ErrIndexingPaused) {
+		t.Fatalf("expected indexing paused for incompatible implementation, got %v", err)
+	}
+}
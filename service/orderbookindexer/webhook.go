@@ -0,0 +1,37 @@
+package orderbookindexer
+
+import (
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"gorm.io/gorm"
+
+	"github.com/yaoxc/EasySwapSync/service/webhook"
+)
+
+// SetWebhooks 设置 webhook 推送，未设置时不推送
+func (s *Service) SetWebhooks(webhooks *webhook.Dispatcher) {
+	s.webhooks = webhooks
+}
+
+// publishWebhook 在处理器写入订单、成交的事务 tx 中把活动对应的事件写入 webhook 推送队列。
+// 写入失败时返回 ErrRetryLater，事务回滚，同步停在该区块稍后重新处理，事件不会丢失
+func (s *Service) publishWebhook(tx *gorm.DB, eventType string, orderId string, activity multi.Activity, logIndex uint) error {
+	event := webhook.Event{
+		Id:                webhook.EventId(activity.TxHash, logIndex),
+		Type:              eventType,
+		CollectionAddress: activity.CollectionAddress,
+		TokenId:           activity.TokenId,
+		OrderId:           orderId,
+		Maker:             activity.Maker,
+		Taker:             activity.Taker,
+		CurrencyAddress:   activity.CurrencyAddress,
+		Price:             activity.Price,
+		BlockNumber:       uint64(activity.BlockNumber),
+		TxHash:            activity.TxHash,
+		EventTime:         activity.EventTime,
+	}
+	if err := s.webhooks.Publish(tx, event); err != nil {
+		return errors.Wrapf(ErrRetryLater, "failed on publish %s webhook event for order %s: %v", eventType, orderId, err)
+	}
+	return nil
+}
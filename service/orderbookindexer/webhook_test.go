@@ -0,0 +1,92 @@
+package orderbookindexer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"go.uber.org/zap"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/webhook"
+)
+
+func TestHandleMakeEventWebhookInTransaction(t *testing.T) {
+	s := newTestService(t)
+	if err := s.db.Exec(fmt.Sprintf(`CREATE TABLE %s (id integer primary key autoincrement, name text, url text, secret text,
+collections text, event_types text, min_price text, create_time integer, update_time integer)`,
+		model.WebhookSubscriberTableName(testChain))).Error; err != nil {
+		t.Fatal(err)
+	}
+	ctx := xzap.ToContext(context.Background(), zap.NewNop())
+	webhooks := webhook.New(ctx, config.WebhookCfg{Enable: true}, s.db, testChain, 11155111, s.currencies, nil)
+	if _, err := webhooks.CreateSubscriber(webhook.Subscriber{Name: "partner", Url: "https://example.com/hook"}); err != nil {
+		t.Fatal(err)
+	}
+	s.SetWebhooks(webhooks)
+
+	parsedAbi, err := abi.JSON(strings.NewReader(contractAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orderKey := common.HexToHash("0x01")
+	seller := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	nft := testAsset{TokenId: big.NewInt(7), Collection: common.HexToAddress("0x3333333333333333333333333333333333333333"), Amount: big.NewInt(1)}
+	s.blockTimes[1] = 1700000000
+	log := ethereumTypes.Log{
+		Address: common.HexToAddress(testDexAddress),
+		Topics: []common.Hash{
+			parsedAbi.Events[LogMakeEvent].ID,
+			common.BigToHash(big.NewInt(List)),
+			common.BigToHash(big.NewInt(FixForItem)),
+			common.BytesToHash(seller.Bytes()),
+		},
+		Data:        packEvent(t, parsedAbi, LogMakeEvent, orderKey, nft, big.NewInt(1e18), uint64(4102444800), uint64(1)),
+		BlockNumber: 1,
+		TxHash:      common.HexToHash("0xaa"),
+	}
+	countOrders := func() int64 {
+		var count int64
+		if err := s.db.Table(multi.OrderTableName(testChain)).Where("order_id = ?", orderKey.Hex()).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	// 推送队列表不可写时订单写入一起回滚，同步停在该区块重试
+	if err := s.handleLog(log); !errors.Is(err, ErrRetryLater) {
+		t.Fatalf("expected ErrRetryLater, got %v", err)
+	}
+	if count := countOrders(); count != 0 {
+		t.Fatalf("expected order rolled back, got %d", count)
+	}
+
+	if err := s.db.Exec(fmt.Sprintf(`CREATE TABLE %s (id integer primary key autoincrement, subscriber_id integer, event_id text,
+event_type text, payload text, attempts integer default 0, next_attempt_time integer, last_error text default '',
+create_time integer, update_time integer, unique (subscriber_id, event_id))`,
+		model.WebhookDeliveryTableName(testChain))).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.handleLog(log); err != nil {
+		t.Fatal(err)
+	}
+	if count := countOrders(); count != 1 {
+		t.Fatalf("expected order created on retry, got %d", count)
+	}
+	var deliveries int64
+	if err := s.db.Table(model.WebhookDeliveryTableName(testChain)).Count(&deliveries).Error; err != nil {
+		t.Fatal(err)
+	}
+	if deliveries != 1 {
+		t.Fatalf("expected 1 delivery, got %d", deliveries)
+	}
+}
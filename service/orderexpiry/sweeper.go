@@ -0,0 +1,194 @@
+package orderexpiry
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/ordermanager"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/yaoxc/EasySwapBase/stores/xkv"
+	"github.com/zeromicro/go-zero/core/threading"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/comm"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+	"github.com/yaoxc/EasySwapSync/service/itemprice"
+)
+
+const (
+	SweepInterval = 10 // in seconds
+	zeroAddress   = "0x0000000000000000000000000000000000000000"
+)
+
+// Sweeper 定时把已过期但仍为 Active 的订单改为 Expired，
+// 写入对应的过期 activity，刷新 item 的 list_price，并推送价格更新事件让地板价及时变化
+type Sweeper struct {
+	ctx     context.Context
+	db      *gorm.DB
+	kv      *xkv.Store
+	chain   string
+	project string
+
+	currencies *currency.Registry // item 的 list_price 按折合 ETH 的最低价计算
+}
+
+func New(ctx context.Context, db *gorm.DB, kv *xkv.Store, chain string, project string, currencies *currency.Registry) *Sweeper {
+	return &Sweeper{
+		ctx:        ctx,
+		db:         db,
+		kv:         kv,
+		chain:      chain,
+		project:    project,
+		currencies: currencies,
+	}
+}
+
+func (s *Sweeper) Start() {
+	threading.GoSafe(s.sweepLoop)
+}
+
+func (s *Sweeper) sweepLoop() {
+	timer := time.NewTicker(SweepInterval * time.Second)
+	defer timer.Stop()
+
+	// 订单也可能被外部 ordermanager 先改为 Expired，按过期时间窗口补刷 item 的 list_price
+	lastSweep := time.Now().Unix()
+	for {
+		select {
+		case <-s.ctx.Done():
+			xzap.WithContext(s.ctx).Info("order expiry sweepLoop stopped due to context cancellation")
+			return
+		case <-timer.C:
+			now := time.Now().Unix()
+			if _, err := s.Sweep(now); err != nil {
+				xzap.WithContext(s.ctx).Error("failed on sweep expired orders", zap.Error(err))
+				continue
+			}
+			if _, err := itemprice.RefreshExpiredListings(s.ctx, s.db, s.project, s.chain, s.currencies, lastSweep, now); err != nil {
+				xzap.WithContext(s.ctx).Error("failed on refresh expired listings", zap.Error(err))
+				continue
+			}
+			lastSweep = now
+		}
+	}
+}
+
+// Sweep 按 comm.DBBatchSizeLimit 分批处理 expire_time <= now 的 Active 订单，返回处理的订单数量。
+// expire_time 为 0 的订单永不过期
+func (s *Sweeper) Sweep(now int64) (int, error) {
+	var total int
+	for {
+		var orders []multi.Order
+		if err := s.db.WithContext(s.ctx).Table(multi.OrderTableName(s.chain)).
+			Select("id, order_id, order_type, collection_address, token_id, currency_address, price, maker").
+			Where("order_status = ? and expire_time > 0 and expire_time <= ?", multi.OrderStatusActive, now).
+			Order("id").
+			Limit(comm.DBBatchSizeLimit).
+			Scan(&orders).Error; err != nil {
+			return total, errors.Wrap(err, "failed on query expired orders")
+		}
+		if len(orders) == 0 {
+			return total, nil
+		}
+
+		expired, err := s.expireBatch(orders, now)
+		if err != nil {
+			return total, err
+		}
+		total += len(expired)
+
+		for _, order := range expired {
+			if err := ordermanager.AddUpdatePriceEvent(s.kv, &ordermanager.TradeEvent{
+				OrderId:        order.OrderID,
+				CollectionAddr: order.CollectionAddress,
+				TokenID:        order.TokenId,
+				EventType:      ordermanager.Expired,
+			}, s.chain); err != nil {
+				xzap.WithContext(s.ctx).Error("failed on add update price event",
+					zap.Error(err),
+					zap.String("type", "expired"),
+					zap.String("order_id", order.OrderID))
+			}
+		}
+
+		if len(orders) < comm.DBBatchSizeLimit {
+			return total, nil
+		}
+	}
+}
+
+// expireBatch 在一个事务中更新订单状态、写入过期 activity 并刷新 item 的 list_price，
+// 返回本次真正由 Active 改为 Expired 的订单
+func (s *Sweeper) expireBatch(orders []multi.Order, now int64) ([]multi.Order, error) {
+	expired := make([]multi.Order, 0, len(orders))
+	if err := s.db.WithContext(s.ctx).Transaction(func(tx *gorm.DB) error {
+		expired = expired[:0]
+		for _, order := range orders {
+			// 带上状态条件，避免覆盖同时被成交或取消的订单
+			result := tx.Table(multi.OrderTableName(s.chain)).
+				Where("id = ? and order_status = ?", order.ID, multi.OrderStatusActive).
+				Update("order_status", multi.OrderStatusExpired)
+			if result.Error != nil {
+				return errors.Wrap(result.Error, "failed on update expired order status")
+			}
+			if result.RowsAffected > 0 {
+				expired = append(expired, order)
+			}
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+
+		activities := make([]model.Activity, 0, len(expired))
+		for _, order := range expired {
+			activities = append(activities, ExpiredActivity(order, now))
+		}
+		if err := tx.Table(multi.ActivityTableName(s.chain)).Clauses(clause.OnConflict{
+			DoNothing: true,
+		}).Create(&activities).Error; err != nil {
+			return errors.Wrap(err, "failed on create expired activities")
+		}
+
+		for _, order := range expired {
+			if order.OrderType != multi.ListingOrder {
+				continue
+			}
+			if err := itemprice.RefreshListing(tx, s.project, s.chain, s.currencies, order.CollectionAddress, order.TokenId); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return expired, nil
+}
+
+// ExpiredActivity 构造订单过期的 activity。过期没有链上交易，tx_hash 记录订单ID，
+// 保证同一订单只会写入一条过期记录
+func ExpiredActivity(order multi.Order, eventTime int64) model.Activity {
+	activityType := comm.ActivityOfferExpired
+	if order.OrderType == multi.ListingOrder {
+		activityType = comm.ActivityListingExpired
+	}
+	return model.Activity{
+		Activity: multi.Activity{
+			ActivityType:      activityType,
+			Maker:             order.Maker,
+			Taker:             zeroAddress,
+			MarketplaceID:     multi.MarketOrderBook,
+			CollectionAddress: order.CollectionAddress,
+			TokenId:           order.TokenId,
+			CurrencyAddress:   order.CurrencyAddress,
+			Price:             order.Price,
+			TxHash:            order.OrderID,
+			EventTime:         eventTime,
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package orderexpiry
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+
+	"github.com/yaoxc/EasySwapSync/service/comm"
+)
+
+func TestExpiredActivity(t *testing.T) {
+	order := multi.Order{
+		OrderID:           "0x8f2c33b5a7d6e8f1c4b3a2918273645546372819aabbccddeeff001122334455",
+		OrderType:         multi.ListingOrder,
+		CollectionAddress: "0x4a7d9f1c2b3e4f5a6b7c8d9e0f1a2b3c4d5e6f70",
+		TokenId:           "42",
+		Price:             decimal.NewFromInt(1000),
+		CurrencyAddress:   "0x0000000000000000000000000000000000000000",
+		Maker:             "0x1111111111111111111111111111111111111111",
+	}
+
+	activity := ExpiredActivity(order, 1700000000)
+	if activity.ActivityType != comm.ActivityListingExpired {
+		t.Fatalf("unexpected activity type %d", activity.ActivityType)
+	}
+	if activity.TxHash != order.OrderID || activity.CurrencyAddress != order.CurrencyAddress || activity.EventTime != 1700000000 || !activity.Price.Equal(order.Price) {
+		t.Fatalf("unexpected activity %+v", activity)
+	}
+
+	for _, orderType := range []int64{multi.OfferOrder, multi.CollectionBidOrder, multi.ItemBidOrder} {
+		order.OrderType = orderType
+		if activity := ExpiredActivity(order, 1700000000); activity.ActivityType != comm.ActivityOfferExpired {
+			t.Fatalf("order type %d: unexpected activity type %d", orderType, activity.ActivityType)
+		}
+	}
+
+	// 过期类型不能与 multi 中的链上活动类型重复
+	for _, activityType := range []int{multi.Buy, multi.Mint, multi.Listing, multi.CancelListing, multi.CancelOffer,
+		multi.MakeOffer, multi.Sale, multi.Transfer, multi.CollectionBid, multi.ItemBid, multi.CancelCollectionBid, multi.CancelItemBid} {
+		if activityType == comm.ActivityListingExpired || activityType == comm.ActivityOfferExpired {
+			t.Fatalf("expired activity type collides with multi activity type %d", activityType)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package pricing
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/stores/gdb"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/zeromicro/go-zero/core/threading"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/comm"
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+)
+
+const (
+	defaultSyncInterval = 3600      // in seconds
+	defaultMaxPriceAge  = 3600 * 24 // in seconds
+	usdPrecision        = 10
+)
+
+type pricedRow struct {
+	Id              int64
+	CurrencyAddress string
+	Price           decimal.Decimal
+	EventTime       int64
+}
+
+// Pricer 从价格数据源同步代币美元价格历史，并给 Sale 活动和 collection 地板价记录补上事件发生时的美元价值
+type Pricer struct {
+	ctx        context.Context
+	cfg        config.PricingCfg
+	db         *gorm.DB
+	chain      string
+	project    string
+	currencies *currency.Registry
+	source     Source
+	watermarks map[string]int64 // 每张表已处理完的最大 id，之前的记录不再重复扫描
+}
+
+func New(ctx context.Context, cfg config.PricingCfg, db *gorm.DB, chain string, project string, currencies *currency.Registry) (*Pricer, error) {
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = defaultSyncInterval
+	}
+	if cfg.MaxPriceAge <= 0 {
+		cfg.MaxPriceAge = defaultMaxPriceAge
+	}
+	source, err := NewSource(cfg.Source, cfg.CsvPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pricer{
+		ctx:        ctx,
+		cfg:        cfg,
+		db:         db,
+		chain:      chain,
+		project:    project,
+		currencies: currencies,
+		source:     source,
+		watermarks: make(map[string]int64),
+	}, nil
+}
+
+func (p *Pricer) Start() {
+	threading.GoSafe(p.syncLoop)
+}
+
+func (p *Pricer) syncLoop() {
+	timer := time.NewTicker(time.Duration(p.cfg.SyncInterval) * time.Second)
+	defer timer.Stop()
+
+	for {
+		p.refresh()
+		select {
+		case <-p.ctx.Done():
+			xzap.WithContext(p.ctx).Info("pricing syncLoop stopped due to context cancellation")
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *Pricer) refresh() {
+	if err := p.SyncPrices(); err != nil {
+		xzap.WithContext(p.ctx).Error("failed on sync price history", zap.Error(err))
+	}
+	if err := p.EnrichSales(); err != nil {
+		xzap.WithContext(p.ctx).Error("failed on enrich sale usd value", zap.Error(err))
+	}
+	if err := p.EnrichFloorPrices(); err != nil {
+		xzap.WithContext(p.ctx).Error("failed on enrich floor price usd value", zap.Error(err))
+	}
+}
+
+// SyncPrices 从数据源拉取价格点写入价格历史表，每个代币从它自己最后一个已保存的价格点之后开始，
+// 后加入或落后于其他代币的价格也能补上更早的数据
+func (p *Pricer) SyncPrices() error {
+	lastTimes, err := p.lastPriceTimes()
+	if err != nil {
+		return err
+	}
+
+	symbols := make([]string, 0)
+	for _, c := range p.currencies.Currencies() {
+		symbols = append(symbols, priceSymbol(c.Symbol))
+	}
+	points, err := p.source.Fetch(p.ctx, syncSince(symbols, lastTimes), time.Now().Unix())
+	if err != nil {
+		return errors.Wrap(err, "failed on fetch prices")
+	}
+	points = newPoints(points, lastTimes)
+	if len(points) == 0 {
+		return nil
+	}
+
+	histories := make([]model.PriceHistory, 0, len(points))
+	for _, point := range points {
+		histories = append(histories, model.PriceHistory{
+			Symbol:    point.Symbol,
+			PriceTime: point.Timestamp,
+			PriceUsd:  point.PriceUsd,
+		})
+	}
+	if err := p.db.WithContext(p.ctx).Table(model.PriceHistoryTableName()).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "symbol"}, {Name: "price_time"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price_usd", "update_time"}),
+	}).CreateInBatches(histories, comm.DBBatchSizeLimit).Error; err != nil {
+		return errors.Wrap(err, "failed on save price history")
+	}
+
+	xzap.WithContext(p.ctx).Info("price history synced", zap.Int("points", len(points)))
+	return nil
+}
+
+// EnrichSales 给尚未有美元价值的 Sale 活动补上成交时的美元价值
+func (p *Pricer) EnrichSales() error {
+	return p.enrich(multi.ActivityTableName(p.chain), func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, currency_address, price, event_time").
+			Where("activity_type = ?", multi.Sale)
+	})
+}
+
+// EnrichFloorPrices 给尚未有美元价值的地板价记录补上美元价值，地板价已折合成 ETH(wei)
+func (p *Pricer) EnrichFloorPrices() error {
+	return p.enrich(gdb.GetMultiProjectCollectionFloorPriceTableName(p.project, p.chain), func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, ? as currency_address, price, event_time", p.currencies.Eth())
+	})
+}
+
+// enrich 按 id 顺序分批处理 price_usd 为空的记录，暂时没有对应价格的记录留到下一轮。
+// 水位之前的记录要么已补上美元价值，要么永远补不上（币种未配置或价格历史已越过事件时间仍没有价格），
+// 每轮只从水位之后开始扫描
+func (p *Pricer) enrich(table string, scope func(db *gorm.DB) *gorm.DB) error {
+	lastTimes, err := p.lastPriceTimes()
+	if err != nil {
+		return err
+	}
+	lastId := p.watermarks[table]
+	watermark := lastId
+	var waiting bool
+	var enriched int
+	for {
+		var rows []pricedRow
+		if err := scope(p.db.WithContext(p.ctx).Table(table)).
+			Where("price_usd is null and id > ?", lastId).
+			Order("id").
+			Limit(comm.DBBatchSizeLimit).
+			Scan(&rows).Error; err != nil {
+			return errors.Wrapf(err, "failed on query %s rows without usd value", table)
+		}
+
+		for _, row := range rows {
+			value, ok, err := p.usdValue(row.CurrencyAddress, row.Price, row.EventTime)
+			if err != nil {
+				return err
+			}
+			if ok {
+				if err := p.db.WithContext(p.ctx).Table(table).
+					Where("id = ?", row.Id).
+					Update("price_usd", value).Error; err != nil {
+					return errors.Wrapf(err, "failed on update %s usd value", table)
+				}
+				enriched++
+			} else if !waiting {
+				waiting = p.awaitingPrice(row, lastTimes)
+			}
+			// 水位停在第一条还在等价格的记录之前
+			if !waiting {
+				watermark = row.Id
+			}
+		}
+
+		if len(rows) < comm.DBBatchSizeLimit {
+			break
+		}
+		lastId = rows[len(rows)-1].Id
+	}
+	p.watermarks[table] = watermark
+
+	if enriched > 0 {
+		xzap.WithContext(p.ctx).Info("usd value enriched", zap.String("table", table), zap.Int("rows", enriched))
+	}
+	return nil
+}
+
+// awaitingPrice 记录的币种已配置，且价格历史还没有同步到事件时间，之后的同步可能补上价格
+func (p *Pricer) awaitingPrice(row pricedRow, lastTimes map[string]int64) bool {
+	c, ok := p.currencies.Get(row.CurrencyAddress)
+	if !ok {
+		return false
+	}
+	return row.EventTime > lastTimes[priceSymbol(c.Symbol)]
+}
+
+// lastPriceTimes 查询各代币已保存的最后价格时间
+func (p *Pricer) lastPriceTimes() (map[string]int64, error) {
+	var lastPoints []struct {
+		Symbol    string
+		PriceTime int64
+	}
+	if err := p.db.WithContext(p.ctx).Table(model.PriceHistoryTableName()).
+		Select("symbol, max(price_time) as price_time").
+		Group("symbol").
+		Scan(&lastPoints).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query last price time")
+	}
+	lastTimes := make(map[string]int64, len(lastPoints))
+	for _, point := range lastPoints {
+		lastTimes[strings.ToUpper(point.Symbol)] = point.PriceTime
+	}
+	return lastTimes, nil
+}
+
+// usdValue 计算以 currencyAddress 计价的最小单位金额在 eventTime 时的美元价值，
+// 未配置的币种或 eventTime 前 MaxPriceAge 内没有价格时返回 false
+func (p *Pricer) usdValue(currencyAddress string, amount decimal.Decimal, eventTime int64) (decimal.Decimal, bool, error) {
+	c, ok := p.currencies.Get(currencyAddress)
+	if !ok {
+		return decimal.Zero, false, nil
+	}
+
+	var histories []model.PriceHistory
+	if err := p.db.WithContext(p.ctx).Table(model.PriceHistoryTableName()).
+		Where("symbol = ? and price_time <= ? and price_time > ?", priceSymbol(c.Symbol), eventTime, eventTime-p.cfg.MaxPriceAge).
+		Order("price_time desc").
+		Limit(1).
+		Find(&histories).Error; err != nil {
+		return decimal.Zero, false, errors.Wrap(err, "failed on query price history")
+	}
+	if len(histories) == 0 {
+		return decimal.Zero, false, nil
+	}
+
+	return UsdValue(amount, c.Decimals, histories[0].PriceUsd), true, nil
+}
+
+// syncSince 拉取的起点取各代币最后价格时间的最小值，还没有价格的代币从头拉取
+func syncSince(symbols []string, lastTimes map[string]int64) int64 {
+	var since int64
+	for i, symbol := range symbols {
+		lastTime := lastTimes[symbol]
+		if i == 0 || lastTime < since {
+			since = lastTime
+		}
+	}
+	return since
+}
+
+// newPoints 过滤掉各代币已保存的价格点
+func newPoints(points []Point, lastTimes map[string]int64) []Point {
+	result := make([]Point, 0, len(points))
+	for _, point := range points {
+		if point.Timestamp > lastTimes[point.Symbol] {
+			result = append(result, point)
+		}
+	}
+	return result
+}
+
+// priceSymbol WETH 与 ETH 1:1 兑换，直接使用 ETH 的价格
+func priceSymbol(symbol string) string {
+	symbol = strings.ToUpper(symbol)
+	if symbol == currency.WethSymbol {
+		return currency.EthSymbol
+	}
+	return symbol
+}
+
+// UsdValue 把最小单位金额按 decimals 换算成整币后乘以美元单价
+func UsdValue(amount decimal.Decimal, decimals int32, priceUsd decimal.Decimal) decimal.Decimal {
+	return amount.Shift(-decimals).Mul(priceUsd).Round(usdPrecision)
+}
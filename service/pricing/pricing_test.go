@@ -0,0 +1,152 @@
+package pricing
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/shopspring/decimal"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+)
+
+func TestParseCsv(t *testing.T) {
+	points, err := ParseCsv(strings.NewReader("symbol,timestamp,price_usd\neth,1700000000,2045.12\nUSDC, 1700000000, 0.9998\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("expected 2 points, got %d", len(points))
+	}
+	if points[0].Symbol != "ETH" || points[0].Timestamp != 1700000000 || points[0].PriceUsd.String() != "2045.12" {
+		t.Errorf("unexpected point %+v", points[0])
+	}
+	if points[1].Symbol != "USDC" || points[1].PriceUsd.String() != "0.9998" {
+		t.Errorf("unexpected point %+v", points[1])
+	}
+
+	if _, err := ParseCsv(strings.NewReader("ETH,1700000000,2045\nETH,abc,2046\n")); err == nil {
+		t.Error("expected error for invalid timestamp")
+	}
+}
+
+func TestUsdValue(t *testing.T) {
+	// 1.5 ETH * 2000 = 3000 USD
+	got := UsdValue(decimal.RequireFromString("1500000000000000000"), 18, decimal.NewFromInt(2000))
+	if !got.Equal(decimal.NewFromInt(3000)) {
+		t.Errorf("unexpected usd value %s", got)
+	}
+	// 2500 USDC * 0.9998
+	got = UsdValue(decimal.RequireFromString("2500000000"), 6, decimal.RequireFromString("0.9998"))
+	if !got.Equal(decimal.RequireFromString("2499.5")) {
+		t.Errorf("unexpected usd value %s", got)
+	}
+}
+
+func TestPriceSymbol(t *testing.T) {
+	if priceSymbol("weth") != "ETH" || priceSymbol("USDC") != "USDC" {
+		t.Error("unexpected price symbol")
+	}
+}
+
+func TestSyncSince(t *testing.T) {
+	lastTimes := map[string]int64{"ETH": 1700000300, "USDC": 1700000100}
+	if since := syncSince([]string{"ETH", "USDC"}, lastTimes); since != 1700000100 {
+		t.Errorf("expected lagging symbol to set since, got %d", since)
+	}
+	// 新加入的代币还没有价格，从头拉取
+	if since := syncSince([]string{"ETH", "USDC", "DAI"}, lastTimes); since != 0 {
+		t.Errorf("expected new symbol to fetch from 0, got %d", since)
+	}
+
+	points := newPoints([]Point{
+		{Symbol: "ETH", Timestamp: 1700000200},
+		{Symbol: "USDC", Timestamp: 1700000200},
+		{Symbol: "DAI", Timestamp: 1700000000},
+	}, lastTimes)
+	if len(points) != 2 || points[0].Symbol != "USDC" || points[1].Symbol != "DAI" {
+		t.Errorf("unexpected new points %+v", points)
+	}
+}
+
+func TestEnrichWatermark(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	const table = "ob_activity_sepolia"
+	for _, ddl := range []string{
+		"CREATE TABLE ob_price_history (id integer primary key autoincrement, symbol text, price_time integer, price_usd text, create_time integer, update_time integer)",
+		"CREATE TABLE " + table + " (id integer primary key, activity_type integer, currency_address text, price text, event_time integer, price_usd text)",
+	} {
+		if err := db.Exec(ddl).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	eth := "0x0000000000000000000000000000000000000000"
+	currencies, err := currency.New(config.ContractCfg{EthAddress: eth}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Pricer{
+		ctx:        xzap.ToContext(context.Background(), zap.NewNop()),
+		cfg:        config.PricingCfg{MaxPriceAge: defaultMaxPriceAge},
+		db:         db,
+		currencies: currencies,
+		watermarks: make(map[string]int64),
+	}
+	scope := func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, currency_address, price, event_time")
+	}
+	addPrice := func(priceTime int64) {
+		if err := db.Exec("INSERT INTO ob_price_history (symbol, price_time, price_usd) VALUES ('ETH', ?, '2000')", priceTime).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	addPrice(1000)
+	// 1 可以定价；2 币种未配置，永远补不上；3 价格历史还没同步到事件时间；4 可以定价但排在等待的记录之后
+	for id, row := range [][]interface{}{
+		{eth, 1000},
+		{"0x1111111111111111111111111111111111111111", 1000},
+		{eth, 200000},
+		{eth, 1000},
+	} {
+		if err := db.Exec("INSERT INTO "+table+" (id, currency_address, price, event_time) VALUES (?, ?, '1000000000000000000', ?)", id+1, row[0], row[1]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := p.enrich(table, scope); err != nil {
+		t.Fatal(err)
+	}
+	if watermark := p.watermarks[table]; watermark != 2 {
+		t.Fatalf("expected watermark to stop before the row waiting for price, got %d", watermark)
+	}
+
+	addPrice(200000)
+	if err := p.enrich(table, scope); err != nil {
+		t.Fatal(err)
+	}
+	if watermark := p.watermarks[table]; watermark != 3 {
+		t.Fatalf("expected watermark to move past settled rows, got %d", watermark)
+	}
+	var priced int64
+	if err := db.Table(table).Where("price_usd is not null").Count(&priced).Error; err != nil {
+		t.Fatal(err)
+	}
+	if priced != 3 {
+		t.Errorf("expected 3 rows priced, got %d", priced)
+	}
+}
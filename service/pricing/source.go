@@ -0,0 +1,101 @@
+package pricing
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Point 某个代币在某一时刻的美元价格
+type Point struct {
+	Symbol    string
+	Timestamp int64 // in seconds
+	PriceUsd  decimal.Decimal
+}
+
+// Source 价格数据源，返回 (since, until] 区间内的价格点；
+// 新的数据源（如交易所或预言机 API）实现该接口后在 NewSource 中注册即可
+type Source interface {
+	Fetch(ctx context.Context, since int64, until int64) ([]Point, error)
+}
+
+const SourceCsv = "csv"
+
+func NewSource(name string, csvPath string) (Source, error) {
+	switch name {
+	case "", SourceCsv:
+		return &CsvSource{Path: csvPath}, nil
+	default:
+		return nil, errors.Errorf("unsupported price source %s", name)
+	}
+}
+
+// CsvSource 从本地 CSV 文件导入价格，离线环境可用。
+// 文件格式：symbol,timestamp,price_usd，第一行可以是表头，timestamp 为秒级时间戳；未配置路径时不导入
+type CsvSource struct {
+	Path string
+}
+
+func (c *CsvSource) Fetch(ctx context.Context, since int64, until int64) ([]Point, error) {
+	if c.Path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(c.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on open price csv")
+	}
+	defer f.Close()
+
+	points, err := ParseCsv(f)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Point, 0, len(points))
+	for _, p := range points {
+		if p.Timestamp > since && p.Timestamp <= until {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// ParseCsv 解析 symbol,timestamp,price_usd 格式的价格数据
+func ParseCsv(r io.Reader) ([]Point, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	points := make([]Point, 0)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return points, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed on read price csv")
+		}
+
+		timestamp, err := strconv.ParseInt(record[1], 10, 64)
+		if err != nil {
+			if line == 1 {
+				continue // 表头
+			}
+			return nil, errors.Wrapf(err, "invalid timestamp at line %d", line)
+		}
+		price, err := decimal.NewFromString(record[2])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid price at line %d", line)
+		}
+		points = append(points, Point{
+			Symbol:    strings.ToUpper(strings.TrimSpace(record[0])),
+			Timestamp: timestamp,
+			PriceUsd:  price,
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package rarity
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/stores/gdb"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"github.com/zeromicro/go-zero/core/threading"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/comm"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+)
+
+const (
+	RecomputeInterval = 30 // in seconds
+	scorePrecision    = 10
+)
+
+// Calculator 按 collection 重新计算 trait 频率、item 稀有度得分/排名以及 trait 地板价
+type Calculator struct {
+	ctx     context.Context
+	db      *gorm.DB
+	chain   string
+	project string
+
+	currencies *currency.Registry // trait 地板价按折合 ETH 的最低价计算
+
+	dirty map[string]bool // trait 有变化、待重算的 collection
+	lock  *sync.Mutex
+}
+
+func New(ctx context.Context, db *gorm.DB, chain string, project string, currencies *currency.Registry) *Calculator {
+	return &Calculator{
+		ctx:        ctx,
+		db:         db,
+		chain:      chain,
+		project:    project,
+		currencies: currencies,
+		dirty:      make(map[string]bool),
+		lock:       &sync.Mutex{},
+	}
+}
+
+func (c *Calculator) Start() {
+	threading.GoSafe(c.recomputeLoop)
+}
+
+// MarkDirty 标记 collection 的 trait 已变化，下一轮重算
+func (c *Calculator) MarkDirty(collectionAddress string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.dirty[strings.ToLower(collectionAddress)] = true
+}
+
+func (c *Calculator) takeDirty() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	collections := make([]string, 0, len(c.dirty))
+	for collection := range c.dirty {
+		collections = append(collections, collection)
+	}
+	c.dirty = make(map[string]bool)
+	return collections
+}
+
+func (c *Calculator) recomputeLoop() {
+	recomputeTimer := time.NewTicker(RecomputeInterval * time.Second)
+	defer recomputeTimer.Stop()
+	traitFloorTimer := time.NewTicker(comm.CollectionFloorSyncPeriod * time.Second)
+	defer traitFloorTimer.Stop()
+
+	// 启动时全部重算一次，补上停机期间写入的 trait
+	collections, err := c.traitCollections()
+	if err != nil {
+		xzap.WithContext(c.ctx).Error("failed on query trait collections", zap.Error(err))
+	}
+	for _, collection := range collections {
+		c.MarkDirty(collection)
+	}
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			xzap.WithContext(c.ctx).Info("rarity recomputeLoop stopped due to context cancellation")
+			return
+		case <-recomputeTimer.C:
+			for _, collection := range c.takeDirty() {
+				if err := c.Recompute(collection); err != nil {
+					xzap.WithContext(c.ctx).Error("failed on recompute collection rarity",
+						zap.String("collection_address", collection), zap.Error(err))
+				}
+			}
+		case <-traitFloorTimer.C:
+			collections, err := c.traitCollections()
+			if err != nil {
+				xzap.WithContext(c.ctx).Error("failed on query trait collections", zap.Error(err))
+				continue
+			}
+			for _, collection := range collections {
+				if err := c.RefreshTraitFloorPrices(collection); err != nil {
+					xzap.WithContext(c.ctx).Error("failed on refresh trait floor price",
+						zap.String("collection_address", collection), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+func (c *Calculator) traitCollections() ([]string, error) {
+	var collections []string
+	if err := c.db.WithContext(c.ctx).Table(gdb.GetMultiProjectItemTraitTableName(c.project, c.chain)).
+		Distinct("collection_address").
+		Pluck("collection_address", &collections).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query trait collections")
+	}
+	return collections, nil
+}
+
+// Recompute 重算单个 collection 的 trait 统计和 item 稀有度，并刷新 trait 地板价
+func (c *Calculator) Recompute(collectionAddress string) error {
+	var traits []multi.ItemTrait
+	if err := c.db.WithContext(c.ctx).Table(gdb.GetMultiProjectItemTraitTableName(c.project, c.chain)).
+		Select("token_id, trait, trait_value").
+		Where("collection_address = ?", collectionAddress).
+		Scan(&traits).Error; err != nil {
+		return errors.Wrap(err, "failed on query item traits")
+	}
+
+	var itemCount int64
+	if err := c.db.WithContext(c.ctx).Table(gdb.GetMultiProjectCollectionTableName(c.project, c.chain)).
+		Select("coalesce(max(item_amount), 0)").
+		Where("address = ?", collectionAddress).
+		Scan(&itemCount).Error; err != nil {
+		return errors.Wrap(err, "failed on query collection item amount")
+	}
+
+	traitStats, rarities := Calculate(collectionAddress, traits, itemCount)
+	if err := c.db.WithContext(c.ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(model.CollectionTraitTableName(c.chain)).
+			Where("collection_address = ?", collectionAddress).
+			Delete(&model.CollectionTrait{}).Error; err != nil {
+			return errors.Wrap(err, "failed on delete collection traits")
+		}
+		if err := tx.Table(model.ItemRarityTableName(c.chain)).
+			Where("collection_address = ?", collectionAddress).
+			Delete(&model.ItemRarity{}).Error; err != nil {
+			return errors.Wrap(err, "failed on delete item rarities")
+		}
+		if len(traitStats) > 0 {
+			if err := tx.Table(model.CollectionTraitTableName(c.chain)).
+				CreateInBatches(traitStats, comm.DBBatchSizeLimit).Error; err != nil {
+				return errors.Wrap(err, "failed on create collection traits")
+			}
+		}
+		if len(rarities) > 0 {
+			if err := tx.Table(model.ItemRarityTableName(c.chain)).
+				CreateInBatches(rarities, comm.DBBatchSizeLimit).Error; err != nil {
+				return errors.Wrap(err, "failed on create item rarities")
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return c.RefreshTraitFloorPrices(collectionAddress)
+}
+
+// RefreshTraitFloorPrices 按 trait/value 计算有效 listing 的最低价，判断条件与 collection 地板价一致，
+// 不同币种的价格折合成 ETH 后比较，未配置的币种不参与比较
+func (c *Calculator) RefreshTraitFloorPrices(collectionAddress string) error {
+	var currencyFloorPrices []traitFloorPrice
+	sql := fmt.Sprintf(`SELECT it.trait as trait, it.trait_value as trait_value, co.currency_address as currency_address, min(co.price) as price
+FROM %s as ci
+         join %s it on it.collection_address = ci.collection_address and it.token_id = ci.token_id
+         join %s co on co.collection_address = ci.collection_address and co.token_id = ci.token_id
+WHERE ci.collection_address = ? and (%s) group by it.trait, it.trait_value, co.currency_address`,
+		gdb.GetMultiProjectItemTableName(c.project, c.chain),
+		gdb.GetMultiProjectItemTraitTableName(c.project, c.chain),
+		gdb.GetMultiProjectOrderTableName(c.project, c.chain),
+		comm.ActiveListingCondition)
+	args := append([]interface{}{collectionAddress}, comm.ActiveListingArgs()...)
+	if err := c.db.WithContext(c.ctx).Raw(sql, args...).Scan(&currencyFloorPrices).Error; err != nil {
+		return errors.Wrap(err, "failed on query trait floor price")
+	}
+	floorPrices := lowestEthFloorPrices(currencyFloorPrices, c.currencies)
+
+	return c.db.WithContext(c.ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(model.CollectionTraitTableName(c.chain)).
+			Where("collection_address = ?", collectionAddress).
+			Update("floor_price", nil).Error; err != nil {
+			return errors.Wrap(err, "failed on reset trait floor price")
+		}
+		for _, floorPrice := range floorPrices {
+			if err := tx.Table(model.CollectionTraitTableName(c.chain)).
+				Where("collection_address = ? and trait = ? and trait_value = ?", collectionAddress, floorPrice.Trait, floorPrice.TraitValue).
+				Update("floor_price", floorPrice.Price).Error; err != nil {
+				return errors.Wrap(err, "failed on update trait floor price")
+			}
+		}
+		return nil
+	})
+}
+
+type traitFloorPrice struct {
+	Trait           string
+	TraitValue      string
+	CurrencyAddress string
+	Price           decimal.Decimal
+}
+
+// lowestEthFloorPrices 把各币种的 trait 地板价折合成 ETH 后取每个 trait/value 的最低值
+func lowestEthFloorPrices(prices []traitFloorPrice, currencies *currency.Registry) []traitFloorPrice {
+	index := make(map[[2]string]int)
+	floorPrices := make([]traitFloorPrice, 0)
+	for _, p := range prices {
+		ethPrice, err := currencies.ToEth(p.CurrencyAddress, p.Price)
+		if err != nil {
+			continue
+		}
+		p.Price = ethPrice
+		key := [2]string{p.Trait, p.TraitValue}
+		if i, ok := index[key]; ok {
+			if ethPrice.LessThan(floorPrices[i].Price) {
+				floorPrices[i] = p
+			}
+			continue
+		}
+		index[key] = len(floorPrices)
+		floorPrices = append(floorPrices, p)
+	}
+	return floorPrices
+}
+
+// Calculate 计算 trait 频率与统计稀有度：
+// 频率的分母为 collection 的 item 总数 itemCount（小于有元数据的 item 数量时取后者），
+// 尚未抓到元数据的 item 按缺少所有 trait 计入；
+// 得分为各 trait 类型下 item 取值频率倒数之和，缺少某个 trait 类型的 item 按取值为空计入，
+// 同一 item 重复的 trait 取值只计一次，排名按得分从高到低，得分相同的 item 排名相同
+func Calculate(collectionAddress string, traits []multi.ItemTrait, itemCount int64) ([]model.CollectionTrait, []model.ItemRarity) {
+	tokenTraits := make(map[string]map[string][]string) // token_id -> trait -> values
+	valueCount := make(map[string]map[string]int64)     // trait -> value -> item 数量
+	for _, t := range traits {
+		if tokenTraits[t.TokenId] == nil {
+			tokenTraits[t.TokenId] = make(map[string][]string)
+		}
+		if slices.Contains(tokenTraits[t.TokenId][t.Trait], t.TraitValue) {
+			continue
+		}
+		tokenTraits[t.TokenId][t.Trait] = append(tokenTraits[t.TokenId][t.Trait], t.TraitValue)
+		if valueCount[t.Trait] == nil {
+			valueCount[t.Trait] = make(map[string]int64)
+		}
+		valueCount[t.Trait][t.TraitValue]++
+	}
+
+	if len(tokenTraits) == 0 {
+		return nil, nil
+	}
+	total := max(itemCount, int64(len(tokenTraits)))
+	totalDecimal := decimal.NewFromInt(total)
+
+	traitStats := make([]model.CollectionTrait, 0)
+	missingCount := make(map[string]int64) // trait -> 缺少该 trait 的 item 数量
+	for trait, values := range valueCount {
+		var present int64
+		for value, count := range values {
+			traitStats = append(traitStats, model.CollectionTrait{
+				CollectionAddress: collectionAddress,
+				Trait:             trait,
+				TraitValue:        value,
+				ItemCount:         count,
+				Frequency:         decimal.NewFromInt(count).DivRound(totalDecimal, scorePrecision),
+			})
+		}
+		for _, tt := range tokenTraits {
+			if _, ok := tt[trait]; ok {
+				present++
+			}
+		}
+		missingCount[trait] = total - present
+	}
+	sort.Slice(traitStats, func(i, j int) bool {
+		if traitStats[i].Trait != traitStats[j].Trait {
+			return traitStats[i].Trait < traitStats[j].Trait
+		}
+		return traitStats[i].TraitValue < traitStats[j].TraitValue
+	})
+
+	rarities := make([]model.ItemRarity, 0, total)
+	for tokenId, tt := range tokenTraits {
+		score := decimal.Zero
+		for trait, values := range valueCount {
+			tokenValues, ok := tt[trait]
+			if !ok {
+				score = score.Add(totalDecimal.DivRound(decimal.NewFromInt(missingCount[trait]), scorePrecision))
+				continue
+			}
+			for _, value := range tokenValues {
+				score = score.Add(totalDecimal.DivRound(decimal.NewFromInt(values[value]), scorePrecision))
+			}
+		}
+		rarities = append(rarities, model.ItemRarity{
+			CollectionAddress: collectionAddress,
+			TokenId:           tokenId,
+			RarityScore:       score,
+		})
+	}
+	sort.Slice(rarities, func(i, j int) bool {
+		if !rarities[i].RarityScore.Equal(rarities[j].RarityScore) {
+			return rarities[i].RarityScore.GreaterThan(rarities[j].RarityScore)
+		}
+		return rarities[i].TokenId < rarities[j].TokenId
+	})
+	for i := range rarities {
+		if i > 0 && rarities[i].RarityScore.Equal(rarities[i-1].RarityScore) {
+			rarities[i].RarityRank = rarities[i-1].RarityRank
+		} else {
+			rarities[i].RarityRank = int64(i + 1)
+		}
+	}
+
+	return traitStats, rarities
+}
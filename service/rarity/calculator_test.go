@@ -0,0 +1,65 @@
+package rarity
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+)
+
+func TestCalculate(t *testing.T) {
+	traits := []multi.ItemTrait{
+		{TokenId: "1", Trait: "Background", TraitValue: "Blue"},
+		{TokenId: "1", Trait: "Hat", TraitValue: "Crown"},
+		{TokenId: "2", Trait: "Background", TraitValue: "Blue"},
+		{TokenId: "3", Trait: "Background", TraitValue: "Red"},
+		{TokenId: "4", Trait: "Background", TraitValue: "Blue"},
+	}
+	traitStats, rarities := Calculate("0xabc", traits, 0)
+
+	if len(traitStats) != 3 {
+		t.Fatalf("Expected 3 trait stats, got %d", len(traitStats))
+	}
+	if traitStats[0].TraitValue != "Blue" || traitStats[0].ItemCount != 3 || !traitStats[0].Frequency.Equal(decimal.RequireFromString("0.75")) {
+		t.Errorf("Unexpected trait stat: %+v", traitStats[0])
+	}
+
+	// 1: 4/3 + 4/1 = 5.33; 3: 4/1 + 4/3 = 5.33; 2,4: 4/3 + 4/3 = 2.67
+	expectedRank := map[string]int64{"1": 1, "3": 1, "2": 3, "4": 3}
+	for _, r := range rarities {
+		if r.RarityRank != expectedRank[r.TokenId] {
+			t.Errorf("Unexpected rank of token %s: expected %d, got %d (score %s)", r.TokenId, expectedRank[r.TokenId], r.RarityRank, r.RarityScore)
+		}
+	}
+}
+
+func TestCalculateEmpty(t *testing.T) {
+	traitStats, rarities := Calculate("0xabc", nil, 10)
+	if traitStats != nil || rarities != nil {
+		t.Errorf("Expected no result for empty traits")
+	}
+}
+
+func TestCalculateItemCount(t *testing.T) {
+	traits := []multi.ItemTrait{
+		{TokenId: "1", Trait: "Background", TraitValue: "Blue"},
+		{TokenId: "1", Trait: "Background", TraitValue: "Blue"}, // 重复的取值只计一次
+		{TokenId: "2", Trait: "Background", TraitValue: "Red"},
+	}
+	// collection 共 4 个 item，其中 2 个还没有元数据
+	traitStats, rarities := Calculate("0xabc", traits, 4)
+
+	if len(traitStats) != 2 {
+		t.Fatalf("Expected 2 trait stats, got %d", len(traitStats))
+	}
+	for _, stat := range traitStats {
+		if stat.ItemCount != 1 || !stat.Frequency.Equal(decimal.RequireFromString("0.25")) {
+			t.Errorf("Unexpected trait stat: %+v", stat)
+		}
+	}
+	for _, r := range rarities {
+		if !r.RarityScore.Equal(decimal.NewFromInt(4)) {
+			t.Errorf("Unexpected score of token %s: expected 4, got %s", r.TokenId, r.RarityScore)
+		}
+	}
+}
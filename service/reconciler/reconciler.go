@@ -0,0 +1,399 @@
+package reconciler
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/yaoxc/EasySwapBase/chain/chainclient"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/base"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/yaoxc/EasySwapSync/service/comm"
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+	"github.com/yaoxc/EasySwapSync/service/itemprice"
+	"github.com/yaoxc/EasySwapSync/service/orderbookindexer"
+)
+
+// 对账发现的差异类型
+const (
+	DiffMissing = "missing" // 合约中存在但库里没有的订单（按 collection 最优价判断）
+	DiffExtra   = "extra"   // 库里有效但合约中已不存在且未成交的订单
+	DiffStatus  = "status"  // 库里的状态/剩余数量与合约成交数量不一致
+	DiffPrice   = "price"   // 库里的价格或过期时间与合约不一致
+)
+
+// Options 对账参数，Sample 为 0 时遍历全部有效订单
+type Options struct {
+	Sample int
+	Repair bool
+}
+
+// Diff 单条差异
+type Diff struct {
+	Kind              string
+	OrderId           string
+	CollectionAddress string
+	TokenId           string
+	Side              uint8
+	DbPrice           decimal.Decimal
+	ChainPrice        decimal.Decimal
+	Detail            string
+	Repaired          bool
+}
+
+// Report 对账报告
+type Report struct {
+	Block   uint64 // 读取合约状态的区块高度，即同步游标的前一个区块
+	Checked int
+	Diffs   []Diff
+}
+
+// ChainOrder 合约 orders(orderKey) 与 filledAmount(orderKey) 的结果，Maker 为零地址表示订单已不在合约中
+type ChainOrder struct {
+	Side     uint8
+	SaleKind uint8
+	Maker    common.Address
+	Nft      struct {
+		TokenId    *big.Int
+		Collection common.Address
+		Amount     *big.Int
+	}
+	Price  *big.Int
+	Expiry uint64
+	Salt   uint64
+
+	Filled *big.Int
+}
+
+// Exists 订单是否仍在合约存储中
+func (o ChainOrder) Exists() bool {
+	return o.Maker != (common.Address{})
+}
+
+// FilledAmount 合约记录的已成交数量
+func (o ChainOrder) FilledAmount() int64 {
+	if o.Filled == nil {
+		return 0
+	}
+	return o.Filled.Int64()
+}
+
+// Reconciler 用 eth_call 读取订单簿合约存储，与 ob_order 对账
+type Reconciler struct {
+	ctx         context.Context
+	db          *gorm.DB
+	chainClient chainclient.ChainClient
+	chain       string
+	project     string
+	chainId     int64
+	dex         common.Address
+	abis        *orderbookindexer.AbiBook
+	currencies  *currency.Registry
+}
+
+func New(ctx context.Context, cfg *config.Config, db *gorm.DB, chainClient chainclient.ChainClient) (*Reconciler, error) {
+	abis, err := orderbookindexer.LoadAbiBook(cfg.ContractCfg.Abis)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on load contract abi")
+	}
+	currencies, err := currency.New(cfg.ContractCfg, cfg.Currencies)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on create currency registry")
+	}
+	return &Reconciler{
+		ctx:         ctx,
+		db:          db,
+		chainClient: chainClient,
+		chain:       cfg.ChainCfg.Name,
+		project:     cfg.ProjectCfg.Name,
+		chainId:     cfg.ChainCfg.ID,
+		dex:         common.HexToAddress(cfg.ContractCfg.DexAddress),
+		abis:        abis,
+		currencies:  currencies,
+	}, nil
+}
+
+// Run 抽样或遍历有效订单与合约对账，Repair 时按合约状态修正库里的订单；
+// 合约状态和 ABI 都固定在同步游标的前一个区块，与库里已同步的事件对齐
+func (r *Reconciler) Run(opts Options) (*Report, error) {
+	at, err := r.pin()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Block: at.block.Uint64()}
+	collections := make(map[string]bool)
+	check := func(orders []multi.Order) error {
+		for _, order := range orders {
+			chainOrder, err := r.chainOrder(at, order.OrderID)
+			if err != nil {
+				return err
+			}
+			collections[strings.ToLower(order.CollectionAddress)] = true
+			for _, diff := range CompareOrder(order, chainOrder) {
+				if opts.Repair {
+					if err := r.repair(order, chainOrder, diff); err != nil {
+						return err
+					}
+					diff.Repaired = true
+				}
+				report.Diffs = append(report.Diffs, diff)
+			}
+		}
+		report.Checked += len(orders)
+		return nil
+	}
+
+	db := r.db.WithContext(r.ctx).Table(multi.OrderTableName(r.chain)).
+		Where("order_status = ?", multi.OrderStatusActive)
+	if opts.Sample > 0 {
+		var orders []multi.Order
+		if err := db.Order("rand()").Limit(opts.Sample).Find(&orders).Error; err != nil {
+			return nil, errors.Wrap(err, "failed on sample active orders")
+		}
+		if err := check(orders); err != nil {
+			return nil, err
+		}
+	} else {
+		var lastId int64
+		for {
+			var orders []multi.Order
+			if err := db.Session(&gorm.Session{}).Where("id > ?", lastId).
+				Order("id").Limit(comm.DBBatchSizeLimit).Find(&orders).Error; err != nil {
+				return nil, errors.Wrap(err, "failed on query active orders")
+			}
+			if err := check(orders); err != nil {
+				return nil, err
+			}
+			if len(orders) < comm.DBBatchSizeLimit {
+				break
+			}
+			lastId = orders[len(orders)-1].ID
+			xzap.WithContext(r.ctx).Info("reconciling orders ...", zap.Int("checked", report.Checked))
+		}
+	}
+
+	for collection := range collections {
+		diffs, err := r.checkBestPrices(at, collection)
+		if err != nil {
+			return nil, err
+		}
+		report.Diffs = append(report.Diffs, diffs...)
+	}
+	return report, nil
+}
+
+// pinnedContract 对账读取合约状态的区块高度及该高度使用的 ABI
+type pinnedContract struct {
+	abi   abi.ABI
+	block *big.Int
+}
+
+// pin 把对账固定在同步游标之前的最后一个区块：库里只反映到该区块为止的事件，
+// 读取更新的合约状态会把游标与链头之间的订单误报为差异，--repair 时还会抢在同步之前写入
+func (r *Reconciler) pin() (pinnedContract, error) {
+	var cursor int64
+	if err := r.db.WithContext(r.ctx).Table(base.IndexedStatusTableName()).
+		Select("last_indexed_block").
+		Where("chain_id = ? and index_type = ?", r.chainId, orderbookindexer.EventIndexType).
+		Scan(&cursor).Error; err != nil {
+		return pinnedContract{}, errors.Wrap(err, "failed on query sync cursor")
+	}
+	if cursor <= 0 {
+		return pinnedContract{}, errors.New("orderbook sync cursor not found, run `sync init` first")
+	}
+	block := uint64(cursor - 1)
+	return pinnedContract{
+		abi:   r.abis.Lookup(r.dex, block).Abi,
+		block: new(big.Int).SetUint64(block),
+	}, nil
+}
+
+// CompareOrder 比较库里的有效订单和合约中的订单
+func CompareOrder(order multi.Order, chainOrder ChainOrder) []Diff {
+	side := uint8(orderbookindexer.Bid)
+	if order.OrderType == multi.ListingOrder {
+		side = orderbookindexer.List
+	}
+	diff := Diff{
+		OrderId:           order.OrderID,
+		CollectionAddress: order.CollectionAddress,
+		TokenId:           order.TokenId,
+		Side:              side,
+		DbPrice:           order.Price,
+	}
+	if chainOrder.Price != nil {
+		diff.ChainPrice = decimal.NewFromBigInt(chainOrder.Price, 0)
+	}
+
+	filled := chainOrder.FilledAmount()
+	if filled >= order.Size && order.Size > 0 {
+		diff.Kind = DiffStatus
+		diff.Detail = "order filled on chain"
+		return []Diff{diff}
+	}
+	if !chainOrder.Exists() {
+		diff.Kind = DiffExtra
+		diff.Detail = "order not found in contract storage"
+		return []Diff{diff}
+	}
+
+	diffs := make([]Diff, 0)
+	if remaining := order.Size - filled; remaining != order.QuantityRemaining {
+		d := diff
+		d.Kind = DiffStatus
+		d.Detail = "quantity remaining mismatch"
+		diffs = append(diffs, d)
+	}
+	if !diff.ChainPrice.Equal(order.Price) || int64(chainOrder.Expiry) != order.ExpireTime {
+		d := diff
+		d.Kind = DiffPrice
+		d.Detail = "price or expiry mismatch"
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// repair 按合约状态修正订单，listing 变化时同步刷新 item 的 list_price
+func (r *Reconciler) repair(order multi.Order, chainOrder ChainOrder, diff Diff) error {
+	updates := map[string]interface{}{}
+	switch diff.Kind {
+	case DiffExtra:
+		updates["order_status"] = multi.OrderStatusCancelled
+	case DiffStatus:
+		remaining := order.Size - chainOrder.FilledAmount()
+		if remaining <= 0 {
+			updates["order_status"] = multi.OrderStatusFilled
+			remaining = 0
+		}
+		updates["quantity_remaining"] = remaining
+	case DiffPrice:
+		updates["price"] = diff.ChainPrice
+		updates["expire_time"] = int64(chainOrder.Expiry)
+	default:
+		return nil
+	}
+
+	return r.db.WithContext(r.ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(multi.OrderTableName(r.chain)).
+			Where("order_id = ? and order_status = ?", order.OrderID, multi.OrderStatusActive).
+			Updates(updates).Error; err != nil {
+			return errors.Wrap(err, "failed on repair order")
+		}
+		if order.OrderType != multi.ListingOrder {
+			return nil
+		}
+		return itemprice.RefreshListing(tx, r.project, r.chain, r.currencies, order.CollectionAddress, order.TokenId)
+	})
+}
+
+// checkBestPrices 比较合约 getBestPrice 与库里同价位的订单，库里没有该价位的订单（有效或已过期）时记为 missing
+func (r *Reconciler) checkBestPrices(at pinnedContract, collection string) ([]Diff, error) {
+	diffs := make([]Diff, 0)
+	for _, side := range []uint8{orderbookindexer.List, orderbookindexer.Bid} {
+		out, err := r.call(at, "getBestPrice", common.HexToAddress(collection), side)
+		if err != nil {
+			return nil, err
+		}
+		price, ok := out[0].(*big.Int)
+		if !ok || price.Sign() == 0 {
+			continue
+		}
+		chainPrice := decimal.NewFromBigInt(price, 0)
+
+		orderTypes := []int64{multi.ListingOrder}
+		if side == orderbookindexer.Bid {
+			orderTypes = []int64{multi.OfferOrder, multi.CollectionBidOrder, multi.ItemBidOrder}
+		}
+		var count int64
+		if err := r.db.WithContext(r.ctx).Table(multi.OrderTableName(r.chain)).
+			Where("collection_address = ? and order_type in (?) and price = ? and order_status in (?)",
+				collection, orderTypes, chainPrice, []int{multi.OrderStatusActive, multi.OrderStatusExpired}).
+			Count(&count).Error; err != nil {
+			return nil, errors.Wrap(err, "failed on query best price orders")
+		}
+		if count == 0 {
+			diffs = append(diffs, Diff{
+				Kind:              DiffMissing,
+				CollectionAddress: collection,
+				Side:              side,
+				ChainPrice:        chainPrice,
+				Detail:            "no order at contract best price, replay logs to recover",
+			})
+		}
+	}
+	return diffs, nil
+}
+
+func (r *Reconciler) chainOrder(at pinnedContract, orderId string) (ChainOrder, error) {
+	var chainOrder ChainOrder
+	orderKey := [32]byte(common.HexToHash(orderId))
+
+	var result struct {
+		Order ChainOrder
+		Next  [32]byte
+	}
+	if err := r.callInto(at, &result, "orders", orderKey); err != nil {
+		return chainOrder, err
+	}
+	chainOrder = result.Order
+
+	out, err := r.call(at, "filledAmount", orderKey)
+	if err != nil {
+		return chainOrder, err
+	}
+	filled, ok := out[0].(*big.Int)
+	if !ok {
+		return chainOrder, errors.New("unexpected filledAmount result")
+	}
+	chainOrder.Filled = filled
+	return chainOrder, nil
+}
+
+func (r *Reconciler) call(at pinnedContract, method string, args ...interface{}) ([]interface{}, error) {
+	out, err := r.rawCall(at, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	values, err := at.abi.Unpack(method, out)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed on unpack %s", method)
+	}
+	if len(values) == 0 {
+		return nil, errors.Errorf("empty %s result", method)
+	}
+	return values, nil
+}
+
+func (r *Reconciler) callInto(at pinnedContract, result interface{}, method string, args ...interface{}) error {
+	out, err := r.rawCall(at, method, args...)
+	if err != nil {
+		return err
+	}
+	if err := at.abi.UnpackIntoInterface(result, method, out); err != nil {
+		return errors.Wrapf(err, "failed on unpack %s", method)
+	}
+	return nil
+}
+
+func (r *Reconciler) rawCall(at pinnedContract, method string, args ...interface{}) ([]byte, error) {
+	data, err := at.abi.Pack(method, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed on pack %s", method)
+	}
+	out, err := r.chainClient.CallContract(r.ctx, ethereum.CallMsg{To: &r.dex, Data: data}, at.block)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed on call %s", method)
+	}
+	return out, nil
+}
@@ -0,0 +1,58 @@
+package reconciler
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	"github.com/yaoxc/EasySwapBase/stores/gdb/orderbookmodel/multi"
+)
+
+func TestCompareOrder(t *testing.T) {
+	order := multi.Order{
+		OrderID:           "0x01",
+		OrderType:         multi.ListingOrder,
+		Price:             decimal.NewFromInt(1000),
+		ExpireTime:        1700000000,
+		Size:              1,
+		QuantityRemaining: 1,
+	}
+	onChain := func(price int64, expiry uint64, filled int64, exists bool) ChainOrder {
+		chainOrder := ChainOrder{Price: big.NewInt(price), Expiry: expiry, Filled: big.NewInt(filled)}
+		if exists {
+			chainOrder.Maker = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		}
+		return chainOrder
+	}
+
+	cases := []struct {
+		name       string
+		chainOrder ChainOrder
+		want       []string
+	}{
+		{"consistent", onChain(1000, 1700000000, 0, true), nil},
+		{"cancelled on chain", onChain(0, 0, 0, false), []string{DiffExtra}},
+		{"filled on chain", onChain(0, 0, 1, false), []string{DiffStatus}},
+		{"price changed", onChain(900, 1700000000, 0, true), []string{DiffPrice}},
+		{"expiry changed", onChain(1000, 1800000000, 0, true), []string{DiffPrice}},
+	}
+	for _, c := range cases {
+		diffs := CompareOrder(order, c.chainOrder)
+		if len(diffs) != len(c.want) {
+			t.Fatalf("%s: unexpected diffs %+v", c.name, diffs)
+		}
+		for i, diff := range diffs {
+			if diff.Kind != c.want[i] {
+				t.Fatalf("%s: diff %d kind %s, want %s", c.name, i, diff.Kind, c.want[i])
+			}
+		}
+	}
+
+	partial := order
+	partial.Size, partial.QuantityRemaining = 5, 5
+	diffs := CompareOrder(partial, onChain(1000, 1700000000, 2, true))
+	if len(diffs) != 1 || diffs[0].Kind != DiffStatus {
+		t.Fatalf("unexpected partial fill diffs %+v", diffs)
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"context" // 上下文管理
 	"fmt"     // 格式化输出
 	"sync"    // 并发同步
+	"sync/atomic"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"    // 合约 ABI
+	"github.com/ethereum/go-ethereum/common"          // 地址类型
 	"github.com/pkg/errors"                           // 错误处理
 	"github.com/yaoxc/EasySwapBase/chain"             // 链相关常量
 	"github.com/yaoxc/EasySwapBase/chain/chainclient" // 区块链客户端
@@ -15,29 +18,53 @@ import (
 	"github.com/zeromicro/go-zero/core/stores/cache"  // go-zero 缓存
 	"github.com/zeromicro/go-zero/core/stores/kv"     // go-zero KV
 	"github.com/zeromicro/go-zero/core/stores/redis"  // go-zero Redis
+	"github.com/zeromicro/go-zero/core/threading"     // 安全启动协程
 	"gorm.io/gorm"                                    // ORM 数据库
 
 	"github.com/yaoxc/EasySwapSync/service/orderbookindexer" // 订单簿同步器
+	"github.com/yaoxc/EasySwapSync/service/orderexpiry"      // 订单过期处理
 
-	"github.com/yaoxc/EasySwapSync/model"                    // 数据模型
-	"github.com/yaoxc/EasySwapSync/service/collectionfilter" // 集合过滤器
-	"github.com/yaoxc/EasySwapSync/service/config"           // 配置
+	"github.com/yaoxc/EasySwapSync/db/migrations"              // 表结构版本
+	"github.com/yaoxc/EasySwapSync/model"                      // 数据模型
+	"github.com/yaoxc/EasySwapSync/service/collectionfilter"   // 集合过滤器
+	"github.com/yaoxc/EasySwapSync/service/collectionimporter" // 集合导入器
+	"github.com/yaoxc/EasySwapSync/service/config"             // 配置
+	"github.com/yaoxc/EasySwapSync/service/currency"           // 计价代币
+	"github.com/yaoxc/EasySwapSync/service/health"             // 存活和就绪检查
+	"github.com/yaoxc/EasySwapSync/service/itemmetadata"       // item元数据抓取
+	"github.com/yaoxc/EasySwapSync/service/leader"             // leader 选举
+	"github.com/yaoxc/EasySwapSync/service/metrics"            // 监控指标
+	"github.com/yaoxc/EasySwapSync/service/pricing"            // 美元价格
+	"github.com/yaoxc/EasySwapSync/service/rarity"             // 稀有度计算
+	"github.com/yaoxc/EasySwapSync/service/supervisor"         // 常驻循环重启
+	"github.com/yaoxc/EasySwapSync/service/webhook"            // webhook 推送
 )
 
 // Service 主服务结构体，包含各类依赖和组件
 type Service struct {
-	ctx              context.Context            // 全局上下文
-	config           *config.Config             // 配置
-	kvStore          *xkv.Store                 // KV 存储
-	db               *gorm.DB                   // 数据库连接
-	wg               *sync.WaitGroup            // 并发等待组
-	collectionFilter *collectionfilter.Filter   // 集合过滤器
-	orderbookIndexer *orderbookindexer.Service  // 订单簿同步器
-	orderManager     *ordermanager.OrderManager // 订单管理器
+	ctx                context.Context              // 全局上下文
+	cancel             context.CancelFunc           // 停机时取消 ctx，停止所有后台任务
+	stopping           atomic.Bool                  // 已开始停机，readyz 失败
+	config             *config.Config               // 配置
+	kvStore            *xkv.Store                   // KV 存储
+	db                 *gorm.DB                     // 数据库连接
+	wg                 *sync.WaitGroup              // 并发等待组
+	collectionFilter   *collectionfilter.Filter     // 集合过滤器
+	collectionImporter *collectionimporter.Importer // 集合导入器
+	metadataWorker     *itemmetadata.Worker         // item元数据抓取器
+	rarityCalculator   *rarity.Calculator           // 稀有度计算器
+	expirySweeper      *orderexpiry.Sweeper         // 订单过期处理器
+	pricer             *pricing.Pricer              // 美元价格补全
+	orderbookIndexer   *orderbookindexer.Service    // 订单簿同步器
+	orderManager       *ordermanager.OrderManager   // 订单管理器
+	heartbeat          *health.Heartbeat            // 常驻循环心跳
+	supervisor         *supervisor.Supervisor       // 常驻循环重启
+	elector            *leader.Elector              // leader 选举，未开启时为 nil
+	webhooks           *webhook.Dispatcher          // webhook 推送，未开启时为 nil
 }
 
-// New 构造 Service 实例，初始化各类依赖
-func New(ctx context.Context, cfg *config.Config) (*Service, error) {
+// NewKvStore 根据 Redis 配置创建 KV 存储
+func NewKvStore(cfg *config.Config) *xkv.Store {
 	var kvConf kv.KvConf // KV 配置
 	// 遍历 Redis 配置，组装 KV 节点配置
 	for _, con := range cfg.Kv.Redis {
@@ -51,10 +78,42 @@ func New(ctx context.Context, cfg *config.Config) (*Service, error) {
 		})
 	}
 
-	kvStore := xkv.NewStore(kvConf) // 创建 KV 存储
+	return xkv.NewStore(kvConf)
+}
+
+// Option 创建服务时的可选扩展，在各组件创建完成、Start 之前执行
+type Option func(s *Service) error
+
+// WithHandler 在订单簿同步器上为指定合约的事件登记额外的处理器，当前链没有订单簿同步器时返回错误
+func WithHandler(address common.Address, contractAbi abi.ABI, eventName string, handler orderbookindexer.EventHandler) Option {
+	return func(s *Service) error {
+		if s.orderbookIndexer == nil {
+			return errors.Errorf("orderbook indexer is not enabled on chain %s", s.config.ChainCfg.Name)
+		}
+		return s.orderbookIndexer.RegisterHandler(address, contractAbi, eventName, handler)
+	}
+}
+
+// New 构造 Service 实例，初始化各类依赖，opts 在返回前依次执行
+func New(ctx context.Context, cfg *config.Config, opts ...Option) (_ *Service, err error) {
+	ctx, cancel := context.WithCancel(ctx) // 由 Stop 取消，各组件共用
+	defer func() {
+		if err != nil {
+			cancel()
+		}
+	}()
+	kvStore := NewKvStore(cfg) // 创建 KV 存储
 
-	var err error
 	db := model.NewDB(cfg.DB) // 初始化数据库连接
+	// 表结构版本必须与程序一致，否则先执行 sync migrate
+	if err := migrations.Verify(ctx, db, cfg.ChainCfg.Name); err != nil {
+		return nil, errors.Wrap(err, "failed on verify schema version")
+	}
+	if cfg.Monitor != nil && cfg.Monitor.MetricsEnable {
+		if err := metrics.InstrumentDB(db); err != nil {
+			return nil, err
+		}
+	}
 
 	collectionFilter := collectionfilter.New(ctx, db, cfg.ChainCfg.Name, cfg.ProjectCfg.Name)  // 创建集合过滤器
 	orderManager := ordermanager.New(ctx, db, kvStore, cfg.ChainCfg.Name, cfg.ProjectCfg.Name) // 创建订单管理器
@@ -67,25 +126,86 @@ func New(ctx context.Context, cfg *config.Config) (*Service, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed on create evm client") // 创建失败返回错误
 	}
+	chainClient = metrics.InstrumentChainClient(chainClient, cfg.ChainCfg.Name) // 记录RPC耗时和错误
+
+	// 创建集合导入器，首次出现的collection由它补全信息并加入过滤器
+	collectionImporter, err := collectionimporter.New(ctx, db, kvStore, chainClient, cfg.ChainCfg.ID, cfg.ChainCfg.Name, cfg.ProjectCfg.Name, collectionFilter)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on create collection importer")
+	}
+
+	// 支持的计价代币注册表，地板价、item list_price 和交易量按它折合成ETH
+	currencies, err := currency.New(cfg.ContractCfg, cfg.Currencies)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on create currency registry")
+	}
+
+	// 创建稀有度计算器，trait变化后重算稀有度排名和trait地板价
+	rarityCalculator := rarity.New(ctx, db, cfg.ChainCfg.Name, cfg.ProjectCfg.Name, currencies)
+	// 创建item元数据抓取器，新出现的token由它补全名称、图片和属性
+	metadataWorker, err := itemmetadata.New(ctx, cfg.MetadataCfg, db, chainClient, cfg.ChainCfg.Name, cfg.ProjectCfg.Name, rarityCalculator)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on create item metadata worker")
+	}
+
+	// 创建订单过期处理器，过期订单改为Expired并写入过期activity
+	expirySweeper := orderexpiry.New(ctx, db, kvStore, cfg.ChainCfg.Name, cfg.ProjectCfg.Name, currencies)
+
+	// 创建美元价格补全器，给成交活动和地板价记录补上美元价值
+	pricer, err := pricing.New(ctx, cfg.PricingCfg, db, cfg.ChainCfg.Name, cfg.ProjectCfg.Name, currencies)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed on create pricer")
+	}
 
 	// 根据链 ID 初始化订单簿同步器
 	switch cfg.ChainCfg.ID {
 	case chain.EthChainID, chain.OptimismChainID, chain.SepoliaChainID:
-		orderbookSyncer = orderbookindexer.New(ctx, cfg, db, kvStore, chainClient, cfg.ChainCfg.ID, cfg.ChainCfg.Name, orderManager)
+		orderbookSyncer, err = orderbookindexer.New(ctx, cfg, db, kvStore, chainClient, cfg.ChainCfg.ID, cfg.ChainCfg.Name, orderManager, collectionImporter, metadataWorker, currencies)
 	}
 	if err != nil {
 		return nil, errors.Wrap(err, "failed on create trade info server") // 创建失败返回错误
 	}
+	heartbeat := health.NewHeartbeat()                    // 常驻循环心跳，/healthz 据此判断存活
+	loopSupervisor := supervisor.New(ctx, cfg.Supervisor) // 常驻循环退出后按指数退避重启
+	var elector *leader.Elector                           // 多副本部署时按链和合约竞选，只有 leader 同步订单簿
+	if cfg.LeaderCfg.Enable {
+		elector = leader.New(ctx, kvStore, cfg.LeaderCfg, cfg.ChainCfg.Name, cfg.ContractCfg.DexAddress)
+	}
+	var webhooks *webhook.Dispatcher // 挂单、成交和取消事件推送给订阅者
+	if cfg.WebhookCfg.Enable {
+		webhooks = webhook.New(ctx, cfg.WebhookCfg, db, cfg.ChainCfg.Name, cfg.ChainCfg.ID, currencies, elector)
+	}
+	if orderbookSyncer != nil {
+		orderbookSyncer.SetHeartbeat(heartbeat)
+		orderbookSyncer.SetSupervisor(loopSupervisor)
+		orderbookSyncer.SetElector(elector)
+		orderbookSyncer.SetWebhooks(webhooks)
+	}
 	// 构造 Service 实例
 	manager := Service{
-		ctx:              ctx,               // 上下文
-		config:           cfg,               // 配置
-		db:               db,                // 数据库
-		kvStore:          kvStore,           // KV 存储
-		collectionFilter: collectionFilter,  // 集合过滤器
-		orderbookIndexer: orderbookSyncer,   // 订单簿同步器
-		orderManager:     orderManager,      // 订单管理器
-		wg:               &sync.WaitGroup{}, // 并发等待组
+		ctx:                ctx,                // 上下文
+		cancel:             cancel,             // 取消上下文
+		config:             cfg,                // 配置
+		db:                 db,                 // 数据库
+		kvStore:            kvStore,            // KV 存储
+		collectionFilter:   collectionFilter,   // 集合过滤器
+		collectionImporter: collectionImporter, // 集合导入器
+		metadataWorker:     metadataWorker,     // item元数据抓取器
+		rarityCalculator:   rarityCalculator,   // 稀有度计算器
+		expirySweeper:      expirySweeper,      // 订单过期处理器
+		pricer:             pricer,             // 美元价格补全
+		orderbookIndexer:   orderbookSyncer,    // 订单簿同步器
+		orderManager:       orderManager,       // 订单管理器
+		heartbeat:          heartbeat,          // 常驻循环心跳
+		supervisor:         loopSupervisor,     // 常驻循环重启
+		elector:            elector,            // leader 选举
+		webhooks:           webhooks,           // webhook 推送
+		wg:                 &sync.WaitGroup{},  // 并发等待组
+	}
+	for _, opt := range opts {
+		if err := opt(&manager); err != nil {
+			return nil, errors.Wrap(err, "failed on apply service option")
+		}
 	}
 	return &manager, nil // 返回实例
 }
@@ -97,7 +217,62 @@ func (s *Service) Start() error {
 		return errors.Wrap(err, "failed on preload collection to filter") // 预加载失败返回错误
 	}
 
-	s.orderbookIndexer.Start() // 启动订单簿同步器
-	s.orderManager.Start()     // 启动订单管理器
-	return nil                 // 启动成功返回 nil
+	// 缺失的同步记录从合约部署区块建行，游标早于部署区块或超过链头时拒绝启动
+	if s.orderbookIndexer != nil {
+		if err := s.orderbookIndexer.PrepareCursors(); err != nil {
+			return errors.Wrap(err, "failed on prepare sync cursors")
+		}
+	}
+
+	if s.elector != nil {
+		threading.GoSafe(s.elector.Run) // 竞选 leader，当选前同步循环空转
+	}
+
+	s.collectionImporter.Start() // 启动集合导入器
+	s.metadataWorker.Start()     // 启动item元数据抓取器
+	s.rarityCalculator.Start()   // 启动稀有度计算器
+	s.expirySweeper.Start()      // 启动订单过期处理器
+	s.pricer.Start()             // 启动美元价格补全
+	s.orderbookIndexer.Start()   // 启动订单簿同步器
+	s.orderManager.Start()       // 启动订单管理器
+	s.webhooks.Start()           // 启动 webhook 推送
+	return nil                   // 启动成功返回 nil
+}
+
+// Stop 优雅停机：先停止订单簿同步循环并等待当前区块区间提交，再等待导入和元数据队列处理完，
+// 然后取消 ctx 停止订单管理器和其余定时任务，最后关闭数据库连接。
+// ctx 结束时不再等待，直接取消和关闭，返回第一个遇到的错误
+func (s *Service) Stop(ctx context.Context) error {
+	s.stopping.Store(true) // readyz 立即失败，摘除流量
+
+	s.supervisor.Stop()
+	if s.orderbookIndexer != nil {
+		s.orderbookIndexer.Stop()
+	}
+	err := s.supervisor.Wait(ctx) // 等待当前区块区间提交
+	s.elector.Stop()              // 区间提交后再释放租约，备用实例尽快接管
+	if err == nil {
+		err = s.collectionImporter.Drain(ctx)
+	}
+	if err == nil {
+		err = s.metadataWorker.Drain(ctx)
+	}
+
+	s.orderManager.Stop()
+	s.cancel() // 停止订单管理器、稀有度、过期和价格等定时任务
+
+	// go-zero 的 Redis 连接池按地址全局共享且没有关闭接口，使用方都已随 ctx 停止，连接随进程退出释放
+	sqlDB, dbErr := s.db.DB()
+	if dbErr == nil {
+		dbErr = sqlDB.Close()
+	}
+	if dbErr != nil && err == nil {
+		err = errors.Wrap(dbErr, "failed on close db")
+	}
+	return err
+}
+
+// Fatal 常驻循环连续失败次数过多时收到错误，调用方应退出进程以便重新调度
+func (s *Service) Fatal() <-chan error {
+	return s.supervisor.Fatal()
 }
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"go.uber.org/zap"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/orderbookindexer"
+)
+
+const rewardsAbi = `[{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"user","type":"address"},{"indexed":false,"internalType":"uint256","name":"amount","type":"uint256"}],"name":"RewardPaid","type":"event"}]`
+
+func TestWithHandler(t *testing.T) {
+	ctx := xzap.ToContext(context.Background(), zap.NewNop())
+	parsedAbi, err := abi.JSON(strings.NewReader(rewardsAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewards := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	handler := orderbookindexer.NewEventHandler("rewards", func(log ethereumTypes.Log) error { return nil })
+	opt := WithHandler(rewards, parsedAbi, "RewardPaid", handler)
+
+	s := &Service{ctx: ctx, config: &config.Config{ChainCfg: config.ChainCfg{Name: "sepolia"}}}
+	if err := opt(s); err == nil {
+		t.Fatal("expected error without orderbook indexer")
+	}
+
+	s.orderbookIndexer, err = orderbookindexer.New(ctx, nil, nil, nil, nil, 11155111, "sepolia", nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := opt(s); err != nil {
+		t.Fatal(err)
+	}
+	// 同一合约事件重复登记失败，说明处理器已登记到同步器上
+	if err := opt(s); err == nil {
+		t.Fatal("expected duplicate handler registration to fail")
+	}
+	if err := WithHandler(rewards, parsedAbi, "RewardClaimed", handler)(s); err == nil {
+		t.Fatal("expected error for event missing from abi")
+	}
+}
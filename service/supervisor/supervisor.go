@@ -0,0 +1,268 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/zeromicro/go-zero/core/threading"
+	"go.uber.org/zap"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+)
+
+const (
+	DefaultInitialBackoff = 1   // in seconds
+	DefaultMaxBackoff     = 60  // in seconds
+	DefaultMaxFailures    = 5   // 连续失败次数达到该值后不再重启
+	HealthyRunTime        = 300 // in seconds，运行超过该时长后退出不计入连续失败
+)
+
+type State string
+
+const (
+	StateRunning State = "running"
+	StateBackoff State = "backoff" // 等待重启
+	StateStopped State = "stopped" // ctx 取消或 Stop 后正常退出
+	StateFailed  State = "failed"  // 连续失败次数过多，不再重启
+)
+
+// LoopStatus 常驻循环的运行状态
+type LoopStatus struct {
+	Name                string    `json:"name"`
+	State               State     `json:"state"`
+	Restarts            int       `json:"restarts"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastStart           time.Time `json:"last_start"`
+	LastExit            time.Time `json:"last_exit,omitempty"`
+	NextRestart         time.Time `json:"next_restart,omitempty"`
+}
+
+// Supervisor 运行常驻循环，循环提前返回或 panic 时按指数退避重启，
+// 连续失败达到 max_failures 后通过 Fatal 通知进程退出，由编排系统重新调度
+type Supervisor struct {
+	ctx            context.Context
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	maxFailures    int
+	healthyRunTime time.Duration
+
+	loops    map[string]*LoopStatus
+	lock     *sync.RWMutex
+	fatal    chan error
+	quit     chan struct{} // Stop 时关闭，不再重启退出的循环
+	stopOnce *sync.Once
+	wg       *sync.WaitGroup // 运行中的循环
+}
+
+func New(ctx context.Context, cfg config.SupervisorCfg) *Supervisor {
+	initialBackoff := cfg.InitialBackoff
+	if initialBackoff <= 0 {
+		initialBackoff = DefaultInitialBackoff
+	}
+	maxBackoff := cfg.MaxBackoff
+	if maxBackoff < initialBackoff {
+		maxBackoff = DefaultMaxBackoff
+	}
+	maxFailures := cfg.MaxFailures
+	if maxFailures <= 0 {
+		maxFailures = DefaultMaxFailures
+	}
+	return &Supervisor{
+		ctx:            ctx,
+		initialBackoff: time.Duration(initialBackoff) * time.Second,
+		maxBackoff:     time.Duration(maxBackoff) * time.Second,
+		maxFailures:    maxFailures,
+		healthyRunTime: HealthyRunTime * time.Second,
+		loops:          make(map[string]*LoopStatus),
+		lock:           &sync.RWMutex{},
+		fatal:          make(chan error, 1),
+		quit:           make(chan struct{}),
+		stopOnce:       &sync.Once{},
+		wg:             &sync.WaitGroup{},
+	}
+}
+
+// Go 在后台运行循环 loop，s 为 nil 时退化为 threading.GoSafe
+func (s *Supervisor) Go(name string, loop func()) {
+	if s == nil {
+		threading.GoSafe(loop)
+		return
+	}
+	s.lock.Lock()
+	s.loops[name] = &LoopStatus{Name: name}
+	s.lock.Unlock()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(name, loop)
+	}()
+}
+
+// Stop 停止重启循环，循环自身需另行通知退出，之后用 Wait 等待循环返回
+func (s *Supervisor) Stop() {
+	if s == nil {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.quit)
+	})
+}
+
+// Wait 等待所有循环返回，ctx 结束时返回 ctx 的错误
+func (s *Supervisor) Wait(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "failed on wait for loops to stop")
+	}
+}
+
+func (s *Supervisor) stopped() bool {
+	select {
+	case <-s.quit:
+		return true
+	default:
+		return s.ctx.Err() != nil
+	}
+}
+
+func (s *Supervisor) run(name string, loop func()) {
+	backoff := s.initialBackoff
+	for {
+		start := time.Now()
+		s.update(name, func(status *LoopStatus) {
+			status.State = StateRunning
+			status.LastStart = start
+			status.NextRestart = time.Time{}
+		})
+
+		err := s.runOnce(loop)
+		if s.stopped() {
+			s.update(name, func(status *LoopStatus) {
+				status.State = StateStopped
+				status.LastExit = time.Now()
+			})
+			return
+		}
+
+		healthy := time.Since(start) >= s.healthyRunTime
+		if healthy {
+			backoff = s.initialBackoff
+		}
+		var failures int
+		s.update(name, func(status *LoopStatus) {
+			if healthy {
+				status.ConsecutiveFailures = 0
+			}
+			status.ConsecutiveFailures++
+			status.LastError = err.Error()
+			status.LastExit = time.Now()
+			failures = status.ConsecutiveFailures
+		})
+		xzap.WithContext(s.ctx).Error("loop exited unexpectedly",
+			zap.String("loop", name), zap.Int("consecutive_failures", failures), zap.Error(err))
+
+		if failures >= s.maxFailures {
+			s.update(name, func(status *LoopStatus) {
+				status.State = StateFailed
+			})
+			s.escalate(errors.Errorf("loop %s failed %d times in a row: %s", name, failures, err.Error()))
+			return
+		}
+
+		s.update(name, func(status *LoopStatus) {
+			status.State = StateBackoff
+			status.Restarts++
+			status.NextRestart = time.Now().Add(backoff)
+		})
+		select {
+		case <-s.ctx.Done():
+			s.update(name, func(status *LoopStatus) {
+				status.State = StateStopped
+			})
+			return
+		case <-s.quit:
+			s.update(name, func(status *LoopStatus) {
+				status.State = StateStopped
+			})
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
+
+// runOnce 运行一次循环，循环返回或 panic 都视为失败
+func (s *Supervisor) runOnce(loop func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			xzap.WithContext(s.ctx).Error("loop panicked",
+				zap.Any("panic", r), zap.ByteString("stack", debug.Stack()))
+		}
+	}()
+	loop()
+	return errors.New("loop returned")
+}
+
+func (s *Supervisor) update(name string, fn func(status *LoopStatus)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fn(s.loops[name])
+}
+
+func (s *Supervisor) escalate(err error) {
+	xzap.WithContext(s.ctx).Error("loop failed permanently, exiting", zap.Error(err))
+	select {
+	case s.fatal <- err:
+	default: // 已经有循环通知过退出
+	}
+}
+
+// Fatal 有循环连续失败次数过多时收到错误，调用方应退出进程
+func (s *Supervisor) Fatal() <-chan error {
+	return s.fatal
+}
+
+// Status 返回按名称排序的循环状态
+func (s *Supervisor) Status() []LoopStatus {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	statuses := make([]LoopStatus, 0, len(s.loops))
+	for _, status := range s.loops {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Check 有循环已放弃重启时返回错误
+func (s *Supervisor) Check() error {
+	for _, status := range s.Status() {
+		if status.State == StateFailed {
+			return errors.Errorf("loop %s failed: %s", status.Name, status.LastError)
+		}
+	}
+	return nil
+}
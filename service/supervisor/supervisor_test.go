@@ -0,0 +1,109 @@
+package supervisor
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"go.uber.org/zap"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+)
+
+func newTestSupervisor(ctx context.Context, maxFailures int) *Supervisor {
+	s := New(xzap.ToContext(ctx, zap.NewNop()), config.SupervisorCfg{MaxFailures: maxFailures})
+	s.initialBackoff = time.Millisecond
+	s.maxBackoff = 4 * time.Millisecond
+	return s
+}
+
+func TestSupervisorEscalatesAfterConsecutiveFailures(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newTestSupervisor(ctx, 3)
+
+	var runs atomic.Int32
+	s.Go("crash", func() {
+		if runs.Add(1)%2 == 0 {
+			panic("boom")
+		}
+	})
+
+	select {
+	case err := <-s.Fatal():
+		if err == nil {
+			t.Fatal("expected fatal error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("supervisor did not escalate")
+	}
+	if got := runs.Load(); got != 3 {
+		t.Errorf("runs = %d, want 3", got)
+	}
+	status := s.Status()
+	if len(status) != 1 || status[0].State != StateFailed || status[0].Restarts != 2 {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if err := s.Check(); err == nil {
+		t.Error("expected check to fail")
+	}
+}
+
+func TestSupervisorStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newTestSupervisor(ctx, 3)
+
+	s.Go("loop", func() {
+		<-ctx.Done()
+	})
+	cancel()
+
+	deadline := time.After(time.Second)
+	for {
+		status := s.Status()
+		if status[0].State == StateStopped {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("loop not stopped: %+v", status)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if err := s.Check(); err != nil {
+		t.Errorf("stopped loop should not fail check: %v", err)
+	}
+	select {
+	case err := <-s.Fatal():
+		t.Errorf("unexpected fatal error %v", err)
+	default:
+	}
+}
+
+func TestSupervisorStopWaitsForLoops(t *testing.T) {
+	s := newTestSupervisor(context.Background(), 3)
+
+	quit := make(chan struct{})
+	var finished atomic.Bool
+	s.Go("loop", func() {
+		<-quit
+		time.Sleep(10 * time.Millisecond) // 模拟提交当前区块区间
+		finished.Store(true)
+	})
+
+	s.Stop()
+	close(quit)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Wait(ctx); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if !finished.Load() {
+		t.Error("wait returned before loop finished")
+	}
+	if status := s.Status(); status[0].State != StateStopped || status[0].ConsecutiveFailures != 0 {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
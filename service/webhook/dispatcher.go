@@ -0,0 +1,408 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"github.com/zeromicro/go-zero/core/threading"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+	"github.com/yaoxc/EasySwapSync/service/leader"
+	"github.com/yaoxc/EasySwapSync/service/metrics"
+)
+
+const (
+	EventListing = "listing" // 挂单
+	EventOffer   = "offer"   // 单品或集合买单
+	EventSale    = "sale"    // 成交
+	EventCancel  = "cancel"  // 取消订单
+
+	SignatureHeader = "X-EasySwap-Signature" // sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+	TimestampHeader = "X-EasySwap-Timestamp"
+	EventIdHeader   = "X-EasySwap-Event-Id"
+	EventTypeHeader = "X-EasySwap-Event"
+
+	DefaultTimeout        = 10   // in seconds
+	DefaultMaxAttempts    = 8    // 包含首次推送
+	DefaultInitialBackoff = 10   // in seconds
+	DefaultMaxBackoff     = 3600 // in seconds
+
+	PollInterval              = 2   // in seconds
+	SubscriberRefreshInterval = 30  // in seconds，管理接口修改订阅者时立即刷新
+	deliveryBatchSize         = 100 // 每个订阅者每轮最多推送的数量
+	deliveryConcurrency       = 8   // 同时推送的订阅者数量
+	maxErrorLength            = 512
+	maxResponseBody           = 64 * 1024
+)
+
+// EventTypes 支持订阅的事件类型
+var EventTypes = []string{EventListing, EventOffer, EventSale, EventCancel}
+
+// Event 推送给订阅者的事件。推送至少一次，订阅者按 id 去重
+type Event struct {
+	Id                string          `json:"id"`
+	Type              string          `json:"type"`
+	Chain             string          `json:"chain"`
+	ChainId           int64           `json:"chain_id"`
+	CollectionAddress string          `json:"collection_address"`
+	TokenId           string          `json:"token_id"`
+	OrderId           string          `json:"order_id"`
+	Maker             string          `json:"maker"`
+	Taker             string          `json:"taker"`
+	CurrencyAddress   string          `json:"currency_address"`
+	Price             decimal.Decimal `json:"price"` // 计价代币的最小单位
+	BlockNumber       uint64          `json:"block_number"`
+	TxHash            string          `json:"tx_hash"`
+	EventTime         int64           `json:"event_time"`
+}
+
+// EventId 由交易hash和日志序号组成的事件ID
+func EventId(txHash string, logIndex uint) string {
+	return fmt.Sprintf("%s-%d", txHash, logIndex)
+}
+
+// Sign 计算推送的签名，订阅者用同样的方法校验 SignatureHeader
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher 把订单簿事件按订阅者的过滤条件写入推送队列（ob_webhook_delivery），
+// 由推送循环签名后 POST 给订阅者，失败按指数退避重试，重试次数用尽后移入死信表。
+// 开启 leader 选举时只有 leader 推送
+type Dispatcher struct {
+	ctx            context.Context
+	db             *gorm.DB
+	chain          string
+	chainId        int64
+	currencies     *currency.Registry
+	elector        *leader.Elector
+	client         *http.Client
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	subscribers []Subscriber // 订阅者缓存
+	loadedAt    time.Time
+	lock        *sync.RWMutex
+}
+
+// New 创建 webhook 推送器，elector 不为 nil 时只有 leader 推送
+func New(ctx context.Context, cfg config.WebhookCfg, db *gorm.DB, chain string, chainId int64, currencies *currency.Registry, elector *leader.Elector) *Dispatcher {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	initialBackoff := cfg.InitialBackoff
+	if initialBackoff <= 0 {
+		initialBackoff = DefaultInitialBackoff
+	}
+	maxBackoff := cfg.MaxBackoff
+	if maxBackoff < initialBackoff {
+		maxBackoff = DefaultMaxBackoff
+	}
+	return &Dispatcher{
+		ctx:            ctx,
+		db:             db,
+		chain:          chain,
+		chainId:        chainId,
+		currencies:     currencies,
+		elector:        elector,
+		client:         &http.Client{Timeout: time.Duration(timeout) * time.Second},
+		maxAttempts:    maxAttempts,
+		initialBackoff: time.Duration(initialBackoff) * time.Second,
+		maxBackoff:     time.Duration(maxBackoff) * time.Second,
+		lock:           &sync.RWMutex{},
+	}
+}
+
+// Start 启动推送循环，d 为 nil（未开启 webhook）时忽略
+func (d *Dispatcher) Start() {
+	if d == nil {
+		return
+	}
+	threading.GoSafe(d.deliverLoop)
+}
+
+// Publish 为匹配的订阅者把 event 写入推送队列，d 为 nil（未开启 webhook）时忽略。
+// tx 为写入订单、成交等状态的事务，推送记录随状态变更一起提交或回滚，事件不会在提交后丢失。
+// 同一事件对同一订阅者只入队一次，重放日志不会重复推送尚未送达的事件
+func (d *Dispatcher) Publish(tx *gorm.DB, event Event) error {
+	if d == nil {
+		return nil
+	}
+	event.Chain = d.chain
+	event.ChainId = d.chainId
+
+	subscribers, err := d.cachedSubscribers(tx)
+	if err != nil {
+		return err
+	}
+	var payload []byte
+	var deliveries []model.WebhookDelivery
+	now := time.Now().Unix()
+	for _, subscriber := range subscribers {
+		if !subscriber.Matches(event, d.currencies) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				return errors.Wrap(err, "failed on marshal webhook event")
+			}
+		}
+		deliveries = append(deliveries, model.WebhookDelivery{
+			SubscriberId:    subscriber.Id,
+			EventId:         event.Id,
+			EventType:       event.Type,
+			Payload:         string(payload),
+			NextAttemptTime: now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := tx.Table(model.WebhookDeliveryTableName(d.chain)).Clauses(clause.OnConflict{
+		DoNothing: true,
+	}).Create(&deliveries).Error; err != nil {
+		return errors.Wrap(err, "failed on create webhook deliveries")
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliverLoop() {
+	timer := time.NewTicker(PollInterval * time.Second)
+	defer timer.Stop()
+	for {
+		select {
+		case <-d.ctx.Done():
+			xzap.WithContext(d.ctx).Info("webhook deliverLoop stopped due to context cancellation")
+			return
+		case <-timer.C:
+			if !d.elector.IsLeader() {
+				continue
+			}
+			if _, err := d.Deliver(time.Now()); err != nil {
+				xzap.WithContext(d.ctx).Error("failed on deliver webhooks", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Deliver 推送到期的 webhook，返回推送成功的数量。每个订阅者每轮最多推送 deliveryBatchSize 条，
+// 不同订阅者并发推送（最多 deliveryConcurrency 个），同一订阅者按入队顺序推送，响应慢的订阅者不会拖住其他订阅者
+func (d *Dispatcher) Deliver(now time.Time) (int, error) {
+	// 订阅者删除后 Publish 可能按旧缓存入队，这些记录不会再被推送
+	if err := d.db.WithContext(d.ctx).Exec(fmt.Sprintf(`DELETE FROM %s WHERE subscriber_id NOT IN (SELECT id FROM %s)`,
+		model.WebhookDeliveryTableName(d.chain), model.WebhookSubscriberTableName(d.chain))).Error; err != nil {
+		return 0, errors.Wrap(err, "failed on delete orphan webhook deliveries")
+	}
+	subscribers, err := d.loadSubscribers(d.db)
+	if err != nil {
+		return 0, err
+	}
+
+	var delivered atomic.Int64
+	var firstErr error
+	var errOnce sync.Once
+	limit := make(chan struct{}, deliveryConcurrency)
+	group := threading.NewRoutineGroup()
+	for _, subscriber := range subscribers {
+		subscriber := subscriber
+		limit <- struct{}{}
+		group.RunSafe(func() {
+			defer func() { <-limit }()
+			count, err := d.deliverTo(subscriber, now)
+			delivered.Add(int64(count))
+			if err != nil {
+				errOnce.Do(func() { firstErr = err })
+			}
+		})
+	}
+	group.Wait()
+	return int(delivered.Load()), firstErr
+}
+
+// deliverTo 按顺序推送一个订阅者到期的 webhook，返回推送成功的数量
+func (d *Dispatcher) deliverTo(subscriber Subscriber, now time.Time) (int, error) {
+	var deliveries []model.WebhookDelivery
+	if err := d.db.WithContext(d.ctx).Table(model.WebhookDeliveryTableName(d.chain)).
+		Where("subscriber_id = ? and next_attempt_time <= ?", subscriber.Id, now.Unix()).
+		Order("next_attempt_time, id").
+		Limit(deliveryBatchSize).
+		Find(&deliveries).Error; err != nil {
+		return 0, errors.Wrap(err, "failed on query webhook deliveries")
+	}
+
+	var delivered int
+	for _, delivery := range deliveries {
+		sendErr := d.send(subscriber, delivery)
+		if d.ctx.Err() != nil {
+			// 停机时中断的推送不计入重试次数
+			return delivered, nil
+		}
+		if sendErr == nil {
+			if err := d.deleteDelivery(delivery.Id); err != nil {
+				return delivered, err
+			}
+			delivered++
+			metrics.WebhookDelivered(d.chain, "delivered")
+			continue
+		}
+		if err := d.retry(delivery, sendErr, now); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+func (d *Dispatcher) send(subscriber Subscriber, delivery model.WebhookDelivery) error {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, subscriber.Url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed on create webhook request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIdHeader, delivery.EventId)
+	req.Header.Set(EventTypeHeader, delivery.EventType)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(subscriber.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed on post webhook")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// retry 记录失败并按指数退避安排下次推送，重试次数用尽时移入死信表
+func (d *Dispatcher) retry(delivery model.WebhookDelivery, sendErr error, now time.Time) error {
+	attempts := delivery.Attempts + 1
+	lastError := sendErr.Error()
+	if len(lastError) > maxErrorLength {
+		lastError = lastError[:maxErrorLength]
+	}
+
+	if attempts < d.maxAttempts {
+		metrics.WebhookDelivered(d.chain, "retry")
+		if err := d.db.WithContext(d.ctx).Table(model.WebhookDeliveryTableName(d.chain)).
+			Where("id = ?", delivery.Id).
+			Updates(map[string]interface{}{
+				"attempts":          attempts,
+				"next_attempt_time": now.Add(d.backoff(attempts)).Unix(),
+				"last_error":        lastError,
+			}).Error; err != nil {
+			return errors.Wrap(err, "failed on update webhook delivery")
+		}
+		return nil
+	}
+
+	metrics.WebhookDelivered(d.chain, "dead")
+	xzap.WithContext(d.ctx).Warn("webhook delivery moved to dead letter",
+		zap.Int64("subscriber_id", delivery.SubscriberId), zap.String("event_id", delivery.EventId), zap.String("error", lastError))
+	return d.db.WithContext(d.ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(model.WebhookDeadLetterTableName(d.chain)).Clauses(clause.OnConflict{
+			UpdateAll: true,
+		}).Create(&model.WebhookDeadLetter{
+			SubscriberId: delivery.SubscriberId,
+			EventId:      delivery.EventId,
+			EventType:    delivery.EventType,
+			Payload:      delivery.Payload,
+			Attempts:     attempts,
+			LastError:    lastError,
+		}).Error; err != nil {
+			return errors.Wrap(err, "failed on create webhook dead letter")
+		}
+		if err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, model.WebhookDeliveryTableName(d.chain)), delivery.Id).Error; err != nil {
+			return errors.Wrap(err, "failed on delete webhook delivery")
+		}
+		return nil
+	})
+}
+
+// backoff 第 attempts 次失败后的等待时间
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.initialBackoff
+	for i := 1; i < attempts && backoff < d.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > d.maxBackoff {
+		backoff = d.maxBackoff
+	}
+	return backoff
+}
+
+func (d *Dispatcher) deleteDelivery(id int64) error {
+	if err := d.db.WithContext(d.ctx).Exec(fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, model.WebhookDeliveryTableName(d.chain)), id).Error; err != nil {
+		return errors.Wrap(err, "failed on delete webhook delivery")
+	}
+	return nil
+}
+
+// cachedSubscribers 按 SubscriberRefreshInterval 刷新的订阅者缓存，避免每个事件都查询数据库，过期时通过 db 重新加载
+func (d *Dispatcher) cachedSubscribers(db *gorm.DB) ([]Subscriber, error) {
+	d.lock.RLock()
+	subscribers, loadedAt := d.subscribers, d.loadedAt
+	d.lock.RUnlock()
+	if !loadedAt.IsZero() && time.Since(loadedAt) < SubscriberRefreshInterval*time.Second {
+		return subscribers, nil
+	}
+	return d.loadSubscribers(db)
+}
+
+func (d *Dispatcher) loadSubscribers(db *gorm.DB) ([]Subscriber, error) {
+	var rows []model.WebhookSubscriber
+	if err := db.WithContext(d.ctx).Table(model.WebhookSubscriberTableName(d.chain)).
+		Order("id").
+		Find(&rows).Error; err != nil {
+		return nil, errors.Wrap(err, "failed on query webhook subscribers")
+	}
+	subscribers := make([]Subscriber, 0, len(rows))
+	for _, row := range rows {
+		subscribers = append(subscribers, fromModel(row))
+	}
+
+	d.lock.Lock()
+	d.subscribers = subscribers
+	d.loadedAt = time.Now()
+	d.lock.Unlock()
+	return subscribers, nil
+}
+
+// invalidate 订阅者变更后下次 Publish 重新加载
+func (d *Dispatcher) invalidate() {
+	d.lock.Lock()
+	d.loadedAt = time.Time{}
+	d.lock.Unlock()
+}
@@ -0,0 +1,162 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/pkg/errors"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+)
+
+const testChain = "sepolia"
+
+// newTestDispatcher 创建使用内存 SQLite 的推送器
+func newTestDispatcher(t *testing.T) *Dispatcher {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	tables := map[string]string{
+		model.WebhookSubscriberTableName(testChain): `id integer primary key autoincrement, name text, url text, secret text,
+collections text, event_types text, min_price text, create_time integer, update_time integer`,
+		model.WebhookDeliveryTableName(testChain): `id integer primary key autoincrement, subscriber_id integer, event_id text,
+event_type text, payload text, attempts integer default 0, next_attempt_time integer, last_error text default '',
+create_time integer, update_time integer, unique (subscriber_id, event_id)`,
+		model.WebhookDeadLetterTableName(testChain): `id integer primary key autoincrement, subscriber_id integer, event_id text,
+event_type text, payload text, attempts integer, last_error text, create_time integer, update_time integer,
+unique (subscriber_id, event_id)`,
+	}
+	for table, columns := range tables {
+		if err := db.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", table, columns)).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	currencies, err := currency.New(config.ContractCfg{EthAddress: ethAddress}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := xzap.ToContext(context.Background(), zap.NewNop())
+	return New(ctx, config.WebhookCfg{Enable: true}, db, testChain, 11155111, currencies, nil)
+}
+
+func countDeliveries(t *testing.T, d *Dispatcher) int64 {
+	var count int64
+	if err := d.db.Table(model.WebhookDeliveryTableName(testChain)).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestPublishInTransaction(t *testing.T) {
+	d := newTestDispatcher(t)
+	if _, err := d.CreateSubscriber(Subscriber{Name: "partner", Url: "https://example.com/hook"}); err != nil {
+		t.Fatal(err)
+	}
+	event := Event{Id: EventId("0xaa", 0), Type: EventSale, CollectionAddress: collection, CurrencyAddress: ethAddress}
+
+	// 调用方的事务回滚时推送记录一起回滚
+	rollback := errors.New("rollback")
+	if err := d.db.Transaction(func(tx *gorm.DB) error {
+		if err := d.Publish(tx, event); err != nil {
+			return err
+		}
+		return rollback
+	}); !errors.Is(err, rollback) {
+		t.Fatalf("expected rollback, got %v", err)
+	}
+	if count := countDeliveries(t, d); count != 0 {
+		t.Fatalf("expected no delivery after rollback, got %d", count)
+	}
+
+	if err := d.db.Transaction(func(tx *gorm.DB) error {
+		return d.Publish(tx, event)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if count := countDeliveries(t, d); count != 1 {
+		t.Fatalf("expected 1 delivery after commit, got %d", count)
+	}
+}
+
+func TestDeliverSubscribersConcurrently(t *testing.T) {
+	d := newTestDispatcher(t)
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	received := make(chan struct{}, 1)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer fast.Close()
+
+	for name, url := range map[string]string{"slow": slow.URL, "fast": fast.URL} {
+		if _, err := d.CreateSubscriber(Subscriber{Name: name, Url: url}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Publish(d.db, Event{Id: EventId("0xaa", 0), Type: EventSale, CollectionAddress: collection, CurrencyAddress: ethAddress}); err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		delivered int
+		err       error
+	}
+	done := make(chan result, 1)
+	go func() {
+		delivered, err := d.Deliver(time.Now())
+		done <- result{delivered, err}
+	}()
+
+	// 慢订阅者还没有响应时，其他订阅者已经收到推送
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fast subscriber blocked by slow subscriber")
+	}
+	close(release)
+	r := <-done
+	if r.err != nil || r.delivered != 2 {
+		t.Fatalf("expected 2 delivered, got %d %v", r.delivered, r.err)
+	}
+	if count := countDeliveries(t, d); count != 0 {
+		t.Fatalf("expected deliveries removed, got %d", count)
+	}
+}
+
+func TestDeliverRemovesOrphans(t *testing.T) {
+	d := newTestDispatcher(t)
+	if err := d.db.Table(model.WebhookDeliveryTableName(testChain)).Create(&model.WebhookDelivery{
+		SubscriberId: 42,
+		EventId:      EventId("0xaa", 0),
+		EventType:    EventSale,
+		Payload:      "{}",
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Deliver(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if count := countDeliveries(t, d); count != 0 {
+		t.Fatalf("expected orphan delivery removed, got %d", count)
+	}
+}
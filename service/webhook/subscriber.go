@@ -0,0 +1,228 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/yaoxc/EasySwapBase/logger/xzap"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/yaoxc/EasySwapSync/model"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+)
+
+const secretBytes = 32
+
+var (
+	// ErrInvalidSubscriber 订阅者参数不合法
+	ErrInvalidSubscriber = errors.New("invalid webhook subscriber")
+	// ErrSubscriberNotFound 订阅者不存在
+	ErrSubscriberNotFound = errors.New("webhook subscriber not found")
+)
+
+// Subscriber webhook 订阅者，Collections、EventTypes 为空表示不过滤，MinPrice 为折合 ETH(wei) 的最低价格
+type Subscriber struct {
+	Id          int64           `json:"id"`
+	Name        string          `json:"name"`
+	Url         string          `json:"url"`
+	Secret      string          `json:"secret,omitempty"` // 只在创建时返回
+	Collections []string        `json:"collections"`
+	EventTypes  []string        `json:"event_types"`
+	MinPrice    decimal.Decimal `json:"min_price"`
+	CreateTime  int64           `json:"create_time"`
+}
+
+// Matches event 是否满足订阅者的过滤条件，其他币种的价格折合成 ETH 后与 MinPrice 比较
+func (s Subscriber) Matches(event Event, currencies *currency.Registry) bool {
+	if len(s.EventTypes) > 0 && !slices.Contains(s.EventTypes, event.Type) {
+		return false
+	}
+	if len(s.Collections) > 0 && !slices.Contains(s.Collections, strings.ToLower(event.CollectionAddress)) {
+		return false
+	}
+	if s.MinPrice.IsPositive() {
+		price, err := currencies.ToEth(event.CurrencyAddress, event.Price)
+		if err != nil || price.LessThan(s.MinPrice) {
+			return false
+		}
+	}
+	return true
+}
+
+func fromModel(row model.WebhookSubscriber) Subscriber {
+	return Subscriber{
+		Id:          row.Id,
+		Name:        row.Name,
+		Url:         row.Url,
+		Secret:      row.Secret,
+		Collections: splitList(row.Collections),
+		EventTypes:  splitList(row.EventTypes),
+		MinPrice:    row.MinPrice,
+		CreateTime:  row.CreateTime,
+	}
+}
+
+// normalize 校验订阅者参数，地址转小写，未指定密钥时生成随机密钥
+func (s *Subscriber) normalize() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.Wrap(ErrInvalidSubscriber, "name is required")
+	}
+	u, err := url.Parse(s.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Wrapf(ErrInvalidSubscriber, "invalid url %q", s.Url)
+	}
+	for i, collection := range s.Collections {
+		collection = strings.ToLower(strings.TrimSpace(collection))
+		if len(collection) != 42 || !strings.HasPrefix(collection, "0x") {
+			return errors.Wrapf(ErrInvalidSubscriber, "invalid collection address %q", s.Collections[i])
+		}
+		s.Collections[i] = collection
+	}
+	for _, eventType := range s.EventTypes {
+		if !slices.Contains(EventTypes, eventType) {
+			return errors.Wrapf(ErrInvalidSubscriber, "unknown event type %q, supported: %s", eventType, strings.Join(EventTypes, ","))
+		}
+	}
+	if s.MinPrice.IsNegative() {
+		return errors.Wrap(ErrInvalidSubscriber, "min_price must not be negative")
+	}
+	if s.Secret == "" {
+		secret := make([]byte, secretBytes)
+		if _, err := rand.Read(secret); err != nil {
+			return errors.Wrap(err, "failed on generate webhook secret")
+		}
+		s.Secret = hex.EncodeToString(secret)
+	}
+	return nil
+}
+
+// CreateSubscriber 注册订阅者，返回的 Secret 用于校验推送签名
+func (d *Dispatcher) CreateSubscriber(subscriber Subscriber) (Subscriber, error) {
+	if err := subscriber.normalize(); err != nil {
+		return subscriber, err
+	}
+	row := model.WebhookSubscriber{
+		Name:        subscriber.Name,
+		Url:         subscriber.Url,
+		Secret:      subscriber.Secret,
+		Collections: strings.Join(subscriber.Collections, ","),
+		EventTypes:  strings.Join(subscriber.EventTypes, ","),
+		MinPrice:    subscriber.MinPrice,
+	}
+	if err := d.db.WithContext(d.ctx).Table(model.WebhookSubscriberTableName(d.chain)).Create(&row).Error; err != nil {
+		return subscriber, errors.Wrap(err, "failed on create webhook subscriber")
+	}
+	d.invalidate()
+	xzap.WithContext(d.ctx).Info("webhook subscriber created",
+		zap.Int64("subscriber_id", row.Id), zap.String("name", row.Name), zap.String("url", row.Url))
+	return fromModel(row), nil
+}
+
+// ListSubscribers 返回所有订阅者，不包含密钥
+func (d *Dispatcher) ListSubscribers() ([]Subscriber, error) {
+	subscribers, err := d.loadSubscribers(d.db)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Subscriber, len(subscribers))
+	for i, subscriber := range subscribers {
+		subscriber.Secret = ""
+		result[i] = subscriber
+	}
+	return result, nil
+}
+
+// DeleteSubscriber 删除订阅者及其待推送的 webhook，死信保留
+func (d *Dispatcher) DeleteSubscriber(id int64) error {
+	if err := d.db.WithContext(d.ctx).Transaction(func(tx *gorm.DB) error {
+		deleted := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, model.WebhookSubscriberTableName(d.chain)), id)
+		if deleted.Error != nil {
+			return errors.Wrap(deleted.Error, "failed on delete webhook subscriber")
+		}
+		if deleted.RowsAffected == 0 {
+			return errors.Wrapf(ErrSubscriberNotFound, "id %d", id)
+		}
+		if err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE subscriber_id = ?`, model.WebhookDeliveryTableName(d.chain)), id).Error; err != nil {
+			return errors.Wrap(err, "failed on delete webhook deliveries")
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	d.invalidate()
+	xzap.WithContext(d.ctx).Info("webhook subscriber deleted", zap.Int64("subscriber_id", id))
+	return nil
+}
+
+// Redrive 把订阅者的死信重新放回推送队列，返回重新入队的数量
+func (d *Dispatcher) Redrive(subscriberId int64) (int, error) {
+	var count int64
+	if err := d.db.WithContext(d.ctx).Table(model.WebhookSubscriberTableName(d.chain)).
+		Where("id = ?", subscriberId).
+		Count(&count).Error; err != nil {
+		return 0, errors.Wrap(err, "failed on query webhook subscriber")
+	}
+	if count == 0 {
+		return 0, errors.Wrapf(ErrSubscriberNotFound, "id %d", subscriberId)
+	}
+
+	var deadLetters []model.WebhookDeadLetter
+	if err := d.db.WithContext(d.ctx).Table(model.WebhookDeadLetterTableName(d.chain)).
+		Where("subscriber_id = ?", subscriberId).
+		Order("id").
+		Find(&deadLetters).Error; err != nil {
+		return 0, errors.Wrap(err, "failed on query webhook dead letters")
+	}
+	if len(deadLetters) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now().Unix()
+	deliveries := make([]model.WebhookDelivery, 0, len(deadLetters))
+	ids := make([]int64, 0, len(deadLetters))
+	for _, deadLetter := range deadLetters {
+		deliveries = append(deliveries, model.WebhookDelivery{
+			SubscriberId:    deadLetter.SubscriberId,
+			EventId:         deadLetter.EventId,
+			EventType:       deadLetter.EventType,
+			Payload:         deadLetter.Payload,
+			NextAttemptTime: now,
+		})
+		ids = append(ids, deadLetter.Id)
+	}
+	if err := d.db.WithContext(d.ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(model.WebhookDeliveryTableName(d.chain)).Clauses(clause.OnConflict{
+			DoNothing: true,
+		}).Create(&deliveries).Error; err != nil {
+			return errors.Wrap(err, "failed on create webhook deliveries")
+		}
+		if err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE id in ?`, model.WebhookDeadLetterTableName(d.chain)), ids).Error; err != nil {
+			return errors.Wrap(err, "failed on delete webhook dead letters")
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	xzap.WithContext(d.ctx).Info("webhook dead letters redriven",
+		zap.Int64("subscriber_id", subscriberId), zap.Int("count", len(deliveries)))
+	return len(deliveries), nil
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/yaoxc/EasySwapSync/service/config"
+	"github.com/yaoxc/EasySwapSync/service/currency"
+)
+
+const (
+	ethAddress  = "0x0000000000000000000000000000000000000000"
+	usdcAddress = "0x94a9d9ac8a22534e3faca9f4e7f2e2cf85d5e4c8"
+	collection  = "0x1111111111111111111111111111111111111111"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"0xabc-1"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("secret", "1700000000", body); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if Sign("other", "1700000000", body) == want {
+		t.Error("signature does not depend on secret")
+	}
+	if Sign("secret", "1700000001", body) == want {
+		t.Error("signature does not depend on timestamp")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	currencies, err := currency.New(config.ContractCfg{EthAddress: ethAddress}, []config.CurrencyCfg{
+		{Address: usdcAddress, Symbol: "USDC", Decimals: 6, EthRate: "0.0005"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oneEth := decimal.New(1, 18)
+	event := Event{Type: EventSale, CollectionAddress: collection, CurrencyAddress: ethAddress, Price: oneEth}
+
+	cases := []struct {
+		name       string
+		subscriber Subscriber
+		event      Event
+		want       bool
+	}{
+		{"no filter", Subscriber{}, event, true},
+		{"event type", Subscriber{EventTypes: []string{EventSale}}, event, true},
+		{"other event type", Subscriber{EventTypes: []string{EventListing, EventCancel}}, event, false},
+		{"collection", Subscriber{Collections: []string{collection}}, event, true},
+		{"other collection", Subscriber{Collections: []string{"0x2222222222222222222222222222222222222222"}}, event, false},
+		{"min price", Subscriber{MinPrice: oneEth}, event, true},
+		{"below min price", Subscriber{MinPrice: oneEth.Mul(decimal.NewFromInt(2))}, event, false},
+		// 2000 USDC * 0.0005 = 1 ETH
+		{
+			"erc20 converted to eth",
+			Subscriber{MinPrice: oneEth},
+			Event{Type: EventSale, CollectionAddress: collection, CurrencyAddress: usdcAddress, Price: decimal.NewFromInt(2000_000000)},
+			true,
+		},
+		{
+			"unknown currency with min price",
+			Subscriber{MinPrice: oneEth},
+			Event{Type: EventSale, CollectionAddress: collection, CurrencyAddress: "0x3333333333333333333333333333333333333333", Price: oneEth},
+			false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.subscriber.Matches(c.event, currencies); got != c.want {
+				t.Errorf("Matches = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	valid := func() Subscriber {
+		return Subscriber{
+			Name:        "partner",
+			Url:         "https://example.com/hook",
+			Collections: []string{" 0x1111111111111111111111111111111111111111 "},
+			EventTypes:  []string{EventListing, EventSale},
+		}
+	}
+
+	s := valid()
+	if err := s.normalize(); err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if s.Collections[0] != collection {
+		t.Errorf("collection = %q, want %q", s.Collections[0], collection)
+	}
+	if len(s.Secret) != 2*secretBytes {
+		t.Errorf("generated secret length = %d, want %d", len(s.Secret), 2*secretBytes)
+	}
+
+	invalid := map[string]func(*Subscriber){
+		"empty name":         func(s *Subscriber) { s.Name = " " },
+		"relative url":       func(s *Subscriber) { s.Url = "/hook" },
+		"unsupported scheme": func(s *Subscriber) { s.Url = "ftp://example.com/hook" },
+		"bad collection":     func(s *Subscriber) { s.Collections = []string{"0x1234"} },
+		"unknown event type": func(s *Subscriber) { s.EventTypes = []string{"transfer"} },
+		"negative min price": func(s *Subscriber) { s.MinPrice = decimal.NewFromInt(-1) },
+	}
+	for name, mutate := range invalid {
+		t.Run(name, func(t *testing.T) {
+			s := valid()
+			mutate(&s)
+			if err := s.normalize(); !errors.Is(err, ErrInvalidSubscriber) {
+				t.Errorf("normalize error = %v, want ErrInvalidSubscriber", err)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{initialBackoff: 10 * time.Second, maxBackoff: 60 * time.Second}
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 60 * time.Second, 60 * time.Second}
+	for i, w := range want {
+		if got := d.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}